@@ -43,6 +43,13 @@ func main() {
 				os.Exit(1)
 			}
 
+			// Exit if the operator cannot continue, e.g. when leadership is lost.
+			go func() {
+				<-defaultOperator.ShutdownRequested()
+				defaultOperator.Shutdown()
+				os.Exit(1)
+			}()
+
 			// Block action by waiting signals from OS.
 			utils_signal.WaitForProcessInterruption(func() {
 				defaultOperator.Shutdown()
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"time"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -12,6 +13,7 @@ var AppDescription = "Run your custom cluster-wide scripts in reaction to Kubern
 var Version = "dev"
 
 var HooksDir = ""
+var HooksReloadInterval time.Duration = 0
 var TempDir = "/tmp/shell-operator"
 
 var Namespace = ""
@@ -35,6 +37,12 @@ var CommonFlagsInfo = map[string]FlagInfo{
 		"SHELL_OPERATOR_HOOKS_DIR",
 		true,
 	},
+	"hooks-reload-interval": {
+		"hooks-reload-interval",
+		"An interval to check hooks dir for changes and reload changed hooks, e.g. '10s'. Zero value disables reloading. Can be set with $SHELL_OPERATOR_HOOKS_RELOAD_INTERVAL.",
+		"SHELL_OPERATOR_HOOKS_RELOAD_INTERVAL",
+		true,
+	},
 	"tmp-dir": {
 		"tmp-dir",
 		"A path to store temporary files with data for hooks. Can be set with $SHELL_OPERATOR_TMP_DIR.",
@@ -85,6 +93,14 @@ func DefineStartCommandFlags(kpApp *kingpin.Application, cmd *kingpin.CmdClause)
 			StringVar(&HooksDir)
 	}
 
+	flag = CommonFlagsInfo["hooks-reload-interval"]
+	if flag.Define {
+		cmd.Flag(flag.Name, flag.Help).
+			Envar(flag.Envar).
+			Default(HooksReloadInterval.String()).
+			DurationVar(&HooksReloadInterval)
+	}
+
 	flag = CommonFlagsInfo["tmp-dir"]
 	if flag.Define {
 		cmd.Flag(flag.Name, flag.Help).
@@ -134,8 +150,14 @@ func DefineStartCommandFlags(kpApp *kingpin.Application, cmd *kingpin.CmdClause)
 	}
 
 	DefineKubeClientFlags(cmd)
+	DefineKubeSnapshotFlags(cmd)
 	DefineValidatingWebhookFlags(cmd)
 	DefineJqFlags(cmd)
+	DefineHookRuntimeFlags(cmd)
+	DefineLeaderElectionFlags(cmd)
+	DefineTaskStoreFlags(cmd)
+	DefineTracingFlags(cmd)
+	DefineEventsFlags(cmd)
 	DefineLoggingFlags(cmd)
 	DefineDebugFlags(kpApp, cmd)
 }
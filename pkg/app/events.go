@@ -0,0 +1,18 @@
+package app
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+var RecordEvents = false
+var PodName = ""
+
+// DefineEventsFlags set flags to publish Kubernetes Events about hook failures.
+func DefineEventsFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("record-events", "Publish Kubernetes Events for failed hook runs, exhausted retries and webhook denials. Can be set with $SHELL_OPERATOR_RECORD_EVENTS.").
+		Envar("SHELL_OPERATOR_RECORD_EVENTS").
+		Default("false").
+		BoolVar(&RecordEvents)
+	cmd.Flag("pod-name", "A name of the shell-operator Pod. Events without an involved object are attached to this Pod. Default is a hostname. Can be set with $SHELL_OPERATOR_POD_NAME.").
+		Envar("SHELL_OPERATOR_POD_NAME").
+		Default(PodName).
+		StringVar(&PodName)
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var HookRuntime = "exec"
+
+// HookConfigTimeout is a timeout to get a config from the hook.
+var HookConfigTimeout = time.Minute
+
+// HookTimeout is a default timeout for hook runs, 0 means no timeout.
+var HookTimeout time.Duration = 0
+var HookTimeoutGracePeriod = 5 * time.Second
+
+// HookHistorySize is a number of last executions kept in memory for each hook.
+var HookHistorySize = 20
+
+// DefineHookRuntimeFlags set flag for hook runtime
+func DefineHookRuntimeFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("hook-runtime", "A runtime to run hooks: 'exec' to run a hook executable for every event or 'worker' to keep a long-lived process for each hook. Can be set with $SHELL_OPERATOR_HOOK_RUNTIME.").
+		Envar("SHELL_OPERATOR_HOOK_RUNTIME").
+		Default(HookRuntime).
+		EnumVar(&HookRuntime, "exec", "worker")
+	cmd.Flag("hook-config-timeout", "A timeout to get a config from the hook with '--config' or with the config request to the worker. Can be set with $SHELL_OPERATOR_HOOK_CONFIG_TIMEOUT.").
+		Envar("SHELL_OPERATOR_HOOK_CONFIG_TIMEOUT").
+		Default(HookConfigTimeout.String()).
+		DurationVar(&HookConfigTimeout)
+	cmd.Flag("hook-timeout", "A default timeout for hook runs, e.g. '5m'. A hook is terminated with SIGTERM and then with SIGKILL after a grace period. Can be overridden with 'timeout' in the binding configuration. Can be set with $SHELL_OPERATOR_HOOK_TIMEOUT.").
+		Envar("SHELL_OPERATOR_HOOK_TIMEOUT").
+		Default(HookTimeout.String()).
+		DurationVar(&HookTimeout)
+	cmd.Flag("hook-timeout-grace-period", "A time to wait after SIGTERM before sending SIGKILL to a hook terminated by timeout. Can be set with $SHELL_OPERATOR_HOOK_TIMEOUT_GRACE_PERIOD.").
+		Envar("SHELL_OPERATOR_HOOK_TIMEOUT_GRACE_PERIOD").
+		Default(HookTimeoutGracePeriod.String()).
+		DurationVar(&HookTimeoutGracePeriod)
+	cmd.Flag("hook-history-size", "A number of last executions to keep in memory for each hook, 0 disables the history. Can be set with $SHELL_OPERATOR_HOOK_HISTORY_SIZE.").
+		Envar("SHELL_OPERATOR_HOOK_HISTORY_SIZE").
+		Default(strconv.Itoa(HookHistorySize)).
+		IntVar(&HookHistorySize)
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var KubeSnapshotDir = ""
+var KubeSnapshotSaveInterval = time.Minute
+
+// DefineKubeSnapshotFlags set flags to keep caches of informers on disk.
+func DefineKubeSnapshotFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("kube-snapshot-dir", "A directory to save objects of 'kubernetes' bindings. Informers resume watches from saved snapshots on start instead of a full List. Disabled if empty. Can be set with $SHELL_OPERATOR_KUBE_SNAPSHOT_DIR.").
+		Envar("SHELL_OPERATOR_KUBE_SNAPSHOT_DIR").
+		Default(KubeSnapshotDir).
+		StringVar(&KubeSnapshotDir)
+	cmd.Flag("kube-snapshot-save-interval", "An interval to save changed snapshots. Snapshots are also saved on shutdown. Can be set with $SHELL_OPERATOR_KUBE_SNAPSHOT_SAVE_INTERVAL.").
+		Envar("SHELL_OPERATOR_KUBE_SNAPSHOT_SAVE_INTERVAL").
+		Default(KubeSnapshotSaveInterval.String()).
+		DurationVar(&KubeSnapshotSaveInterval)
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type leaderElectionSettings struct {
+	Enabled       bool
+	LeaseName     string
+	Namespace     string
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+var LeaderElectionSettings = &leaderElectionSettings{
+	Enabled:       false,
+	LeaseName:     "shell-operator",
+	Namespace:     "",
+	Identity:      "",
+	LeaseDuration: 15 * time.Second,
+	RenewDeadline: 10 * time.Second,
+	RetryPeriod:   2 * time.Second,
+}
+
+// DefineLeaderElectionFlags defines flags for leader election between replicas.
+func DefineLeaderElectionFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("leader-election", "Enable leader election to run multiple replicas. Only the leader executes hooks from queues. Can be set with $LEADER_ELECTION.").
+		Envar("LEADER_ELECTION").
+		Default("false").
+		BoolVar(&LeaderElectionSettings.Enabled)
+	cmd.Flag("leader-election-lease-name", "A name of a Lease resource for leader election. Can be set with $LEADER_ELECTION_LEASE_NAME.").
+		Envar("LEADER_ELECTION_LEASE_NAME").
+		Default(LeaderElectionSettings.LeaseName).
+		StringVar(&LeaderElectionSettings.LeaseName)
+	cmd.Flag("leader-election-namespace", "A namespace of a Lease resource. Default is the namespace of a shell-operator. Can be set with $LEADER_ELECTION_NAMESPACE.").
+		Envar("LEADER_ELECTION_NAMESPACE").
+		Default(LeaderElectionSettings.Namespace).
+		StringVar(&LeaderElectionSettings.Namespace)
+	cmd.Flag("leader-election-identity", "A unique identity of a replica. Default is a hostname. Can be set with $LEADER_ELECTION_IDENTITY.").
+		Envar("LEADER_ELECTION_IDENTITY").
+		Default(LeaderElectionSettings.Identity).
+		StringVar(&LeaderElectionSettings.Identity)
+	cmd.Flag("leader-election-lease-duration", "A duration that non-leader replicas will wait before trying to acquire leadership. Can be set with $LEADER_ELECTION_LEASE_DURATION.").
+		Envar("LEADER_ELECTION_LEASE_DURATION").
+		Default(LeaderElectionSettings.LeaseDuration.String()).
+		DurationVar(&LeaderElectionSettings.LeaseDuration)
+	cmd.Flag("leader-election-renew-deadline", "A duration that the leader will retry refreshing leadership before giving up. Can be set with $LEADER_ELECTION_RENEW_DEADLINE.").
+		Envar("LEADER_ELECTION_RENEW_DEADLINE").
+		Default(LeaderElectionSettings.RenewDeadline.String()).
+		DurationVar(&LeaderElectionSettings.RenewDeadline)
+	cmd.Flag("leader-election-retry-period", "A duration between attempts to acquire or renew leadership. Can be set with $LEADER_ELECTION_RETRY_PERIOD.").
+		Envar("LEADER_ELECTION_RETRY_PERIOD").
+		Default(LeaderElectionSettings.RetryPeriod.String()).
+		DurationVar(&LeaderElectionSettings.RetryPeriod)
+}
@@ -0,0 +1,23 @@
+package app
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+var TaskStore = "none"
+var TaskStorePath = ""
+var TaskStoreConfigMapName = "shell-operator-tasks"
+
+// DefineTaskStoreFlags set flags to persist queued tasks between restarts.
+func DefineTaskStoreFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("task-store", "A storage to persist queued tasks and the dead letter queue between restarts: 'none', 'file' or 'configmap'. Can be set with $SHELL_OPERATOR_TASK_STORE.").
+		Envar("SHELL_OPERATOR_TASK_STORE").
+		Default(TaskStore).
+		EnumVar(&TaskStore, "none", "file", "configmap")
+	cmd.Flag("task-store-path", "A path to a file for 'file' task store. Default is 'tasks.json' in the tmp dir. Can be set with $SHELL_OPERATOR_TASK_STORE_PATH.").
+		Envar("SHELL_OPERATOR_TASK_STORE_PATH").
+		Default(TaskStorePath).
+		StringVar(&TaskStorePath)
+	cmd.Flag("task-store-configmap", "A name of a ConfigMap for 'configmap' task store. ConfigMap is created in the namespace of a shell-operator. Can be set with $SHELL_OPERATOR_TASK_STORE_CONFIGMAP.").
+		Envar("SHELL_OPERATOR_TASK_STORE_CONFIGMAP").
+		Default(TaskStoreConfigMapName).
+		StringVar(&TaskStoreConfigMapName)
+}
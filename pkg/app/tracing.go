@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// TracingOtlpEndpoint is a host:port of an OTLP HTTP receiver. Tracing is disabled if empty.
+var TracingOtlpEndpoint = ""
+var TracingOtlpInsecure = false
+var TracingServiceName = "shell-operator"
+var TracingSampleRatio = 1.0
+
+// DefineTracingFlags set flags for OpenTelemetry tracing.
+func DefineTracingFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("tracing-otlp-endpoint", "A host:port of an OTLP HTTP receiver to export traces, e.g. 'otel-collector:4318'. Tracing is disabled if empty. Can be set with $SHELL_OPERATOR_TRACING_OTLP_ENDPOINT.").
+		Envar("SHELL_OPERATOR_TRACING_OTLP_ENDPOINT").
+		Default(TracingOtlpEndpoint).
+		StringVar(&TracingOtlpEndpoint)
+	cmd.Flag("tracing-otlp-insecure", "Use HTTP instead of HTTPS to export traces. Can be set with $SHELL_OPERATOR_TRACING_OTLP_INSECURE.").
+		Envar("SHELL_OPERATOR_TRACING_OTLP_INSECURE").
+		BoolVar(&TracingOtlpInsecure)
+	cmd.Flag("tracing-service-name", "A service name for exported traces. Can be set with $SHELL_OPERATOR_TRACING_SERVICE_NAME.").
+		Envar("SHELL_OPERATOR_TRACING_SERVICE_NAME").
+		Default(TracingServiceName).
+		StringVar(&TracingServiceName)
+	cmd.Flag("tracing-sample-ratio", "A ratio of sampled traces from 0 to 1. Can be set with $SHELL_OPERATOR_TRACING_SAMPLE_RATIO.").
+		Envar("SHELL_OPERATOR_TRACING_SAMPLE_RATIO").
+		Default(fmt.Sprintf("%g", TracingSampleRatio)).
+		Float64Var(&TracingSampleRatio)
+}
@@ -5,26 +5,28 @@ import (
 )
 
 type validatingWebhookSettings struct {
-	ServerCertPath    string
-	ServerKeyPath     string
-	CAPath            string
-	CABundle          []byte
-	ClientCAPaths     []string
-	ServiceName       string
-	ConfigurationName string
-	ListenPort        string
-	ListenAddr        string
+	ServerCertPath            string
+	ServerKeyPath             string
+	CAPath                    string
+	CABundle                  []byte
+	ClientCAPaths             []string
+	ServiceName               string
+	ConfigurationName         string
+	MutatingConfigurationName string
+	ListenPort                string
+	ListenAddr                string
 }
 
 var ValidatingWebhookSettings = &validatingWebhookSettings{
-	ServerCertPath:    "/validating-certs/cert.crt",
-	ServerKeyPath:     "/validating-certs/cert.key",
-	CAPath:            "/validating-certs/ca.crt",
-	ClientCAPaths:     nil,
-	ServiceName:       "shell-operator-validating-svc",
-	ConfigurationName: "shell-operator-hooks",
-	ListenAddr:        "0.0.0.0",
-	ListenPort:        "9680",
+	ServerCertPath:            "/validating-certs/cert.crt",
+	ServerKeyPath:             "/validating-certs/cert.key",
+	CAPath:                    "/validating-certs/ca.crt",
+	ClientCAPaths:             nil,
+	ServiceName:               "shell-operator-validating-svc",
+	ConfigurationName:         "shell-operator-hooks",
+	MutatingConfigurationName: "shell-operator-hooks",
+	ListenAddr:                "0.0.0.0",
+	ListenPort:                "9680",
 }
 
 // DefineValidatingWebhookFlags defines flags for ValidatingWebhook server.
@@ -33,6 +35,10 @@ func DefineValidatingWebhookFlags(cmd *kingpin.CmdClause) {
 		Envar("VALIDATING_WEBHOOK_CONFIGURATION_NAME").
 		Default(ValidatingWebhookSettings.ConfigurationName).
 		StringVar(&ValidatingWebhookSettings.ConfigurationName)
+	cmd.Flag("mutating-webhook-configuration-name", "A name of a MutatingWebhookConfiguration resource. Can be set with $MUTATING_WEBHOOK_CONFIGURATION_NAME.").
+		Envar("MUTATING_WEBHOOK_CONFIGURATION_NAME").
+		Default(ValidatingWebhookSettings.MutatingConfigurationName).
+		StringVar(&ValidatingWebhookSettings.MutatingConfigurationName)
 	cmd.Flag("validating-webhook-service-name", "A name of a service used in ValidatingWebhookConfiguration. Can be set with $VALIDATING_WEBHOOK_SERVICE_NAME.").
 		Envar("VALIDATING_WEBHOOK_SERVICE_NAME").
 		Default(ValidatingWebhookSettings.ServiceName).
@@ -1,8 +1,10 @@
 package debug
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -61,3 +63,56 @@ func (c *Client) Get(url string) ([]byte, error) {
 
 	return ioutil.ReadAll(resp.Body)
 }
+
+func (c *Client) Post(url string) ([]byte, error) {
+	httpc, err := c.newHttpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpc.Post(url, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = checkResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// PostStream sends a POST request with a body and copies a response into out while it is received.
+func (c *Client) PostStream(url string, contentType string, body io.Reader, out io.Writer) error {
+	httpc, err := c.newHttpClient()
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpc.Post(url, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = checkResponse(resp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// checkResponse returns an error with the response body for not successful requests.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(resp.Body)
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return fmt.Errorf("%s", msg)
+}
@@ -0,0 +1,52 @@
+package debug
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_Client_Post(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "debug_client")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	socketPath := filepath.Join(tmpDir, "debug.socket")
+	listener, err := net.Listen("unix", socketPath)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("done\n"))
+	})
+	mux.HandleFunc("/missing", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = writer.Write([]byte("queue 'main' is not found\n"))
+	})
+	server := &http.Server{Handler: mux}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	client := NewClient()
+	client.WithSocketPath(socketPath)
+
+	resp, err := client.Post("http://unix/ok")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(resp)).Should(Equal("done\n"))
+
+	_, err = client.Post("http://unix/missing")
+	g.Expect(err).Should(MatchError("queue 'main' is not found"))
+
+	// Unknown path without a body.
+	_, err = client.Post("http://unix/unknown")
+	g.Expect(err).Should(HaveOccurred())
+}
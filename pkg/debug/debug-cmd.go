@@ -1,7 +1,12 @@
 package debug
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 
@@ -26,6 +31,214 @@ func DefineDebugCommands(kpApp *kingpin.Application) {
 	AddOutputJsonYamlTextFlag(queueListCmd)
 	app.DefineDebugUnixSocketFlag(queueListCmd)
 
+	// Queue control commands
+	var queueName, taskId string
+	var taskPosition int
+	queuePauseCmd := queueCmd.Command("pause", "Stop handling of tasks in the queue. New tasks are still added, the running task is not interrupted.").
+		Action(func(c *kingpin.ParseContext) error {
+			return printResponse(Queue(DefaultClient()).Pause(queueName))
+		})
+	queuePauseCmd.Arg("queue", "A name of the queue.").Required().StringVar(&queueName)
+	app.DefineDebugUnixSocketFlag(queuePauseCmd)
+
+	queueResumeCmd := queueCmd.Command("resume", "Resume handling of tasks in the paused queue.").
+		Action(func(c *kingpin.ParseContext) error {
+			return printResponse(Queue(DefaultClient()).Resume(queueName))
+		})
+	queueResumeCmd.Arg("queue", "A name of the queue.").Required().StringVar(&queueName)
+	app.DefineDebugUnixSocketFlag(queueResumeCmd)
+
+	queueDrainCmd := queueCmd.Command("drain", "Pause the queue and remove all tasks except the running one.").
+		Action(func(c *kingpin.ParseContext) error {
+			return printResponse(Queue(DefaultClient()).Drain(queueName))
+		})
+	queueDrainCmd.Arg("queue", "A name of the queue.").Required().StringVar(&queueName)
+	app.DefineDebugUnixSocketFlag(queueDrainCmd)
+
+	queueRemoveCmd := queueCmd.Command("remove", "Remove a task from the queue. The running task cannot be removed.").
+		Action(func(c *kingpin.ParseContext) error {
+			return printResponse(Queue(DefaultClient()).RemoveTask(queueName, taskId))
+		})
+	queueRemoveCmd.Arg("queue", "A name of the queue.").Required().StringVar(&queueName)
+	queueRemoveCmd.Arg("id", "An id of the task.").Required().StringVar(&taskId)
+	app.DefineDebugUnixSocketFlag(queueRemoveCmd)
+
+	queueMoveCmd := queueCmd.Command("move", "Move a task to another position in the queue.").
+		Action(func(c *kingpin.ParseContext) error {
+			return printResponse(Queue(DefaultClient()).MoveTask(queueName, taskId, taskPosition))
+		})
+	queueMoveCmd.Arg("queue", "A name of the queue.").Required().StringVar(&queueName)
+	queueMoveCmd.Arg("id", "An id of the task.").Required().StringVar(&taskId)
+	queueMoveCmd.Flag("position", "A new position of the task: 0 is the head, -1 is the tail.").Default("0").IntVar(&taskPosition)
+	app.DefineDebugUnixSocketFlag(queueMoveCmd)
+
+	queueDroppedCmd := queueCmd.Command("dropped", "Dump the last tasks that are dropped after retries.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Queue(DefaultClient()).DroppedList(OutputFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	AddOutputJsonYamlTextFlag(queueDroppedCmd)
+	app.DefineDebugUnixSocketFlag(queueDroppedCmd)
+
+	// Dead letter queue commands
+	dlqCmd := queueCmd.Command("dlq", "Manage tasks that are given up on after retries.")
+
+	dlqListCmd := dlqCmd.Command("list", "Dump tasks in the dead letter queue.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Queue(DefaultClient()).DeadLetterList(OutputFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	AddOutputJsonYamlTextFlag(dlqListCmd)
+	app.DefineDebugUnixSocketFlag(dlqListCmd)
+
+	var dlqTaskId string
+	var dlqAll bool
+	dlqShowCmd := dlqCmd.Command("show", "Show a task with the binding context and the failure message.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Queue(DefaultClient()).DeadLetterShow(dlqTaskId, OutputFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	dlqShowCmd.Arg("id", "An id of the task.").Required().StringVar(&dlqTaskId)
+	AddOutputJsonYamlTextFlag(dlqShowCmd)
+	app.DefineDebugUnixSocketFlag(dlqShowCmd)
+
+	dlqReplayCmd := dlqCmd.Command("replay", "Add a task back to its queue. Use --all to replay all tasks.").
+		Action(func(c *kingpin.ParseContext) error {
+			if err := checkIdOrAll(dlqTaskId, dlqAll); err != nil {
+				return err
+			}
+			resp, err := Queue(DefaultClient()).DeadLetterReplay(dlqTaskId)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(resp))
+			return nil
+		})
+	dlqReplayCmd.Arg("id", "An id of the task.").StringVar(&dlqTaskId)
+	dlqReplayCmd.Flag("all", "Replay all tasks.").BoolVar(&dlqAll)
+	app.DefineDebugUnixSocketFlag(dlqReplayCmd)
+
+	dlqPurgeCmd := dlqCmd.Command("purge", "Remove a task from the dead letter queue. Use --all to remove all tasks.").
+		Action(func(c *kingpin.ParseContext) error {
+			if err := checkIdOrAll(dlqTaskId, dlqAll); err != nil {
+				return err
+			}
+			resp, err := Queue(DefaultClient()).DeadLetterPurge(dlqTaskId)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(resp))
+			return nil
+		})
+	dlqPurgeCmd.Arg("id", "An id of the task.").StringVar(&dlqTaskId)
+	dlqPurgeCmd.Flag("all", "Remove all tasks.").BoolVar(&dlqAll)
+	app.DefineDebugUnixSocketFlag(dlqPurgeCmd)
+
+	// Hook managing commands
+	hookCmd := app.CommandWithDefaultUsageTemplate(kpApp, "hook", "Manage hooks.")
+
+	hookReloadCmd := hookCmd.Command("reload", "Reload changed hooks from hooks dir.").
+		Action(func(c *kingpin.ParseContext) error {
+			resp, err := Hook(DefaultClient()).Reload()
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(resp))
+			return nil
+		})
+	app.DefineDebugUnixSocketFlag(hookReloadCmd)
+
+	var hookName, bindingName, bindingContextPath string
+	var waitHookRun bool
+	hookRunCmd := hookCmd.Command("run", "Queue a hook run for a schedule or a kubernetes binding and print the task id.").
+		Action(func(c *kingpin.ParseContext) error {
+			var bindingContext []byte
+			if bindingContextPath != "" {
+				var err error
+				bindingContext, err = ioutil.ReadFile(bindingContextPath)
+				if err != nil {
+					return err
+				}
+			}
+			return Hook(DefaultClient()).Run(hookName, bindingName, bindingContext, waitHookRun, os.Stdout)
+		})
+	hookRunCmd.Arg("name", "A name of the hook.").Required().StringVar(&hookName)
+	hookRunCmd.Flag("binding", "A name of the schedule or kubernetes binding.").Required().StringVar(&bindingName)
+	hookRunCmd.Flag("binding-context", "A path to a JSON file with a binding context in the format for hooks. Binding context is built from current objects if not set.").
+		StringVar(&bindingContextPath)
+	hookRunCmd.Flag("wait", "Wait for the hook run and print its log lines.").BoolVar(&waitHookRun)
+	app.DefineDebugUnixSocketFlag(hookRunCmd)
+
+	var snapshotsFormat string
+	hookSnapshotsCmd := hookCmd.Command("snapshots", "Dump cached objects of kubernetes bindings: ids, checksums, filter results and sizes.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Hook(DefaultClient()).Snapshots(hookName, bindingName, snapshotsFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	hookSnapshotsCmd.Arg("name", "A name of the hook.").Required().StringVar(&hookName)
+	hookSnapshotsCmd.Flag("binding", "Dump only this kubernetes binding.").StringVar(&bindingName)
+	hookSnapshotsCmd.Flag("output", "Output format: json|yaml.").Short('o').
+		Default("yaml").
+		EnumVar(&snapshotsFormat, "json", "yaml")
+	app.DefineDebugUnixSocketFlag(hookSnapshotsCmd)
+
+	hookListCmd := hookCmd.Command("list", "List hooks with their bindings, queues and runtime state.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Hook(DefaultClient()).List(OutputFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	AddOutputJsonYamlTextFlag(hookListCmd)
+	app.DefineDebugUnixSocketFlag(hookListCmd)
+
+	var configFormat string
+	hookConfigCmd := hookCmd.Command("config", "Dump an effective configuration of the hook with a runtime state of bindings.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Hook(DefaultClient()).Config(hookName, configFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	hookConfigCmd.Arg("name", "A name of the hook.").Required().StringVar(&hookName)
+	hookConfigCmd.Flag("output", "Output format: json|yaml.").Short('o').
+		Default("yaml").
+		EnumVar(&configFormat, "json", "yaml")
+	app.DefineDebugUnixSocketFlag(hookConfigCmd)
+
+	hookHistoryCmd := hookCmd.Command("history", "Dump last executions of the hook from the newest to the oldest.").
+		Action(func(c *kingpin.ParseContext) error {
+			dump, err := Hook(DefaultClient()).History(hookName, OutputFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(dump))
+			return nil
+		})
+	hookHistoryCmd.Arg("name", "A name of the hook.").Required().StringVar(&hookName)
+	AddOutputJsonYamlTextFlag(hookHistoryCmd)
+	app.DefineDebugUnixSocketFlag(hookHistoryCmd)
+
 	// Raw request command
 	var rawUrl string
 	rawCommand := app.CommandWithDefaultUsageTemplate(kpApp, "raw", "Make a raw request to debug endpoint.").
@@ -48,6 +261,24 @@ func AddOutputJsonYamlTextFlag(cmd *kingpin.CmdClause) {
 		EnumVar(&OutputFormat, "json", "yaml", "text")
 }
 
+func printResponse(resp []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(resp))
+	return nil
+}
+
+func checkIdOrAll(id string, all bool) error {
+	if id == "" && !all {
+		return fmt.Errorf("task id or --all flag is required")
+	}
+	if id != "" && all {
+		return fmt.Errorf("task id and --all flag are mutually exclusive")
+	}
+	return nil
+}
+
 type QueueRequest struct {
 	client *Client
 }
@@ -62,3 +293,102 @@ func (qr *QueueRequest) Dump(format string) ([]byte, error) {
 	url := fmt.Sprintf("http://unix/queue/list.%s", format)
 	return qr.client.Get(url)
 }
+
+func (qr *QueueRequest) Pause(name string) ([]byte, error) {
+	return qr.client.Post(fmt.Sprintf("http://unix/queue/%s/pause", url.PathEscape(name)))
+}
+
+func (qr *QueueRequest) Resume(name string) ([]byte, error) {
+	return qr.client.Post(fmt.Sprintf("http://unix/queue/%s/resume", url.PathEscape(name)))
+}
+
+func (qr *QueueRequest) Drain(name string) ([]byte, error) {
+	return qr.client.Post(fmt.Sprintf("http://unix/queue/%s/drain", url.PathEscape(name)))
+}
+
+func (qr *QueueRequest) RemoveTask(name string, id string) ([]byte, error) {
+	return qr.client.Post(fmt.Sprintf("http://unix/queue/%s/task/%s/remove", url.PathEscape(name), url.PathEscape(id)))
+}
+
+func (qr *QueueRequest) MoveTask(name string, id string, position int) ([]byte, error) {
+	return qr.client.Post(fmt.Sprintf("http://unix/queue/%s/task/%s/move?position=%d", url.PathEscape(name), url.PathEscape(id), position))
+}
+
+func (qr *QueueRequest) DroppedList(format string) ([]byte, error) {
+	url := fmt.Sprintf("http://unix/queue/dropped/list.%s", format)
+	return qr.client.Get(url)
+}
+
+func (qr *QueueRequest) DeadLetterList(format string) ([]byte, error) {
+	url := fmt.Sprintf("http://unix/queue/dlq/list.%s", format)
+	return qr.client.Get(url)
+}
+
+func (qr *QueueRequest) DeadLetterShow(id string, format string) ([]byte, error) {
+	url := fmt.Sprintf("http://unix/queue/dlq/task/%s.%s", id, format)
+	return qr.client.Get(url)
+}
+
+// DeadLetterReplay replays a task by id or all tasks if id is empty.
+func (qr *QueueRequest) DeadLetterReplay(id string) ([]byte, error) {
+	url := "http://unix/queue/dlq/replay"
+	if id != "" {
+		url += "/" + id
+	}
+	return qr.client.Post(url)
+}
+
+// DeadLetterPurge removes a task by id or all tasks if id is empty.
+func (qr *QueueRequest) DeadLetterPurge(id string) ([]byte, error) {
+	url := "http://unix/queue/dlq/purge"
+	if id != "" {
+		url += "/" + id
+	}
+	return qr.client.Post(url)
+}
+
+type HookRequest struct {
+	client *Client
+}
+
+func Hook(client *Client) *HookRequest {
+	return &HookRequest{
+		client: client,
+	}
+}
+
+func (hr *HookRequest) Reload() ([]byte, error) {
+	return hr.client.Post("http://unix/hook/reload")
+}
+
+func (hr *HookRequest) List(format string) ([]byte, error) {
+	return hr.client.Get(fmt.Sprintf("http://unix/hook/list.%s", format))
+}
+
+func (hr *HookRequest) Config(name string, format string) ([]byte, error) {
+	return hr.client.Get(fmt.Sprintf("http://unix/hook/%s/config.%s", url.PathEscape(name), format))
+}
+
+func (hr *HookRequest) History(name string, format string) ([]byte, error) {
+	return hr.client.Get(fmt.Sprintf("http://unix/hook/%s/history.%s", url.PathEscape(name), format))
+}
+
+func (hr *HookRequest) Snapshots(name string, binding string, format string) ([]byte, error) {
+	snapshotsUrl := fmt.Sprintf("http://unix/hook/%s/snapshots.%s", url.PathEscape(name), format)
+	if binding != "" {
+		snapshotsUrl += "?binding=" + url.QueryEscape(binding)
+	}
+	return hr.client.Get(snapshotsUrl)
+}
+
+// Run queues a hook run and writes the task id into out. Log lines of the hook run
+// and the result are written if wait is true.
+func (hr *HookRequest) Run(name string, binding string, bindingContext []byte, wait bool, out io.Writer) error {
+	query := url.Values{}
+	query.Set("binding", binding)
+	if wait {
+		query.Set("wait", "true")
+	}
+	runUrl := fmt.Sprintf("http://unix/hook/%s/run?%s", url.PathEscape(name), query.Encode())
+	return hr.client.PostStream(runUrl, "application/json", bytes.NewReader(bindingContext), out)
+}
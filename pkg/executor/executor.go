@@ -2,9 +2,12 @@ package executor
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -13,10 +16,61 @@ import (
 	utils "github.com/flant/shell-operator/pkg/utils/labels"
 )
 
+// TerminationGracePeriod is a time between SIGTERM and SIGKILL for a command terminated by timeout.
+var TerminationGracePeriod = 5 * time.Second
+
+// OutputTailLines is a number of last lines of stdout and stderr that are kept in CmdUsage.
+// Longer lines are truncated to OutputTailLineLength bytes.
+var OutputTailLines = 20
+var OutputTailLineLength = 1024
+
+// CmdUsage is a resource usage and a result of the command.
 type CmdUsage struct {
 	Sys    time.Duration
 	User   time.Duration
 	MaxRss int64
+
+	ExitCode   int
+	StdoutTail []string
+	StderrTail []string
+}
+
+// String returns only the resource usage. Use fields to get output tails.
+func (u *CmdUsage) String() string {
+	return fmt.Sprintf("{Sys:%s User:%s MaxRss:%d}", u.Sys, u.User, u.MaxRss)
+}
+
+// outputTail keeps last lines of the command output.
+type outputTail struct {
+	lines []string
+}
+
+func (t *outputTail) Add(line string) {
+	if OutputTailLines <= 0 {
+		return
+	}
+	if len(line) > OutputTailLineLength {
+		line = line[:OutputTailLineLength] + "..."
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > OutputTailLines {
+		t.lines = t.lines[len(t.lines)-OutputTailLines:]
+	}
+}
+
+// TimeoutError is returned if the command is terminated after the timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("terminated after timeout %s", e.Timeout.String())
+}
+
+// IsTimeoutError returns true if err is a TimeoutError.
+func IsTimeoutError(err error) bool {
+	_, ok := err.(*TimeoutError)
+	return ok
 }
 
 func Run(cmd *exec.Cmd) error {
@@ -28,6 +82,13 @@ func Run(cmd *exec.Cmd) error {
 }
 
 func RunAndLogLines(cmd *exec.Cmd, logLabels map[string]string) (*CmdUsage, error) {
+	return RunAndLogLinesWithTimeout(cmd, logLabels, 0)
+}
+
+// RunAndLogLinesWithTimeout runs a command in a separate process group. If timeout is exceeded,
+// the group is terminated with SIGTERM and then with SIGKILL after TerminationGracePeriod.
+// Output is not read after one more TerminationGracePeriod. Zero timeout means no timeout.
+func RunAndLogLinesWithTimeout(cmd *exec.Cmd, logLabels map[string]string, timeout time.Duration) (*CmdUsage, error) {
 	// TODO observability
 	logEntry := log.WithFields(utils.LabelsToLogFields(logLabels))
 	stdoutLogEntry := logEntry.WithField("output", "stdout")
@@ -36,6 +97,7 @@ func RunAndLogLines(cmd *exec.Cmd, logLabels map[string]string) (*CmdUsage, erro
 	logEntry.Debugf("Executing command '%s' in '%s' dir", strings.Join(cmd.Args, " "), cmd.Dir)
 
 	var wg sync.WaitGroup
+	var stdoutTail, stderrTail outputTail
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -47,19 +109,46 @@ func RunAndLogLines(cmd *exec.Cmd, logLabels map[string]string) (*CmdUsage, erro
 		return nil, err
 	}
 
+	if timeout > 0 {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+
 	err = cmd.Start()
 	if err != nil {
 		return nil, err
 	}
 
-	//	cmd.Process.Pid
+	var timedOut int32
+	if timeout > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-time.After(timeout):
+				atomic.StoreInt32(&timedOut, 1)
+				logEntry.Warnf("Command is running longer than %s, terminate it", timeout.String())
+				TerminateProcessGroup(cmd, done)
+				// A process that left the group can keep the output open, stop reading it
+				// after the grace period.
+				select {
+				case <-done:
+				case <-time.After(TerminationGracePeriod):
+					logEntry.Warnf("Command output is still open after termination, stop reading it")
+					_ = stdout.Close()
+					_ = stderr.Close()
+				}
+			case <-done:
+			}
+		}()
+	}
 
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
-			stdoutLogEntry.Info(scanner.Text())
+			LogOutputLine(stdoutLogEntry, scanner.Text())
+			stdoutTail.Add(scanner.Text())
 		}
 	}()
 
@@ -67,7 +156,8 @@ func RunAndLogLines(cmd *exec.Cmd, logLabels map[string]string) (*CmdUsage, erro
 		defer wg.Done()
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			stderrLogEntry.Info(scanner.Text())
+			LogOutputLine(stderrLogEntry, scanner.Text())
+			stderrTail.Add(scanner.Text())
 		}
 	}()
 
@@ -78,8 +168,11 @@ func RunAndLogLines(cmd *exec.Cmd, logLabels map[string]string) (*CmdUsage, erro
 	var usage *CmdUsage = nil
 	if cmd.ProcessState != nil {
 		usage = &CmdUsage{
-			Sys:  cmd.ProcessState.SystemTime(),
-			User: cmd.ProcessState.UserTime(),
+			Sys:        cmd.ProcessState.SystemTime(),
+			User:       cmd.ProcessState.UserTime(),
+			ExitCode:   cmd.ProcessState.ExitCode(),
+			StdoutTail: stdoutTail.lines,
+			StderrTail: stderrTail.lines,
 		}
 		// FIXME Maxrss is Unix specific.
 		sysUsage := cmd.ProcessState.SysUsage()
@@ -89,9 +182,26 @@ func RunAndLogLines(cmd *exec.Cmd, logLabels map[string]string) (*CmdUsage, erro
 		}
 	}
 
+	if atomic.LoadInt32(&timedOut) == 1 {
+		err = &TimeoutError{Timeout: timeout}
+	}
+
 	return usage, err
 }
 
+// TerminateProcessGroup sends SIGTERM to the process group of the started command and
+// SIGKILL after TerminationGracePeriod if done is not closed. The command should be started
+// with Setpgid.
+func TerminateProcessGroup(cmd *exec.Cmd, done <-chan struct{}) {
+	pgid := cmd.Process.Pid
+	_ = syscall.Kill(-pgid, syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(TerminationGracePeriod):
+		_ = syscall.Kill(-pgid, syscall.SIGKILL)
+	}
+}
+
 func Output(cmd *exec.Cmd) (output []byte, err error) {
 	// TODO context: hook name, hook phase, hook binding
 	// TODO observability
@@ -100,6 +210,49 @@ func Output(cmd *exec.Cmd) (output []byte, err error) {
 	return
 }
 
+// OutputWithTimeout is an Output that runs the command in a separate process group and
+// terminates the group if timeout is exceeded. Zero timeout means no timeout.
+func OutputWithTimeout(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		return Output(cmd)
+	}
+	log.Debugf("Executing command '%s' in '%s' dir with timeout %s", strings.Join(cmd.Args, " "), cmd.Dir, timeout.String())
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	var timedOut int32
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-time.After(timeout):
+			atomic.StoreInt32(&timedOut, 1)
+			log.Warnf("Command '%s' is running longer than %s, terminate it", strings.Join(cmd.Args, " "), timeout.String())
+			TerminateProcessGroup(cmd, done)
+		case <-done:
+		}
+	}()
+
+	err = cmd.Wait()
+	close(done)
+
+	if atomic.LoadInt32(&timedOut) == 1 {
+		return stdout.Bytes(), &TimeoutError{Timeout: timeout}
+	}
+	if ee, ok := err.(*exec.ExitError); ok {
+		// Keep stderr for error messages as exec.Cmd.Output does.
+		ee.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
 func MakeCommand(dir string, entrypoint string, args []string, envs []string) *exec.Cmd {
 	cmd := exec.Command(entrypoint, args...)
 	cmd.Env = append(cmd.Env, envs...)
@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_RunAndLogLinesWithTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	defer func(d time.Duration) { TerminationGracePeriod = d }(TerminationGracePeriod)
+	TerminationGracePeriod = 200 * time.Millisecond
+
+	// Command is finished in time.
+	cmd := MakeCommand("", "sh", []string{"-c", "echo ok"}, os.Environ())
+	_, err := RunAndLogLinesWithTimeout(cmd, map[string]string{}, time.Second)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	// SIGTERM is ignored by the shell and its child, so the group is killed after the grace period.
+	cmd = MakeCommand("", "sh", []string{"-c", "trap '' TERM; sleep 10; echo done"}, os.Environ())
+	start := time.Now()
+	_, err = RunAndLogLinesWithTimeout(cmd, map[string]string{}, 100*time.Millisecond)
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(IsTimeoutError(err)).Should(BeTrue())
+	g.Expect(time.Since(start)).Should(BeNumerically("<", 5*time.Second))
+
+	// A process in a new session is not killed with the group and keeps the output open.
+	if _, err := exec.LookPath("setsid"); err == nil {
+		cmd = MakeCommand("", "sh", []string{"-c", "setsid sleep 10 & sleep 10"}, os.Environ())
+		start = time.Now()
+		_, err = RunAndLogLinesWithTimeout(cmd, map[string]string{}, 100*time.Millisecond)
+		g.Expect(IsTimeoutError(err)).Should(BeTrue())
+		g.Expect(time.Since(start)).Should(BeNumerically("<", 5*time.Second))
+	}
+}
+
+func Test_OutputWithTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	defer func(d time.Duration) { TerminationGracePeriod = d }(TerminationGracePeriod)
+	TerminationGracePeriod = 200 * time.Millisecond
+
+	cmd := MakeCommand("", "sh", []string{"-c", "echo ok"}, os.Environ())
+	output, err := OutputWithTimeout(cmd, time.Second)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(output)).Should(Equal("ok\n"))
+
+	cmd = MakeCommand("", "sh", []string{"-c", "echo fail >&2; exit 1"}, os.Environ())
+	_, err = OutputWithTimeout(cmd, time.Second)
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(string(err.(*exec.ExitError).Stderr)).Should(Equal("fail\n"))
+
+	cmd = MakeCommand("", "sh", []string{"-c", "sleep 10; echo done"}, os.Environ())
+	start := time.Now()
+	_, err = OutputWithTimeout(cmd, 100*time.Millisecond)
+	g.Expect(IsTimeoutError(err)).Should(BeTrue())
+	g.Expect(time.Since(start)).Should(BeNumerically("<", 5*time.Second))
+}
+
+func Test_RunAndLogLines_OutputTail(t *testing.T) {
+	g := NewWithT(t)
+
+	defer func(n int) { OutputTailLines = n }(OutputTailLines)
+	OutputTailLines = 2
+
+	cmd := MakeCommand("", "sh", []string{"-c", "echo 1; echo 2; echo 3; echo err >&2; exit 3"}, os.Environ())
+	usage, err := RunAndLogLines(cmd, map[string]string{})
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(usage).ShouldNot(BeNil())
+	g.Expect(usage.ExitCode).Should(Equal(3))
+	g.Expect(usage.StdoutTail).Should(Equal([]string{"2", "3"}))
+	g.Expect(usage.StderrTail).Should(Equal([]string{"err"}))
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	utils "github.com/flant/shell-operator/pkg/utils/labels"
+)
+
+/*
+A hook can write structured log lines: JSON objects with a "msg" or "message" field
+and an optional "level" field. Other fields are added to the log entry. Fields that
+collide with log labels or with logrus fields are prefixed with "hook.".
+
+  {"level":"warning","msg":"pod is not ready","pod":"web-1"}
+
+Other lines are logged as is with the Info level.
+*/
+
+var messageKeys = []string{"msg", "message"}
+var levelKeys = []string{"level", "severity"}
+
+// LogOutputLine logs a line of the hook output with the entry.
+func LogOutputLine(entry *log.Entry, line string) {
+	level, msg, fields, ok := ParseStructuredLine(line)
+	if !ok {
+		entry.Info(line)
+		return
+	}
+	for k, v := range fields {
+		if _, has := entry.Data[k]; has || k == log.FieldKeyMsg || k == log.FieldKeyLevel || k == log.FieldKeyTime {
+			k = "hook." + k
+		}
+		entry = entry.WithField(k, v)
+	}
+	entry.Log(level, msg)
+}
+
+// LogFileLines logs lines from the file with the "output" field set to "log". Empty lines are ignored.
+func LogFileLines(filePath string, logLabels map[string]string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entry := log.WithFields(utils.LabelsToLogFields(logLabels)).WithField("output", "log")
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		LogOutputLine(entry, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// ParseStructuredLine returns a level, a message and other fields of the JSON log line.
+// ok is false for lines that are not JSON objects or have no message and level.
+func ParseStructuredLine(line string) (level log.Level, msg string, fields map[string]interface{}, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return log.InfoLevel, "", nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewBufferString(trimmed))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return log.InfoLevel, "", nil, false
+	}
+
+	hasMsg := false
+	for _, key := range messageKeys {
+		if v, has := fields[key]; has {
+			msg = fmt.Sprint(v)
+			hasMsg = true
+			delete(fields, key)
+			break
+		}
+	}
+
+	level = log.InfoLevel
+	hasLevel := false
+	for _, key := range levelKeys {
+		if v, has := fields[key]; has {
+			level = ParseHookLogLevel(fmt.Sprint(v))
+			hasLevel = true
+			delete(fields, key)
+			break
+		}
+	}
+
+	if !hasMsg && !hasLevel {
+		return log.InfoLevel, "", nil, false
+	}
+	return level, msg, fields, true
+}
+
+// ParseHookLogLevel converts a level name to a logrus level. Unknown levels are Info.
+// Fatal and panic levels are converted to Error to not stop the operator.
+func ParseHookLogLevel(name string) log.Level {
+	switch strings.ToLower(name) {
+	case "critical", "fatal", "panic", "err":
+		return log.ErrorLevel
+	case "warn":
+		return log.WarnLevel
+	}
+	level, err := log.ParseLevel(name)
+	if err != nil {
+		return log.InfoLevel
+	}
+	if level < log.ErrorLevel {
+		return log.ErrorLevel
+	}
+	return level
+}
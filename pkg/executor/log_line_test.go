@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func Test_LogOutputLine(t *testing.T) {
+	g := NewWithT(t)
+
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(log.DebugLevel)
+	entry := logger.WithFields(log.Fields{"hook": "hook.sh", "output": "stdout"})
+
+	LogOutputLine(entry, `{"level":"WARNING","msg":"pod is not ready","pod":"web-1","count":2,"hook":"python","time":"now"}`)
+	last := hook.LastEntry()
+	g.Expect(last.Level).Should(Equal(log.WarnLevel))
+	g.Expect(last.Message).Should(Equal("pod is not ready"))
+	g.Expect(last.Data).Should(HaveKeyWithValue("pod", "web-1"))
+	g.Expect(last.Data).Should(HaveKeyWithValue("count", json.Number("2")))
+	g.Expect(last.Data).Should(HaveKeyWithValue("hook", "hook.sh"))
+	g.Expect(last.Data).Should(HaveKeyWithValue("hook.hook", "python"))
+	g.Expect(last.Data).Should(HaveKeyWithValue("hook.time", "now"))
+
+	LogOutputLine(entry, `{"severity":"critical","message":"failed"}`)
+	g.Expect(hook.LastEntry().Level).Should(Equal(log.ErrorLevel))
+	g.Expect(hook.LastEntry().Message).Should(Equal("failed"))
+
+	// Plain lines and JSON without a message or a level are logged as is.
+	for _, line := range []string{"plain line", `{"a":1}`, `{"msg": broken`} {
+		LogOutputLine(entry, line)
+		g.Expect(hook.LastEntry().Level).Should(Equal(log.InfoLevel))
+		g.Expect(hook.LastEntry().Message).Should(Equal(line))
+	}
+}
+
+func Test_ParseHookLogLevel(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ParseHookLogLevel("debug")).Should(Equal(log.DebugLevel))
+	g.Expect(ParseHookLogLevel("warn")).Should(Equal(log.WarnLevel))
+	g.Expect(ParseHookLogLevel("fatal")).Should(Equal(log.ErrorLevel))
+	g.Expect(ParseHookLogLevel("unknown")).Should(Equal(log.InfoLevel))
+}
+
+func Test_LogFileLines(t *testing.T) {
+	g := NewWithT(t)
+
+	hook := test.NewGlobal()
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.DebugLevel)
+
+	tmpDir, err := ioutil.TempDir("", "log-lines")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	logPath := filepath.Join(tmpDir, "log.jsonl")
+	err = ioutil.WriteFile(logPath, []byte("{\"level\":\"debug\",\"msg\":\"one\"}\n\ntwo\n"), 0644)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	err = LogFileLines(logPath, map[string]string{"hook": "hook.sh"})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(hook.AllEntries()).Should(HaveLen(2))
+	g.Expect(hook.AllEntries()[0].Level).Should(Equal(log.DebugLevel))
+	g.Expect(hook.AllEntries()[0].Data).Should(HaveKeyWithValue("output", "log"))
+	g.Expect(hook.AllEntries()[1].Message).Should(Equal("two"))
+}
@@ -0,0 +1,199 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	utils "github.com/flant/shell-operator/pkg/utils/labels"
+)
+
+// WorkerStopTimeout is a time to wait for a worker process to exit after closing its stdin.
+var WorkerStopTimeout = 5 * time.Second
+
+// WorkerRequestTimeout is a timeout for requests sent with Request.
+var WorkerRequestTimeout = time.Minute
+
+// Worker is a long-lived process that reads requests from stdin and writes
+// responses to stdout, one JSON object per line. Stderr lines are logged.
+//
+// The process is started on the first request and restarted if it exits.
+// Requests are serialized: the next request is sent after the response for
+// the previous one is received.
+type Worker struct {
+	Dir        string
+	Entrypoint string
+	Args       []string
+	Envs       []string
+	LogLabels  map[string]string
+
+	m      sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	// exited is closed when stderr of the process is closed.
+	exited chan struct{}
+}
+
+func NewWorker(dir string, entrypoint string, args []string, envs []string) *Worker {
+	return &Worker{
+		Dir:        dir,
+		Entrypoint: entrypoint,
+		Args:       args,
+		Envs:       envs,
+		LogLabels:  map[string]string{},
+	}
+}
+
+func (w *Worker) WithLogLabels(logLabels map[string]string) {
+	w.LogLabels = utils.MergeLabels(logLabels)
+}
+
+// Request sends req as a JSON line and decodes one line of response into resp.
+// The worker is terminated if the response is not received in WorkerRequestTimeout.
+func (w *Worker) Request(req interface{}, resp interface{}) error {
+	return w.RequestWithTimeout(req, resp, WorkerRequestTimeout)
+}
+
+// RequestWithTimeout is a Request that terminates the process group of the worker
+// if the response is not received in time. Zero timeout means no timeout.
+func (w *Worker) RequestWithTimeout(req interface{}, resp interface{}, timeout time.Duration) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	err = w.ensureStarted()
+	if err != nil {
+		return err
+	}
+
+	var timedOut int32
+	if timeout > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go func(cmd *exec.Cmd, exited chan struct{}) {
+			select {
+			case <-time.After(timeout):
+				atomic.StoreInt32(&timedOut, 1)
+				log.WithFields(utils.LabelsToLogFields(w.LogLabels)).
+					Warnf("Worker is handling request longer than %s, terminate it", timeout.String())
+				TerminateProcessGroup(cmd, exited)
+			case <-done:
+			}
+		}(w.cmd, w.exited)
+	}
+
+	_, err = w.stdin.Write(append(data, '\n'))
+	if err != nil {
+		w.stop()
+		return fmt.Errorf("write request to worker: %v", err)
+	}
+
+	line, err := w.stdout.ReadBytes('\n')
+	if err != nil {
+		w.stop()
+		if atomic.LoadInt32(&timedOut) == 1 {
+			return &TimeoutError{Timeout: timeout}
+		}
+		return fmt.Errorf("read response from worker: %v", err)
+	}
+
+	err = json.Unmarshal(line, resp)
+	if err != nil {
+		w.stop()
+		return fmt.Errorf("bad response from worker: %v", err)
+	}
+
+	return nil
+}
+
+// Stop closes stdin of the process and waits for it to exit. The process is killed after WorkerStopTimeout.
+func (w *Worker) Stop() {
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.stop()
+}
+
+func (w *Worker) ensureStarted() error {
+	if w.cmd != nil {
+		select {
+		case <-w.exited:
+			log.WithFields(utils.LabelsToLogFields(w.LogLabels)).Warnf("Worker process has exited, restart it")
+			w.stop()
+		default:
+			return nil
+		}
+	}
+
+	cmd := MakeCommand(w.Dir, w.Entrypoint, w.Args, w.Envs)
+	// A separate process group to terminate worker and its children on timeout.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	logEntry := log.WithFields(utils.LabelsToLogFields(w.LogLabels))
+	logEntry.Debugf("Starting worker '%s' in '%s' dir", strings.Join(cmd.Args, " "), cmd.Dir)
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		stderrLogEntry := logEntry.WithField("output", "stderr")
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			LogOutputLine(stderrLogEntry, scanner.Text())
+		}
+	}()
+
+	w.cmd = cmd
+	w.stdin = stdin
+	w.stdout = bufio.NewReader(stdout)
+	w.exited = exited
+	return nil
+}
+
+func (w *Worker) stop() {
+	if w.cmd == nil {
+		return
+	}
+
+	_ = w.stdin.Close()
+	select {
+	case <-w.exited:
+	case <-time.After(WorkerStopTimeout):
+		_ = w.cmd.Process.Kill()
+	}
+	_ = w.cmd.Wait()
+
+	w.cmd = nil
+	w.stdin = nil
+	w.stdout = nil
+}
@@ -9,6 +9,7 @@ import (
 
 	. "github.com/flant/shell-operator/pkg/hook/types"
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
 )
 
 // Information about event for hook
@@ -30,6 +31,15 @@ type BindingContext struct {
 	Objects    []ObjectAndFilterResult
 	Snapshots  map[string][]ObjectAndFilterResult
 	Review     *v1.AdmissionReview
+	// additional field for 'kubernetesCustomResourceConversion' binding
+	ConversionReview *ConversionReview
+	// an event for each item in Objects for debounced 'kubernetes' bindings
+	WatchEvents []WatchEventType
+}
+
+// IsBatch returns true for an "Event" binding context of a debounced binding.
+func (bc BindingContext) IsBatch() bool {
+	return bc.Type == TypeEvent && len(bc.WatchEvents) > 0 && len(bc.WatchEvents) == len(bc.Objects)
 }
 
 func (bc BindingContext) MarshalJSON() ([]byte, error) {
@@ -64,8 +74,8 @@ func (bc BindingContext) MapV1() map[string]interface{} {
 		}
 	}
 
-	// KubernetesValidating uses 'group' only for snapshots.
-	if bc.Metadata.Group != "" && bc.Metadata.BindingType != KubernetesValidating {
+	// Webhook bindings use 'group' only for snapshots.
+	if bc.Metadata.Group != "" && !IsWebhookBinding(bc.Metadata.BindingType) {
 		res["binding"] = bc.Metadata.Group
 		res["type"] = "Group"
 		return res
@@ -82,6 +92,18 @@ func (bc BindingContext) MapV1() map[string]interface{} {
 		return res
 	}
 
+	if bc.Metadata.BindingType == KubernetesMutating {
+		res["type"] = "Mutating"
+		res["review"] = bc.Review
+		return res
+	}
+
+	if bc.Metadata.BindingType == KubernetesConversion {
+		res["type"] = "Conversion"
+		res["review"] = bc.ConversionReview
+		return res
+	}
+
 	if bc.Metadata.BindingType != OnKubernetesEvent || bc.Type == "" {
 		return res
 	}
@@ -100,7 +122,16 @@ func (bc BindingContext) MapV1() map[string]interface{} {
 			res["objects"] = bc.Objects
 		}
 	case TypeEvent:
-		if len(bc.Objects) == 0 {
+		if bc.IsBatch() {
+			// Debounced events: each item has watchEvent, object and filterResult.
+			objects := make([]map[string]interface{}, 0, len(bc.Objects))
+			for i, obj := range bc.Objects {
+				item := obj.Map()
+				item["watchEvent"] = string(bc.WatchEvents[i])
+				objects = append(objects, item)
+			}
+			res["objects"] = objects
+		} else if len(bc.Objects) == 0 {
 			res["object"] = nil
 			if bc.Metadata.JqFilter != "" {
 				res["filterResult"] = ""
@@ -54,6 +54,47 @@ definitions:
               type: array
               items:
                 type: string
+  queuePolicy:
+    type: object
+    additionalProperties: false
+    properties:
+      dedupe:
+        type: string
+        enum:
+        - none
+        - byObject
+      maxPending:
+        type: integer
+        minimum: 1
+      onOverflow:
+        type: string
+        enum:
+        - dropOldest
+        - compact
+      concurrency:
+        type: integer
+        minimum: 1
+      orderBy:
+        type: string
+        enum:
+        - object
+        - namespace
+  retry:
+    type: object
+    additionalProperties: false
+    properties:
+      maxAttempts:
+        type: integer
+        minimum: 1
+      initialDelay:
+        type: string
+      maxDelay:
+        type: string
+      onExhausted:
+        type: string
+        enum:
+        - drop
+        - deadLetter
 
 type: object
 additionalProperties: false
@@ -65,6 +106,11 @@ properties:
     type: string
     enum:
     - v1
+  runtime:
+    type: string
+    enum:
+    - exec
+    - worker
   onStartup:
     title: onStartup binding
     description: |
@@ -99,6 +145,12 @@ properties:
             type: string
         queue:
           type: string
+        queuePolicy:
+          "$ref": "#/definitions/queuePolicy"
+        timeout:
+          type: string
+        retry:
+          "$ref": "#/definitions/retry"
         group:
           type: string
   kubernetes:
@@ -137,11 +189,31 @@ properties:
             type: string
         queue:
           type: string
+        queuePolicy:
+          "$ref": "#/definitions/queuePolicy"
+        timeout:
+          type: string
+        retry:
+          "$ref": "#/definitions/retry"
+        debounce:
+          type: string
+          example: "5s"
+        batchWindow:
+          type: string
+          example: "5s"
+        maxWait:
+          type: string
+          example: "30s"
         jqFilter:
           type: string
           example: ".metadata.labels"
         keepFullObjectsInMemory:
           type: boolean
+        watchMode:
+          type: string
+          enum:
+          - full
+          - metadataOnly
         allowFailure:
           type: boolean
         executeHookOnSynchronization:
@@ -274,6 +346,121 @@ properties:
                 - "Cluster"
                 - "Namespaced"
                 - "*"
+  kubernetesMutating:
+    title: MutatingWebhookConfiguration handlers
+    type: array
+    additionalItems: false
+    minItems: 1
+    items:
+      type: object
+      additionalProperties: false
+      required:
+      - name
+      properties:
+        name:
+          type: string
+        group:
+          type: string
+        includeSnapshotsFrom:
+          type: array
+          additionalItems: false
+          minItems: 1
+          items:
+            type: string
+        failurePolicy:
+          type: string
+          enum:
+          - Ignore
+          - Fail
+        sideEffects:
+          type: string
+          enum:
+          - None
+          - NoneOnDryRun
+        timeoutSeconds:
+          type: integer
+          example: 10
+        reinvocationPolicy:
+          type: string
+          enum:
+          - Never
+          - IfNeeded
+        labelSelector:
+          "$ref": "#/definitions/labelSelector"
+        namespace:
+          type: object
+          additionalProperties: false
+          required:
+          - labelSelector
+          properties:
+            labelSelector:
+              "$ref": "#/definitions/labelSelector"
+        rules:
+          type: array
+          additionalItems: false
+          minItems: 1
+          items:
+            type: object
+            additionalProperties: false
+            required:
+              - apiVersions
+              - apiGroups
+              - resources
+              - operations
+            properties:
+              apiVersions:
+                type: array
+                minItems: 1
+                items:
+                  type: string
+              apiGroups:
+                type: array
+                minItems: 1
+                items:
+                  type: string
+              resources:
+                type: array
+                minItems: 1
+                items:
+                  type: string
+              operations:
+                type: array
+                minItems: 1
+                items:
+                  type: string
+                  enum:
+                  - "CREATE"
+                  - "UPDATE"
+                  - "*"
+              scope:
+                type: string
+                enum:
+                - "Cluster"
+                - "Namespaced"
+                - "*"
+  kubernetesCustomResourceConversion:
+    title: CustomResourceDefinition conversion handlers
+    type: array
+    additionalItems: false
+    minItems: 1
+    items:
+      type: object
+      additionalProperties: false
+      required:
+      - crdName
+      properties:
+        name:
+          type: string
+        crdName:
+          type: string
+        group:
+          type: string
+        includeSnapshotsFrom:
+          type: array
+          additionalItems: false
+          minItems: 1
+          items:
+            type: string
 `,
 	"v0": `
 type: object
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
+
+	"github.com/flant/shell-operator/pkg/validating_webhook"
+)
+
+// A link between a hook and a CRD conversion webhook
+type ConversionBindingToWebhookLink struct {
+	BindingName string
+	WebhookId   string
+	CrdName     string
+	// Useful fields to create a BindingContext
+	IncludeSnapshots []string
+	Group            string
+}
+
+// ConversionBindingsController handles kubernetesCustomResourceConversion bindings for one hook.
+type ConversionBindingsController interface {
+	WithConversionBindings([]ConversionConfig)
+	WithWebhookManager(*validating_webhook.WebhookManager)
+	EnableConversionBindings()
+	DisableConversionBindings()
+	CanHandleEvent(event ConversionEvent) bool
+	HandleEvent(event ConversionEvent) BindingExecutionInfo
+}
+
+type conversionBindingsController struct {
+	// WebhookId -> link
+	ConversionLinks map[string]*ConversionBindingToWebhookLink
+
+	ConversionBindings []ConversionConfig
+
+	webhookManager *validating_webhook.WebhookManager
+}
+
+var _ ConversionBindingsController = &conversionBindingsController{}
+
+// NewConversionBindingsController returns an implementation of ConversionBindingsController
+var NewConversionBindingsController = func() *conversionBindingsController {
+	return &conversionBindingsController{
+		ConversionLinks: make(map[string]*ConversionBindingToWebhookLink),
+	}
+}
+
+func (c *conversionBindingsController) WithConversionBindings(bindings []ConversionConfig) {
+	c.ConversionBindings = bindings
+}
+
+func (c *conversionBindingsController) WithWebhookManager(mgr *validating_webhook.WebhookManager) {
+	c.webhookManager = mgr
+}
+
+func (c *conversionBindingsController) EnableConversionBindings() {
+	for _, config := range c.ConversionBindings {
+		c.ConversionLinks[config.Webhook.Metadata.WebhookId] = &ConversionBindingToWebhookLink{
+			BindingName:      config.BindingName,
+			WebhookId:        config.Webhook.Metadata.WebhookId,
+			CrdName:          config.Webhook.CrdName,
+			IncludeSnapshots: config.IncludeSnapshotsFrom,
+			Group:            config.Group,
+		}
+		c.webhookManager.AddConversionWebhook(config.Webhook)
+	}
+}
+
+// DisableConversionBindings removes webhooks from WebhookManager. CustomResourceDefinitions are not changed.
+func (c *conversionBindingsController) DisableConversionBindings() {
+	for _, config := range c.ConversionBindings {
+		c.webhookManager.RemoveConversionWebhook(config.Webhook)
+		delete(c.ConversionLinks, config.Webhook.Metadata.WebhookId)
+	}
+}
+
+func (c *conversionBindingsController) CanHandleEvent(event ConversionEvent) bool {
+	_, has := c.ConversionLinks[event.WebhookId]
+	return has
+}
+
+func (c *conversionBindingsController) HandleEvent(event ConversionEvent) BindingExecutionInfo {
+	link, hasKey := c.ConversionLinks[event.WebhookId]
+	if !hasKey {
+		log.Errorf("Possible bug!!! Unknown conversion event: no binding for webhookId '%s'", event.WebhookId)
+		return BindingExecutionInfo{
+			BindingContext: []BindingContext{},
+			AllowFailure:   false,
+		}
+	}
+
+	bc := BindingContext{
+		Binding:          link.BindingName,
+		ConversionReview: event.Review,
+	}
+	bc.Metadata.BindingType = KubernetesConversion
+	bc.Metadata.IncludeSnapshots = link.IncludeSnapshots
+	bc.Metadata.Group = link.Group
+
+	return BindingExecutionInfo{
+		BindingContext:   []BindingContext{bc},
+		Binding:          link.BindingName,
+		IncludeSnapshots: link.IncludeSnapshots,
+		Group:            link.Group,
+	}
+}
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"fmt"
+
 	. "github.com/flant/shell-operator/pkg/hook/binding_context"
 	. "github.com/flant/shell-operator/pkg/hook/types"
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
@@ -17,6 +19,7 @@ type BindingExecutionInfo struct {
 	IncludeAllSnapshots    bool
 	AllowFailure           bool
 	QueueName              string
+	QueuePolicy            *QueuePolicy
 	Binding                string
 	Group                  string
 	WaitForSynchronization bool
@@ -37,10 +40,14 @@ type HookController interface {
 	InitKubernetesBindings([]OnKubernetesEventConfig, kube_events_manager.KubeEventsManager)
 	InitScheduleBindings([]ScheduleConfig, schedule_manager.ScheduleManager)
 	InitValidatingBindings([]ValidatingConfig, *validating_webhook.WebhookManager)
+	InitMutatingBindings([]MutatingConfig, *validating_webhook.WebhookManager)
+	InitConversionBindings([]ConversionConfig, *validating_webhook.WebhookManager)
 
 	CanHandleKubeEvent(kubeEvent KubeEvent) bool
 	CanHandleScheduleEvent(crontab string) bool
 	CanHandleValidatingEvent(event ValidatingEvent) bool
+	CanHandleMutatingEvent(event MutatingEvent) bool
+	CanHandleConversionEvent(event ConversionEvent) bool
 
 	// These method should call underlying BindingController to get binding context
 	// and then add Snapshots to binding context
@@ -48,17 +55,30 @@ type HookController interface {
 	HandleKubeEvent(event KubeEvent, createTasksFn func(BindingExecutionInfo))
 	HandleScheduleEvent(crontab string, createTasksFn func(BindingExecutionInfo))
 	HandleValidatingEvent(event ValidatingEvent, createTasksFn func(BindingExecutionInfo))
+	HandleMutatingEvent(event MutatingEvent, createTasksFn func(BindingExecutionInfo))
+	HandleConversionEvent(event ConversionEvent, createTasksFn func(BindingExecutionInfo))
+	HandleManualRun(bindingName string, bindingContext []BindingContext, createTasksFn func(BindingExecutionInfo)) error
 
 	StartMonitors()
 	StopMonitors()
+	StopMonitor(bindingName string)
 
 	EnableScheduleBindings()
 	DisableScheduleBindings()
 
 	EnableValidatingBindings()
+	EnableMutatingBindings()
+	EnableConversionBindings()
+
+	DisableValidatingBindings()
+	DisableMutatingBindings()
+	DisableConversionBindings()
 
 	KubernetesSnapshots() map[string][]ObjectAndFilterResult
 	UpdateSnapshots([]BindingContext) []BindingContext
+
+	IsMonitorStarted(bindingName string) bool
+	IsScheduleEnabled(bindingName string) bool
 }
 
 var _ HookController = &hookController{}
@@ -71,9 +91,13 @@ type hookController struct {
 	KubernetesController KubernetesBindingsController
 	ScheduleController   ScheduleBindingsController
 	ValidatingController ValidatingBindingsController
+	MutatingController   MutatingBindingsController
+	ConversionController ConversionBindingsController
 	kubernetesBindings   []OnKubernetesEventConfig
 	scheduleBindings     []ScheduleConfig
 	validatingBindings   []ValidatingConfig
+	mutatingBindings     []MutatingConfig
+	conversionBindings   []ConversionConfig
 }
 
 func (hc *hookController) InitKubernetesBindings(bindings []OnKubernetesEventConfig, kubeEventMgr kube_events_manager.KubeEventsManager) {
@@ -112,6 +136,30 @@ func (hc *hookController) InitValidatingBindings(bindings []ValidatingConfig, we
 	hc.validatingBindings = bindings
 }
 
+func (hc *hookController) InitMutatingBindings(bindings []MutatingConfig, webhookMgr *validating_webhook.WebhookManager) {
+	if len(bindings) == 0 {
+		return
+	}
+
+	bindingCtrl := NewMutatingBindingsController()
+	bindingCtrl.WithWebhookManager(webhookMgr)
+	bindingCtrl.WithMutatingBindings(bindings)
+	hc.MutatingController = bindingCtrl
+	hc.mutatingBindings = bindings
+}
+
+func (hc *hookController) InitConversionBindings(bindings []ConversionConfig, webhookMgr *validating_webhook.WebhookManager) {
+	if len(bindings) == 0 {
+		return
+	}
+
+	bindingCtrl := NewConversionBindingsController()
+	bindingCtrl.WithWebhookManager(webhookMgr)
+	bindingCtrl.WithConversionBindings(bindings)
+	hc.ConversionController = bindingCtrl
+	hc.conversionBindings = bindings
+}
+
 func (hc *hookController) CanHandleKubeEvent(kubeEvent KubeEvent) bool {
 	if hc.KubernetesController != nil {
 		return hc.KubernetesController.CanHandleEvent(kubeEvent)
@@ -133,6 +181,20 @@ func (hc *hookController) CanHandleValidatingEvent(event ValidatingEvent) bool {
 	return false
 }
 
+func (hc *hookController) CanHandleMutatingEvent(event MutatingEvent) bool {
+	if hc.MutatingController != nil {
+		return hc.MutatingController.CanHandleEvent(event)
+	}
+	return false
+}
+
+func (hc *hookController) CanHandleConversionEvent(event ConversionEvent) bool {
+	if hc.ConversionController != nil {
+		return hc.ConversionController.CanHandleEvent(event)
+	}
+	return false
+}
+
 func (hc *hookController) HandleEnableKubernetesBindings(createTasksFn func(BindingExecutionInfo)) error {
 	if hc.KubernetesController != nil {
 
@@ -169,6 +231,26 @@ func (hc *hookController) HandleValidatingEvent(event ValidatingEvent, createTas
 	}
 }
 
+func (hc *hookController) HandleMutatingEvent(event MutatingEvent, createTasksFn func(BindingExecutionInfo)) {
+	if hc.MutatingController == nil {
+		return
+	}
+	execInfo := hc.MutatingController.HandleEvent(event)
+	if createTasksFn != nil {
+		createTasksFn(execInfo)
+	}
+}
+
+func (hc *hookController) HandleConversionEvent(event ConversionEvent, createTasksFn func(BindingExecutionInfo)) {
+	if hc.ConversionController == nil {
+		return
+	}
+	execInfo := hc.ConversionController.HandleEvent(event)
+	if createTasksFn != nil {
+		createTasksFn(execInfo)
+	}
+}
+
 func (hc *hookController) HandleScheduleEvent(crontab string, createTasksFn func(BindingExecutionInfo)) {
 	if hc.ScheduleController == nil {
 		return
@@ -182,6 +264,48 @@ func (hc *hookController) HandleScheduleEvent(crontab string, createTasksFn func
 	}
 }
 
+// HandleManualRun creates a task for a schedule or a kubernetes binding. Binding context is built
+// from current objects if bindingContext is empty. Otherwise, bindingContext is used as is with metadata
+// from the binding configuration.
+func (hc *hookController) HandleManualRun(bindingName string, bindingContext []BindingContext, createTasksFn func(BindingExecutionInfo)) error {
+	var info BindingExecutionInfo
+	found := false
+	if hc.KubernetesController != nil {
+		info, found = hc.KubernetesController.ManualRunInfo(bindingName)
+	}
+	if !found && hc.ScheduleController != nil {
+		info, found = hc.ScheduleController.ManualRunInfo(bindingName)
+	}
+	if !found || len(info.BindingContext) == 0 {
+		return fmt.Errorf("no schedule or kubernetes binding with name '%s'", bindingName)
+	}
+
+	if len(bindingContext) > 0 {
+		metadata := info.BindingContext[0].Metadata
+		info.BindingContext = make([]BindingContext, 0, len(bindingContext))
+		for _, bc := range bindingContext {
+			bc.Metadata = metadata
+			if bc.Binding == "" {
+				bc.Binding = bindingName
+			}
+			info.BindingContext = append(info.BindingContext, bc)
+		}
+	}
+
+	if createTasksFn != nil {
+		createTasksFn(info)
+	}
+	return nil
+}
+
+func (hc *hookController) IsMonitorStarted(bindingName string) bool {
+	return hc.KubernetesController != nil && hc.KubernetesController.IsMonitorStarted(bindingName)
+}
+
+func (hc *hookController) IsScheduleEnabled(bindingName string) bool {
+	return hc.ScheduleController != nil && hc.ScheduleController.IsEnabled(bindingName)
+}
+
 func (hc *hookController) StartMonitors() {
 	if hc.KubernetesController != nil {
 		hc.KubernetesController.StartMonitors()
@@ -194,6 +318,12 @@ func (hc *hookController) StopMonitors() {
 	}
 }
 
+func (hc *hookController) StopMonitor(bindingName string) {
+	if hc.KubernetesController != nil {
+		hc.KubernetesController.StopMonitor(bindingName)
+	}
+}
+
 func (hc *hookController) EnableScheduleBindings() {
 	if hc.ScheduleController != nil {
 		hc.ScheduleController.EnableScheduleBindings()
@@ -212,6 +342,36 @@ func (hc *hookController) EnableValidatingBindings() {
 	}
 }
 
+func (hc *hookController) EnableMutatingBindings() {
+	if hc.MutatingController != nil {
+		hc.MutatingController.EnableMutatingBindings()
+	}
+}
+
+func (hc *hookController) EnableConversionBindings() {
+	if hc.ConversionController != nil {
+		hc.ConversionController.EnableConversionBindings()
+	}
+}
+
+func (hc *hookController) DisableValidatingBindings() {
+	if hc.ValidatingController != nil {
+		hc.ValidatingController.DisableValidatingBindings()
+	}
+}
+
+func (hc *hookController) DisableMutatingBindings() {
+	if hc.MutatingController != nil {
+		hc.MutatingController.DisableMutatingBindings()
+	}
+}
+
+func (hc *hookController) DisableConversionBindings() {
+	if hc.ConversionController != nil {
+		hc.ConversionController.DisableConversionBindings()
+	}
+}
+
 // KubernetesSnapshots returns all exited objects for all registered kubernetes bindings.
 func (hc *hookController) KubernetesSnapshots() map[string][]ObjectAndFilterResult {
 	if hc.KubernetesController != nil {
@@ -246,6 +406,20 @@ func (hc *hookController) KubernetesSnapshotsFor(bindingType BindingType, bindin
 				break
 			}
 		}
+	case KubernetesMutating:
+		for _, binding := range hc.mutatingBindings {
+			if bindingName == binding.BindingName {
+				includeSnapshots = binding.IncludeSnapshotsFrom
+				break
+			}
+		}
+	case KubernetesConversion:
+		for _, binding := range hc.conversionBindings {
+			if bindingName == binding.BindingName {
+				includeSnapshots = binding.IncludeSnapshotsFrom
+				break
+			}
+		}
 	}
 
 	return hc.KubernetesController.SnapshotsFrom(includeSnapshots...)
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -22,8 +23,11 @@ type KubernetesBindingToMonitorLink struct {
 	AllowFailure           bool
 	JqFilter               string
 	QueueName              string
+	QueuePolicy            *QueuePolicy
 	Group                  string
 	WaitForSynchronization bool
+	// Debounced binding gets one binding context with all changed objects.
+	Debounced bool
 }
 
 // KubernetesBindingsController handles kubernetes bindings for one hook.
@@ -33,8 +37,11 @@ type KubernetesBindingsController interface {
 	EnableKubernetesBindings() ([]BindingExecutionInfo, error)
 	StartMonitors()
 	StopMonitors()
+	StopMonitor(bindingName string)
 	CanHandleEvent(kubeEvent KubeEvent) bool
 	HandleEvent(kubeEvent KubeEvent) BindingExecutionInfo
+	ManualRunInfo(bindingName string) (BindingExecutionInfo, bool)
+	IsMonitorStarted(bindingName string) bool
 	BindingNames() []string
 	SnapshotsFrom(bindingNames ...string) map[string][]ObjectAndFilterResult
 	Snapshots() map[string][]ObjectAndFilterResult
@@ -50,6 +57,12 @@ type kubernetesBindingsController struct {
 
 	// dependencies
 	kubeEventsManager kube_events_manager.KubeEventsManager
+
+	monitorsStarted bool
+
+	// linksLock guards BindingMonitorLinks: links are removed on reload
+	// while events are handled in other goroutine.
+	linksLock sync.RWMutex
 }
 
 // kubernetesHooksController should implement the KubernetesHooksController
@@ -76,10 +89,20 @@ func (c *kubernetesBindingsController) EnableKubernetesBindings() ([]BindingExec
 	res := make([]BindingExecutionInfo, 0)
 
 	for _, config := range c.KubernetesBindings {
-		firstKubeEvent, err := c.kubeEventsManager.AddMonitor(config.Monitor)
-		if err != nil {
-			return nil, fmt.Errorf("run monitor: %s", err)
+		var firstKubeEvent *KubeEvent
+		monitorId := config.Monitor.Metadata.MonitorId
+		if c.kubeEventsManager.HasMonitor(monitorId) {
+			// Reuse existing informers, e.g. informers of a standby replica
+			// or informers created before a failed attempt.
+			firstKubeEvent = c.kubeEventsManager.MakeKubeEvent(c.kubeEventsManager.GetMonitor(monitorId))
+		} else {
+			var err error
+			firstKubeEvent, err = c.kubeEventsManager.AddMonitor(config.Monitor)
+			if err != nil {
+				return nil, fmt.Errorf("run monitor: %s", err)
+			}
 		}
+		c.linksLock.Lock()
 		c.BindingMonitorLinks[config.Monitor.Metadata.MonitorId] = &KubernetesBindingToMonitorLink{
 			MonitorId:              config.Monitor.Metadata.MonitorId,
 			BindingName:            config.BindingName,
@@ -87,9 +110,12 @@ func (c *kubernetesBindingsController) EnableKubernetesBindings() ([]BindingExec
 			AllowFailure:           config.AllowFailure,
 			JqFilter:               config.Monitor.JqFilter,
 			QueueName:              config.Queue,
+			QueuePolicy:            config.QueuePolicy,
 			Group:                  config.Group,
 			WaitForSynchronization: config.WaitForSynchronization,
+			Debounced:              config.Monitor.Debounce > 0,
 		}
+		c.linksLock.Unlock()
 
 		// There is no Synchronization event for 'v0' binding configuration.
 		if firstKubeEvent == nil {
@@ -108,34 +134,83 @@ func (c *kubernetesBindingsController) EnableKubernetesBindings() ([]BindingExec
 	return res, nil
 }
 
-// StartMonitors starts kubernetes informers to actually get events from cluster
+// StartMonitors starts kubernetes informers to actually get events from cluster.
+// Informers are started only once.
 func (c *kubernetesBindingsController) StartMonitors() {
-	for monitorId := range c.BindingMonitorLinks {
+	if c.monitorsStarted {
+		return
+	}
+	for _, monitorId := range c.monitorIds() {
 		c.kubeEventsManager.StartMonitor(monitorId)
 	}
+	c.monitorsStarted = true
 }
 
 // StartMonitors starts kubernetes informers to actually get events from cluster
 // TODO handle error!
 func (c *kubernetesBindingsController) StopMonitors() {
-	for monitorId := range c.BindingMonitorLinks {
+	for _, monitorId := range c.monitorIds() {
+		_ = c.kubeEventsManager.StopMonitor(monitorId)
+	}
+	c.monitorsStarted = false
+}
+
+// StopMonitor stops a monitor of the binding.
+func (c *kubernetesBindingsController) StopMonitor(bindingName string) {
+	// Links are removed first, so new events of the monitor are ignored.
+	stopped := []string{}
+	c.linksLock.Lock()
+	for monitorId, link := range c.BindingMonitorLinks {
+		if link.BindingName == bindingName {
+			delete(c.BindingMonitorLinks, monitorId)
+			stopped = append(stopped, monitorId)
+		}
+	}
+	c.linksLock.Unlock()
+
+	for _, monitorId := range stopped {
 		_ = c.kubeEventsManager.StopMonitor(monitorId)
 	}
 }
 
 func (c *kubernetesBindingsController) CanHandleEvent(kubeEvent KubeEvent) bool {
-	for key := range c.BindingMonitorLinks {
-		if key == kubeEvent.MonitorId {
-			return true
+	c.linksLock.RLock()
+	defer c.linksLock.RUnlock()
+
+	_, has := c.BindingMonitorLinks[kubeEvent.MonitorId]
+	return has
+}
+
+func (c *kubernetesBindingsController) monitorIds() []string {
+	c.linksLock.RLock()
+	defer c.linksLock.RUnlock()
+
+	ids := make([]string, 0, len(c.BindingMonitorLinks))
+	for monitorId := range c.BindingMonitorLinks {
+		ids = append(ids, monitorId)
+	}
+	return ids
+}
+
+// bindingLink returns a monitor id and a link for the binding.
+func (c *kubernetesBindingsController) bindingLink(bindingName string) (string, *KubernetesBindingToMonitorLink) {
+	c.linksLock.RLock()
+	defer c.linksLock.RUnlock()
+
+	for monitorId, link := range c.BindingMonitorLinks {
+		if link.BindingName == bindingName {
+			return monitorId, link
 		}
 	}
-	return false
+	return "", nil
 }
 
 // HandleEvent receives event from KubeEventManager and returns a BindingExecutionInfo
 // to help create a new task to run a hook.
 func (c *kubernetesBindingsController) HandleEvent(kubeEvent KubeEvent) BindingExecutionInfo {
+	c.linksLock.RLock()
 	link, hasKey := c.BindingMonitorLinks[kubeEvent.MonitorId]
+	c.linksLock.RUnlock()
 	if !hasKey {
 		log.Errorf("Possible bug!!! Unknown kube event: no such monitor id '%s' registered", kubeEvent.MonitorId)
 		return BindingExecutionInfo{
@@ -151,12 +226,40 @@ func (c *kubernetesBindingsController) HandleEvent(kubeEvent KubeEvent) BindingE
 		IncludeSnapshots:       link.IncludeSnapshots,
 		AllowFailure:           link.AllowFailure,
 		QueueName:              link.QueueName,
+		QueuePolicy:            link.QueuePolicy,
 		Binding:                link.BindingName,
 		Group:                  link.Group,
 		WaitForSynchronization: link.WaitForSynchronization,
 	}
 }
 
+// ManualRunInfo returns a BindingExecutionInfo with a "Synchronization" binding context
+// that contains current objects of the binding.
+func (c *kubernetesBindingsController) ManualRunInfo(bindingName string) (BindingExecutionInfo, bool) {
+	monitorId, link := c.bindingLink(bindingName)
+	if link == nil {
+		return BindingExecutionInfo{}, false
+	}
+	kubeEvent := KubeEvent{
+		MonitorId: monitorId,
+		Type:      TypeSynchronization,
+		Objects:   c.SnapshotsFrom(bindingName)[bindingName],
+	}
+	return c.HandleEvent(kubeEvent), true
+}
+
+// IsMonitorStarted returns true if the monitor for the binding is created and started.
+func (c *kubernetesBindingsController) IsMonitorStarted(bindingName string) bool {
+	if !c.monitorsStarted {
+		return false
+	}
+	monitorId, link := c.bindingLink(bindingName)
+	if link == nil {
+		return false
+	}
+	return c.kubeEventsManager.HasMonitor(monitorId)
+}
+
 func (c *kubernetesBindingsController) BindingNames() []string {
 	names := []string{}
 	for _, binding := range c.KubernetesBindings {
@@ -206,6 +309,21 @@ func ConvertKubeEventToBindingContext(kubeEvent KubeEvent, link *KubernetesBindi
 		bindingContexts = append(bindingContexts, bc)
 
 	case TypeEvent:
+		if link.Debounced {
+			bc := BindingContext{
+				Binding:     link.BindingName,
+				Type:        kubeEvent.Type,
+				WatchEvents: kubeEvent.WatchEvents,
+				Objects:     kubeEvent.Objects,
+			}
+			bc.Metadata.JqFilter = link.JqFilter
+			bc.Metadata.BindingType = OnKubernetesEvent
+			bc.Metadata.IncludeSnapshots = link.IncludeSnapshots
+			bc.Metadata.Group = link.Group
+
+			bindingContexts = append(bindingContexts, bc)
+			break
+		}
 		for _, kEvent := range kubeEvent.WatchEvents {
 			bc := BindingContext{
 				Binding:    link.BindingName,
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/kube_events_manager"
+)
+
+// StopMonitor is called on hook reload while the events handler checks links of the same controller.
+func Test_KubernetesBindingsController_StopMonitor_Concurrent(t *testing.T) {
+	g := NewWithT(t)
+
+	c := NewKubernetesBindingsController()
+	c.WithKubeEventsManager(kube_events_manager.NewKubeEventsManager())
+	for i := 0; i < 1000; i++ {
+		monitorId := fmt.Sprintf("monitor-%d", i)
+		c.BindingMonitorLinks[monitorId] = &KubernetesBindingToMonitorLink{
+			MonitorId:   monitorId,
+			BindingName: fmt.Sprintf("binding-%d", i),
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ev := KubeEvent{MonitorId: "monitor-999", Type: TypeEvent}
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if c.CanHandleEvent(ev) {
+				c.HandleEvent(ev)
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		c.StopMonitor(fmt.Sprintf("binding-%d", i))
+	}
+	close(stop)
+	<-done
+
+	g.Expect(c.BindingMonitorLinks).Should(BeEmpty())
+	g.Expect(c.CanHandleEvent(KubeEvent{MonitorId: "monitor-0"})).Should(BeFalse())
+}
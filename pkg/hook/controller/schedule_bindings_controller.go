@@ -15,6 +15,7 @@ type ScheduleBindingToCrontabLink struct {
 	IncludeSnapshots []string
 	AllowFailure     bool
 	QueueName        string
+	QueuePolicy      *QueuePolicy
 	Group            string
 }
 
@@ -26,6 +27,8 @@ type ScheduleBindingsController interface {
 	DisableScheduleBindings()
 	CanHandleEvent(crontab string) bool
 	HandleEvent(crontab string) []BindingExecutionInfo
+	ManualRunInfo(bindingName string) (BindingExecutionInfo, bool)
+	IsEnabled(bindingName string) bool
 }
 
 // scheduleHooksController is a main implementation of KubernetesHooksController
@@ -84,6 +87,7 @@ func (c *scheduleBindingsController) HandleEvent(crontab string) []BindingExecut
 				IncludeSnapshots: link.IncludeSnapshots,
 				AllowFailure:     link.AllowFailure,
 				QueueName:        link.QueueName,
+				QueuePolicy:      link.QueuePolicy,
 				Binding:          link.BindingName,
 				Group:            link.Group,
 			}
@@ -94,6 +98,31 @@ func (c *scheduleBindingsController) HandleEvent(crontab string) []BindingExecut
 	return res
 }
 
+// ManualRunInfo returns a BindingExecutionInfo for the binding as if its schedule is triggered.
+func (c *scheduleBindingsController) ManualRunInfo(bindingName string) (BindingExecutionInfo, bool) {
+	for _, link := range c.ScheduleLinks {
+		if link.BindingName != bindingName {
+			continue
+		}
+		for _, info := range c.HandleEvent(link.Crontab) {
+			if info.Binding == bindingName {
+				return info, true
+			}
+		}
+	}
+	return BindingExecutionInfo{}, false
+}
+
+// IsEnabled returns true if the schedule entry of the binding is added to the schedule manager.
+func (c *scheduleBindingsController) IsEnabled(bindingName string) bool {
+	for _, link := range c.ScheduleLinks {
+		if link.BindingName == bindingName {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *scheduleBindingsController) EnableScheduleBindings() {
 	for _, config := range c.ScheduleBindings {
 		c.ScheduleLinks[config.ScheduleEntry.Id] = &ScheduleBindingToCrontabLink{
@@ -102,6 +131,7 @@ func (c *scheduleBindingsController) EnableScheduleBindings() {
 			IncludeSnapshots: config.IncludeSnapshotsFrom,
 			AllowFailure:     config.AllowFailure,
 			QueueName:        config.Queue,
+			QueuePolicy:      config.QueuePolicy,
 			Group:            config.Group,
 		}
 		c.scheduleManager.Add(config.ScheduleEntry)
@@ -86,8 +86,13 @@ func (c *validatingBindingsController) EnableValidatingBindings() {
 	}
 }
 
+// DisableValidatingBindings removes webhooks from WebhookManager. WebhookManager.UpdateConfigurations
+// should be called to apply changes.
 func (c *validatingBindingsController) DisableValidatingBindings() {
-	// TODO dynamic enable/disable validating webhooks.
+	for _, config := range c.ValidatingBindings {
+		c.webhookManager.RemoveWebhook(config.Webhook)
+		delete(c.ValidatingLinks, config.Webhook.Metadata.WebhookId)
+	}
 }
 
 func (c *validatingBindingsController) CanHandleEvent(event ValidatingEvent) bool {
@@ -0,0 +1,62 @@
+package hook
+
+import (
+	"fmt"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
+)
+
+// GoHookHandler handles binding contexts in-process. Binding contexts contain fresh snapshots.
+type GoHookHandler func(bindingContexts []BindingContext) (*HookResult, error)
+
+// NewGoHook returns a hook that runs handler instead of an executable.
+// The config is converted and checked the same way as a 'configVersion: v1' config of a shell hook.
+func NewGoHook(name string, configV1 *HookConfigV1, handler GoHookHandler) (*Hook, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("go hook '%s': handler is required", name)
+	}
+
+	h := NewHook(name, "")
+	h.GoHandler = handler
+
+	h.Config.Version = "v1"
+	h.Config.V1 = configV1
+	err := h.Config.ConvertAndCheckV1()
+	if err != nil {
+		return nil, fmt.Errorf("load go hook '%s' config: %s", name, err)
+	}
+
+	return h, nil
+}
+
+// IsGoHook returns true if hook is registered as a Go function.
+func (h *Hook) IsGoHook() bool {
+	return h.GoHandler != nil
+}
+
+// runGoHandler executes the handler without temporary files. A panic in the handler is returned as an error.
+func (h *Hook) runGoHandler(context []BindingContext) (result *HookResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &HookResult{}
+			err = fmt.Errorf("%s FAILED: panic: %v", h.Name, r)
+		}
+	}()
+
+	result, err = h.GoHandler(context)
+	if result == nil {
+		result = &HookResult{}
+	}
+	if err != nil {
+		return result, fmt.Errorf("%s FAILED: %s", h.Name, err)
+	}
+
+	err = object_patch.ValidateOperations(result.KubernetesPatchOperations)
+	if err != nil {
+		return result, fmt.Errorf("got bad kubernetes patch operations: %s", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/metric_storage/operation"
+)
+
+func Test_GoHook_InHookManager(t *testing.T) {
+	g := NewWithT(t)
+
+	var handledContexts []BindingContext
+	goHook, err := NewGoHook("go/startup", &HookConfigV1{
+		OnStartup: 5,
+		Schedule: []ScheduleConfigV1{
+			{Name: "every-minute", Crontab: "* * * * *"},
+		},
+	}, func(bindingContexts []BindingContext) (*HookResult, error) {
+		handledContexts = bindingContexts
+		value := 1.0
+		return &HookResult{
+			Metrics: []operation.MetricOperation{{Name: "go_hook_runs", Add: &value, Action: "add"}},
+		}, nil
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(goHook.IsGoHook()).Should(BeTrue())
+
+	hm, rmFn := newHookManager(t, "testdata/hook_manager")
+	defer rmFn()
+	hm.WithGoHooks(goHook)
+
+	err = hm.Init()
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(hm.GetHook("go/startup")).Should(Equal(goHook))
+	g.Expect(hm.GetHookNames()).Should(ContainElement("go/startup"))
+	startupHooks, _ := hm.GetHooksInOrder(OnStartup)
+	g.Expect(startupHooks).Should(ContainElement("go/startup"))
+	scheduleHooks, _ := hm.GetHooksInOrder(Schedule)
+	g.Expect(scheduleHooks).Should(ContainElement("go/startup"))
+
+	result, err := goHook.Run(context.Background(), OnStartup, []BindingContext{{Binding: "onStartup"}}, map[string]string{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(result.Metrics).Should(HaveLen(1))
+	g.Expect(handledContexts).Should(HaveLen(1))
+	g.Expect(handledContexts[0].Binding).Should(Equal("onStartup"))
+}
+
+func Test_GoHook_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewGoHook("go/bad-crontab", &HookConfigV1{
+		Schedule: []ScheduleConfigV1{{Crontab: "bad"}},
+	}, func([]BindingContext) (*HookResult, error) { return nil, nil })
+	g.Expect(err).Should(HaveOccurred())
+
+	hm, rmFn := newHookManager(t, "testdata/hook_manager_validating")
+	defer rmFn()
+
+	failing, err := NewGoHook("go/failing", &HookConfigV1{OnStartup: 1}, func([]BindingContext) (*HookResult, error) {
+		return nil, fmt.Errorf("something wrong")
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	panicking, err := NewGoHook("go/panicking", &HookConfigV1{OnStartup: 1}, func([]BindingContext) (*HookResult, error) {
+		panic("boom")
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	hm.WithGoHooks(failing, panicking)
+	g.Expect(hm.Init()).ShouldNot(HaveOccurred())
+
+	result, err := failing.Run(context.Background(), OnStartup, []BindingContext{}, map[string]string{})
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(err.Error()).Should(ContainSubstring("something wrong"))
+	g.Expect(result).ShouldNot(BeNil())
+
+	_, err = panicking.Run(context.Background(), OnStartup, []BindingContext{}, map[string]string{})
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(err.Error()).Should(ContainSubstring("boom"))
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is a default number of executions kept for each hook.
+const DefaultMaxEntries = 20
+
+// Entry is a result of one hook execution. Usage fields, ExitCode and output tails
+// are empty for hooks that are not run as a separate process.
+type Entry struct {
+	TaskId      string
+	HookName    string
+	Binding     string
+	BindingType string
+	Queue       string
+	EventTypes  []string
+	ObjectCount int
+	StartedAt   time.Time
+	Duration    time.Duration
+
+	SysTime    time.Duration
+	UserTime   time.Duration
+	MaxRss     int64
+	ExitCode   int
+	StdoutTail []string
+	StderrTail []string
+
+	TimedOut bool
+	Error    string
+}
+
+// Store keeps last executions of each hook in memory.
+type Store struct {
+	m          sync.RWMutex
+	entries    map[string][]*Entry
+	maxEntries int
+}
+
+func NewStore() *Store {
+	return &Store{
+		entries:    make(map[string][]*Entry),
+		maxEntries: DefaultMaxEntries,
+	}
+}
+
+// WithMaxEntries sets a number of executions to keep for each hook. 0 disables the history.
+func (s *Store) WithMaxEntries(n int) *Store {
+	s.maxEntries = n
+	return s
+}
+
+// Add saves an execution. The oldest execution of the hook is removed if there are too many.
+func (s *Store) Add(entry *Entry) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.maxEntries <= 0 {
+		return
+	}
+	entries := append(s.entries[entry.HookName], entry)
+	if len(entries) > s.maxEntries {
+		entries = entries[len(entries)-s.maxEntries:]
+	}
+	s.entries[entry.HookName] = entries
+}
+
+// List returns executions of the hook from the newest to the oldest.
+func (s *Store) List(hookName string) []*Entry {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	entries := s.entries[hookName]
+	res := make([]*Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		res = append(res, entries[i])
+	}
+	return res
+}
+
+// Remove deletes executions of the hook, e.g. when the hook is removed.
+func (s *Store) Remove(hookName string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.entries, hookName)
+}
@@ -0,0 +1,31 @@
+package history
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_Store(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewStore().WithMaxEntries(2)
+	s.Add(&Entry{HookName: "hook-a", TaskId: "1"})
+	s.Add(&Entry{HookName: "hook-a", TaskId: "2"})
+	s.Add(&Entry{HookName: "hook-a", TaskId: "3"})
+	s.Add(&Entry{HookName: "hook-b", TaskId: "4"})
+
+	entries := s.List("hook-a")
+	g.Expect(entries).Should(HaveLen(2))
+	g.Expect(entries[0].TaskId).Should(Equal("3"))
+	g.Expect(entries[1].TaskId).Should(Equal("2"))
+	g.Expect(s.List("hook-b")).Should(HaveLen(1))
+
+	s.Remove("hook-a")
+	g.Expect(s.List("hook-a")).Should(BeEmpty())
+
+	// History is disabled.
+	s = NewStore().WithMaxEntries(0)
+	s.Add(&Entry{HookName: "hook-a", TaskId: "1"})
+	g.Expect(s.List("hook-a")).Should(BeEmpty())
+}
@@ -1,14 +1,18 @@
 package hook
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/kennygrant/sanitize"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
 	uuid "gopkg.in/satori/go.uuid.v1"
 
 	. "github.com/flant/shell-operator/pkg/hook/binding_context"
@@ -18,7 +22,10 @@ import (
 	"github.com/flant/shell-operator/pkg/app"
 	"github.com/flant/shell-operator/pkg/executor"
 	"github.com/flant/shell-operator/pkg/hook/controller"
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
 	"github.com/flant/shell-operator/pkg/metric_storage/operation"
+	"github.com/flant/shell-operator/pkg/tracing"
+	utils "github.com/flant/shell-operator/pkg/utils/labels"
 )
 
 type CommonHook interface {
@@ -29,26 +36,121 @@ type HookResult struct {
 	Usage              *executor.CmdUsage
 	Metrics            []operation.MetricOperation
 	ValidatingResponse *ValidatingResponse
+	MutatingResponse   *MutatingResponse
+	ConversionResponse *ConversionHookResponse
+
+	KubernetesPatchOperations []object_patch.OperationSpec
+
+	// TimedOut is true if the hook is terminated after the timeout.
+	TimedOut bool
+}
+
+// HookFiles are temporary files with input data for the hook and with results of the hook run.
+type HookFiles struct {
+	BindingContextPath     string
+	MetricsPath            string
+	ValidatingResponsePath string
+	MutatingResponsePath   string
+	ConversionResponsePath string
+	KubernetesPatchPath    string
+	// LogPath is a file for structured log lines, they are logged after the hook run.
+	LogPath string
+	// Traceparent is passed to the hook to continue the trace of the hook run.
+	Traceparent string
+}
+
+// Envs returns environment variables with paths to files and a TRACEPARENT if tracing is enabled.
+func (f *HookFiles) Envs() []string {
+	envs := []string{
+		fmt.Sprintf("BINDING_CONTEXT_PATH=%s", f.BindingContextPath),
+		fmt.Sprintf("METRICS_PATH=%s", f.MetricsPath),
+		fmt.Sprintf("VALIDATING_RESPONSE_PATH=%s", f.ValidatingResponsePath),
+		fmt.Sprintf("MUTATING_RESPONSE_PATH=%s", f.MutatingResponsePath),
+		fmt.Sprintf("CONVERSION_RESPONSE_PATH=%s", f.ConversionResponsePath),
+		fmt.Sprintf("KUBERNETES_PATCH_PATH=%s", f.KubernetesPatchPath),
+		fmt.Sprintf("LOG_PATH=%s", f.LogPath),
+	}
+	if f.Traceparent != "" {
+		envs = append(envs, fmt.Sprintf("TRACEPARENT=%s", f.Traceparent))
+	}
+	return envs
+}
+
+func (f *HookFiles) Remove() {
+	for _, filePath := range []string{
+		f.BindingContextPath,
+		f.MetricsPath,
+		f.ValidatingResponsePath,
+		f.MutatingResponsePath,
+		f.ConversionResponsePath,
+		f.KubernetesPatchPath,
+		f.LogPath,
+	} {
+		if filePath != "" {
+			os.Remove(filePath)
+		}
+	}
 }
 
 type Hook struct {
-	Name   string // The unique name like '002-prometheus-hooks/startup_hook'.
-	Path   string // The absolute path to the executable file.
-	Config *HookConfig
+	Name    string // The unique name like '002-prometheus-hooks/startup_hook'.
+	Path    string // The absolute path to the executable file.
+	Config  *HookConfig
+	Runtime HookRuntime
+	// GoHandler is set for hooks registered as Go functions.
+	GoHandler GoHookHandler
 
 	HookController controller.HookController
 
 	TmpDir string
+
+	runStatesMu sync.Mutex
+	runStates   map[string]BindingRunState
+}
+
+// BindingRunState is a result of the last hook run for a binding.
+type BindingRunState struct {
+	LastRun       time.Time
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// RecordRun saves a time and an error of the hook run for the binding.
+func (h *Hook) RecordRun(bindingName string, err error) {
+	h.runStatesMu.Lock()
+	defer h.runStatesMu.Unlock()
+	if h.runStates == nil {
+		h.runStates = make(map[string]BindingRunState)
+	}
+	state := h.runStates[bindingName]
+	state.LastRun = time.Now()
+	if err != nil {
+		state.LastError = err.Error()
+		state.LastErrorTime = state.LastRun
+	}
+	h.runStates[bindingName] = state
+}
+
+// RunState returns a state of the last run for the binding. It is empty if hook was not run.
+func (h *Hook) RunState(bindingName string) BindingRunState {
+	h.runStatesMu.Lock()
+	defer h.runStatesMu.Unlock()
+	return h.runStates[bindingName]
 }
 
 func NewHook(name, path string) *Hook {
 	return &Hook{
-		Name:   name,
-		Path:   path,
-		Config: &HookConfig{},
+		Name:    name,
+		Path:    path,
+		Config:  &HookConfig{},
+		Runtime: NewExecHookRuntime(),
 	}
 }
 
+func (h *Hook) WithRuntime(runtime HookRuntime) {
+	h.Runtime = runtime
+}
+
 func (h *Hook) WithTmpDir(dir string) {
 	h.TmpDir = dir
 }
@@ -74,63 +176,101 @@ func (h *Hook) GetHookController() controller.HookController {
 	return h.HookController
 }
 
-func (h *Hook) Run(bindingType BindingType, context []BindingContext, logLabels map[string]string) (*HookResult, error) {
-	// Refresh snapshots
-	freshBindingContext := h.HookController.UpdateSnapshots(context)
+// RunTimeout returns a maximum timeout for bindings from binding contexts. Bindings
+// without timeout use a default timeout. Zero means no timeout.
+func (h *Hook) RunTimeout(context []BindingContext) time.Duration {
+	if len(context) == 0 {
+		return app.HookTimeout
+	}
+	var res time.Duration
+	for _, bc := range context {
+		timeout := h.Config.BindingTimeout(bc.Binding)
+		if timeout == 0 {
+			timeout = app.HookTimeout
+		}
+		if timeout == 0 {
+			return 0
+		}
+		if timeout > res {
+			res = timeout
+		}
+	}
+	return res
+}
 
-	versionedContextList := ConvertBindingContextList(h.Config.Version, freshBindingContext)
+// Run executes the hook. ctx is used to trace the execution.
+func (h *Hook) Run(ctx context.Context, bindingType BindingType, bindingContext []BindingContext, logLabels map[string]string) (result *HookResult, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "HookExec")
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-	contextPath, err := h.prepareBindingContextJsonFile(versionedContextList)
-	if err != nil {
-		return nil, err
-	}
+	// Refresh snapshots
+	freshBindingContext := h.HookController.UpdateSnapshots(bindingContext)
 
-	metricsPath, err := h.prepareMetricsFile()
-	if err != nil {
-		return nil, err
+	if h.IsGoHook() {
+		return h.runGoHandler(freshBindingContext)
 	}
 
-	validatingPath, err := h.prepareValidatingResponseFile()
+	versionedContextList := ConvertBindingContextList(h.Config.Version, freshBindingContext)
+
+	files, err := h.prepareFiles(versionedContextList)
 	if err != nil {
 		return nil, err
 	}
 
-	// remove tmp file on hook exit
+	// remove tmp files on hook exit
 	defer func() {
 		if app.DebugKeepTmpFiles != "yes" {
-			os.Remove(contextPath)
-			os.Remove(metricsPath)
-			os.Remove(validatingPath)
+			files.Remove()
 		}
 	}()
 
-	envs := []string{}
-	envs = append(envs, os.Environ()...)
-	if contextPath != "" {
-		envs = append(envs, fmt.Sprintf("BINDING_CONTEXT_PATH=%s", contextPath))
-		envs = append(envs, fmt.Sprintf("METRICS_PATH=%s", metricsPath))
-		envs = append(envs, fmt.Sprintf("VALIDATING_RESPONSE_PATH=%s", validatingPath))
-	}
-
-	hookCmd := executor.MakeCommand(path.Dir(h.Path), h.Path, []string{}, envs)
-
-	result := &HookResult{}
+	files.Traceparent = tracing.Traceparent(ctx)
+	result = &HookResult{}
 
-	result.Usage, err = executor.RunAndLogLines(hookCmd, logLabels)
+	result.Usage, err = h.Runtime.Run(h, files, h.RunTimeout(bindingContext), logLabels)
+	// Log lines are useful for failed runs too.
+	if logErr := executor.LogFileLines(files.LogPath, logLabels); logErr != nil {
+		log.WithFields(utils.LabelsToLogFields(logLabels)).Warnf("Read hook log file: %v", logErr)
+	}
 	if err != nil {
+		result.TimedOut = executor.IsTimeoutError(err)
 		return result, fmt.Errorf("%s FAILED: %s", h.Name, err)
 	}
 
-	result.Metrics, err = operation.MetricOperationsFromFile(metricsPath)
+	result.Metrics, err = operation.MetricOperationsFromFile(files.MetricsPath)
 	if err != nil {
 		return result, fmt.Errorf("got bad metrics: %s", err)
 	}
 
-	result.ValidatingResponse, err = ValidatingResponseFromFile(validatingPath)
+	result.ValidatingResponse, err = ValidatingResponseFromFile(files.ValidatingResponsePath)
 	if err != nil {
 		return result, fmt.Errorf("got bad validating response: %s", err)
 	}
 
+	result.MutatingResponse, err = MutatingResponseFromFile(files.MutatingResponsePath)
+	if err != nil {
+		return result, fmt.Errorf("got bad mutating response: %s", err)
+	}
+
+	result.ConversionResponse, err = ConversionHookResponseFromFile(files.ConversionResponsePath)
+	if err != nil {
+		return result, fmt.Errorf("got bad conversion response: %s", err)
+	}
+
+	result.KubernetesPatchOperations, err = object_patch.OperationsFromFile(files.KubernetesPatchPath)
+	if err != nil {
+		return result, fmt.Errorf("got bad kubernetes patch operations: %s", err)
+	}
+	err = object_patch.ValidateOperations(result.KubernetesPatchOperations)
+	if err != nil {
+		return result, fmt.Errorf("got bad kubernetes patch operations: %s", err)
+	}
+
 	return result, nil
 }
 
@@ -183,9 +323,77 @@ func (h *Hook) GetConfigDescription() string {
 		}
 		msgs = append(msgs, fmt.Sprintf("Validate k8s kinds: '%s'", strings.Join(kindList, "', '")))
 	}
+	if len(h.Config.KubernetesMutating) > 0 {
+		kinds := map[string]bool{}
+		for _, mutating := range h.Config.KubernetesMutating {
+			if mutating.Webhook == nil {
+				continue
+			}
+			for _, rule := range mutating.Webhook.Rules {
+				for _, resource := range rule.Resources {
+					kinds[strings.ToLower(resource)] = true
+				}
+			}
+		}
+		kindList := []string{}
+		for kind := range kinds {
+			kindList = append(kindList, kind)
+		}
+		msgs = append(msgs, fmt.Sprintf("Mutate k8s kinds: '%s'", strings.Join(kindList, "', '")))
+	}
+	if len(h.Config.KubernetesConversion) > 0 {
+		crdList := []string{}
+		for _, conversion := range h.Config.KubernetesConversion {
+			crdList = append(crdList, conversion.Webhook.CrdName)
+		}
+		msgs = append(msgs, fmt.Sprintf("Convert CRDs: '%s'", strings.Join(crdList, "', '")))
+	}
 	return strings.Join(msgs, ", ")
 }
 
+// prepareFiles creates a file with binding context and empty files for results.
+func (h *Hook) prepareFiles(context BindingContextList) (*HookFiles, error) {
+	var err error
+	files := &HookFiles{}
+
+	files.BindingContextPath, err = h.prepareBindingContextJsonFile(context)
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	files.MetricsPath, err = h.prepareMetricsFile()
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	files.LogPath, err = h.prepareLogFile()
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	files.ValidatingResponsePath, err = h.prepareValidatingResponseFile()
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	files.MutatingResponsePath, err = h.prepareMutatingResponseFile()
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	files.ConversionResponsePath, err = h.prepareConversionResponseFile()
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	files.KubernetesPatchPath, err = h.prepareKubernetesPatchFile()
+	if err != nil {
+		files.Remove()
+		return nil, err
+	}
+	return files, nil
+}
+
 func (h *Hook) prepareBindingContextJsonFile(context BindingContextList) (string, error) {
 	var err error
 	data, err := context.Json()
@@ -214,6 +422,17 @@ func (h *Hook) prepareMetricsFile() (string, error) {
 	return metricsPath, nil
 }
 
+func (h *Hook) prepareLogFile() (string, error) {
+	logPath := filepath.Join(h.TmpDir, fmt.Sprintf("hook-%s-log-%s.jsonl", h.SafeName(), uuid.NewV4().String()))
+
+	err := ioutil.WriteFile(logPath, []byte{}, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return logPath, nil
+}
+
 func (h *Hook) prepareValidatingResponseFile() (string, error) {
 	validatingPath := filepath.Join(h.TmpDir, fmt.Sprintf("hook-%s-validating-response-%s.json", h.SafeName(), uuid.NewV4().String()))
 
@@ -224,3 +443,36 @@ func (h *Hook) prepareValidatingResponseFile() (string, error) {
 
 	return validatingPath, nil
 }
+
+func (h *Hook) prepareMutatingResponseFile() (string, error) {
+	mutatingPath := filepath.Join(h.TmpDir, fmt.Sprintf("hook-%s-mutating-response-%s.json", h.SafeName(), uuid.NewV4().String()))
+
+	err := ioutil.WriteFile(mutatingPath, []byte{}, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return mutatingPath, nil
+}
+
+func (h *Hook) prepareConversionResponseFile() (string, error) {
+	conversionPath := filepath.Join(h.TmpDir, fmt.Sprintf("hook-%s-conversion-response-%s.json", h.SafeName(), uuid.NewV4().String()))
+
+	err := ioutil.WriteFile(conversionPath, []byte{}, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return conversionPath, nil
+}
+
+func (h *Hook) prepareKubernetesPatchFile() (string, error) {
+	kubernetesPatchPath := filepath.Join(h.TmpDir, fmt.Sprintf("hook-%s-kubernetes-patch-%s.json", h.SafeName(), uuid.NewV4().String()))
+
+	err := ioutil.WriteFile(kubernetesPatchPath, []byte{}, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return kubernetesPatchPath, nil
+}
@@ -2,6 +2,9 @@ package hook
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"gopkg.in/robfig/cron.v2"
@@ -36,6 +39,11 @@ type HookConfig struct {
 	Schedules            []ScheduleConfig
 	OnKubernetesEvents   []OnKubernetesEventConfig
 	KubernetesValidating []ValidatingConfig
+	KubernetesMutating   []MutatingConfig
+	KubernetesConversion []ConversionConfig
+
+	// Runtime is a name of the runtime to run the hook. Empty for a default runtime.
+	Runtime string
 }
 
 type HookConfigV0 struct {
@@ -50,6 +58,9 @@ type HookConfigV1 struct {
 	Schedule             []ScheduleConfigV1             `json:"schedule"`
 	OnKubernetesEvent    []OnKubernetesEventConfigV1    `json:"kubernetes"`
 	KubernetesValidating []KubernetesValidatingConfigV1 `json:"kubernetesValidating"`
+	KubernetesMutating   []KubernetesMutatingConfigV1   `json:"kubernetesMutating"`
+	KubernetesConversion []KubernetesConversionConfigV1 `json:"kubernetesCustomResourceConversion"`
+	Runtime              string                         `json:"runtime,omitempty"`
 }
 
 // Schedule configuration
@@ -61,12 +72,32 @@ type ScheduleConfigV0 struct {
 
 // Schedule configuration
 type ScheduleConfigV1 struct {
-	Name                 string   `json:"name"`
-	Crontab              string   `json:"crontab"`
-	AllowFailure         bool     `json:"allowFailure"`
-	IncludeSnapshotsFrom []string `json:"includeSnapshotsFrom"`
-	Queue                string   `json:"queue"`
-	Group                string   `json:"group,omitempty"`
+	Name                 string         `json:"name"`
+	Crontab              string         `json:"crontab"`
+	AllowFailure         bool           `json:"allowFailure"`
+	IncludeSnapshotsFrom []string       `json:"includeSnapshotsFrom"`
+	Queue                string         `json:"queue"`
+	QueuePolicy          *QueuePolicyV1 `json:"queuePolicy,omitempty"`
+	Timeout              string         `json:"timeout,omitempty"`
+	Retry                *RetryV1       `json:"retry,omitempty"`
+	Group                string         `json:"group,omitempty"`
+}
+
+// QueuePolicyV1 is a policy for pending tasks of a binding.
+type QueuePolicyV1 struct {
+	Dedupe      string `json:"dedupe,omitempty"`
+	MaxPending  int    `json:"maxPending,omitempty"`
+	OnOverflow  string `json:"onOverflow,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	OrderBy     string `json:"orderBy,omitempty"`
+}
+
+// RetryV1 is a retry policy for failed hook runs of a binding.
+type RetryV1 struct {
+	MaxAttempts  int    `json:"maxAttempts,omitempty"`
+	InitialDelay string `json:"initialDelay,omitempty"`
+	MaxDelay     string `json:"maxDelay,omitempty"`
+	OnExhausted  string `json:"onExhausted,omitempty"`
 }
 
 // Legacy version of kubernetes event configuration
@@ -94,6 +125,7 @@ type OnKubernetesEventConfigV1 struct {
 	ExecuteHookOnSynchronization string                   `json:"executeHookOnSynchronization,omitempty"`
 	WaitForSynchronization       string                   `json:"waitForSynchronization,omitempty"`
 	KeepFullObjectsInMemory      string                   `json:"keepFullObjectsInMemory,omitempty"`
+	WatchMode                    string                   `json:"watchMode,omitempty"`
 	Mode                         KubeEventMode            `json:"mode,omitempty"`
 	ApiVersion                   string                   `json:"apiVersion,omitempty"`
 	Kind                         string                   `json:"kind,omitempty"`
@@ -106,7 +138,22 @@ type OnKubernetesEventConfigV1 struct {
 	ResynchronizationPeriod      string                   `json:"resynchronizationPeriod,omitempty"`
 	IncludeSnapshotsFrom         []string                 `json:"includeSnapshotsFrom,omitempty"`
 	Queue                        string                   `json:"queue,omitempty"`
+	QueuePolicy                  *QueuePolicyV1           `json:"queuePolicy,omitempty"`
+	Timeout                      string                   `json:"timeout,omitempty"`
+	Retry                        *RetryV1                 `json:"retry,omitempty"`
 	Group                        string                   `json:"group,omitempty"`
+	Debounce                     string                   `json:"debounce,omitempty"`
+	MaxWait                      string                   `json:"maxWait,omitempty"`
+	// BatchWindow is an alias for Debounce.
+	BatchWindow string `json:"batchWindow,omitempty"`
+}
+
+// DebouncePeriod returns debounce or its alias batchWindow.
+func (cfg OnKubernetesEventConfigV1) DebouncePeriod() string {
+	if cfg.Debounce != "" {
+		return cfg.Debounce
+	}
+	return cfg.BatchWindow
 }
 
 type KubeNameSelectorV1 NameSelector
@@ -128,6 +175,39 @@ type KubernetesValidatingConfigV1 struct {
 	TimeoutSeconds       *int32                   `json:"timeoutSeconds,omitempty"`
 }
 
+// version 1 of kubernetesMutating configuration
+type KubernetesMutatingConfigV1 struct {
+	Name                 string                     `json:"name,omitempty"`
+	IncludeSnapshotsFrom []string                   `json:"includeSnapshotsFrom,omitempty"`
+	Group                string                     `json:"group,omitempty"`
+	Rules                []v1.RuleWithOperations    `json:"rules,omitempty"`
+	FailurePolicy        *v1.FailurePolicyType      `json:"failurePolicy"`
+	LabelSelector        *metav1.LabelSelector      `json:"labelSelector,omitempty"`
+	Namespace            *KubeNamespaceSelectorV1   `json:"namespace,omitempty"`
+	SideEffects          *v1.SideEffectClass        `json:"sideEffects"`
+	TimeoutSeconds       *int32                     `json:"timeoutSeconds,omitempty"`
+	ReinvocationPolicy   *v1.ReinvocationPolicyType `json:"reinvocationPolicy,omitempty"`
+}
+
+// version 1 of kubernetesCustomResourceConversion configuration
+type KubernetesConversionConfigV1 struct {
+	Name                 string   `json:"name,omitempty"`
+	CrdName              string   `json:"crdName,omitempty"`
+	IncludeSnapshotsFrom []string `json:"includeSnapshotsFrom,omitempty"`
+	Group                string   `json:"group,omitempty"`
+}
+
+// IsEqual compares versioned configurations. Effective values are not compared
+// because they contain generated ids.
+func (c *HookConfig) IsEqual(other *HookConfig) bool {
+	if other == nil {
+		return false
+	}
+	return c.Version == other.Version &&
+		reflect.DeepEqual(c.V0, other.V0) &&
+		reflect.DeepEqual(c.V1, other.V1)
+}
+
 // LoadAndValidate loads config from bytes and validate it. Returns multierror.
 func (c *HookConfig) LoadAndValidate(data []byte) error {
 	// - unmarshal json into map
@@ -275,6 +355,8 @@ func (c *HookConfig) ConvertAndCheckV0() (err error) {
 
 // ConvertAndCheckV0 fills non-versioned structures and run inter-field checks not covered by OpenAPI schemas.
 func (c *HookConfig) ConvertAndCheckV1() (err error) {
+	c.Runtime = c.V1.Runtime
+
 	c.OnStartup, err = c.ConvertOnStartup(c.V1.OnStartup)
 	if err != nil {
 		return err
@@ -325,6 +407,10 @@ func (c *HookConfig) ConvertAndCheckV1() (err error) {
 		} else {
 			kubeConfig.Queue = kubeCfg.Queue
 		}
+		kubeConfig.QueuePolicy = ConvertQueuePolicyV1(kubeCfg.QueuePolicy)
+		// Timeout is checked by CheckOnKubernetesEventV1.
+		kubeConfig.Timeout, _ = ParseTimeout(kubeCfg.Timeout)
+		kubeConfig.Retry = ConvertRetryV1(kubeCfg.Retry)
 		kubeConfig.Group = kubeCfg.Group
 
 		// ExecuteHookOnSynchronization is enabled by default.
@@ -346,6 +432,19 @@ func (c *HookConfig) ConvertAndCheckV1() (err error) {
 		}
 		kubeConfig.Monitor.KeepFullObjectsInMemory = kubeConfig.KeepFullObjectsInMemory
 
+		// Watch metadata only if requested or if full objects are not needed for the hook and the jqFilter.
+		switch kubeCfg.WatchMode {
+		case WatchModeMetadataOnly:
+			kubeConfig.Monitor.MetadataOnly = true
+		case "":
+			kubeConfig.Monitor.MetadataOnly = !kubeConfig.KeepFullObjectsInMemory &&
+				kube_events_manager.IsMetadataOnlyJqFilter(kubeCfg.JqFilter)
+		}
+
+		// Debounce and maxWait are checked by CheckOnKubernetesEventV1.
+		kubeConfig.Monitor.Debounce, _ = ParseTimeout(kubeCfg.DebouncePeriod())
+		kubeConfig.Monitor.MaxWait, _ = ParseTimeout(kubeCfg.MaxWait)
+
 		c.OnKubernetesEvents = append(c.OnKubernetesEvents, kubeConfig)
 	}
 
@@ -397,6 +496,48 @@ func (c *HookConfig) ConvertAndCheckV1() (err error) {
 		return err
 	}
 
+	c.KubernetesMutating = []MutatingConfig{}
+	for i, rawMutating := range c.V1.KubernetesMutating {
+		err := c.CheckMutatingV1(rawMutating)
+		if err != nil {
+			return fmt.Errorf("invalid kubernetesMutating config [%d]: %v", i, err)
+		}
+		mutating, err := c.ConvertMutatingV1(rawMutating)
+		if err != nil {
+			return err
+		}
+		c.KubernetesMutating = append(c.KubernetesMutating, mutating)
+	}
+	// Validate mutating webhooks
+	mutatingWebhooks := []v1.MutatingWebhook{}
+	for _, cfg := range c.KubernetesMutating {
+		mutatingWebhooks = append(mutatingWebhooks, *cfg.Webhook.MutatingWebhook)
+	}
+	err = validation.ValidateMutatingWebhooks(&v1.MutatingWebhookConfiguration{
+		Webhooks: mutatingWebhooks,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.KubernetesConversion = []ConversionConfig{}
+	crdNames := map[string]bool{}
+	for i, rawConversion := range c.V1.KubernetesConversion {
+		err := c.CheckConversionV1(rawConversion)
+		if err != nil {
+			return fmt.Errorf("invalid kubernetesCustomResourceConversion config [%d]: %v", i, err)
+		}
+		if crdNames[rawConversion.CrdName] {
+			return fmt.Errorf("invalid kubernetesCustomResourceConversion config [%d]: crdName '%s' is already used", i, rawConversion.CrdName)
+		}
+		crdNames[rawConversion.CrdName] = true
+		conversion, err := c.ConvertConversionV1(rawConversion)
+		if err != nil {
+			return err
+		}
+		c.KubernetesConversion = append(c.KubernetesConversion, conversion)
+	}
+
 	// Update IncludeSnapshotsFrom for every binding with a group.
 	// Merge binding's IncludeSnapshotsFrom with snapshots list calculated for group.
 	var groupSnapshots = make(map[string][]string)
@@ -433,6 +574,22 @@ func (c *HookConfig) ConvertAndCheckV1() (err error) {
 		newValidating = append(newValidating, cfg)
 	}
 	c.KubernetesValidating = newValidating
+	newMutating := make([]MutatingConfig, 0)
+	for _, cfg := range c.KubernetesMutating {
+		if snapshots, ok := groupSnapshots[cfg.Group]; ok {
+			cfg.IncludeSnapshotsFrom = MergeArrays(cfg.IncludeSnapshotsFrom, snapshots)
+		}
+		newMutating = append(newMutating, cfg)
+	}
+	c.KubernetesMutating = newMutating
+	newConversion := make([]ConversionConfig, 0)
+	for _, cfg := range c.KubernetesConversion {
+		if snapshots, ok := groupSnapshots[cfg.Group]; ok {
+			cfg.IncludeSnapshotsFrom = MergeArrays(cfg.IncludeSnapshotsFrom, snapshots)
+		}
+		newConversion = append(newConversion, cfg)
+	}
+	c.KubernetesConversion = newConversion
 
 	return nil
 }
@@ -460,7 +617,7 @@ func MergeArrays(a1 []string, a2 []string) []string {
 func (c *HookConfig) Bindings() []BindingType {
 	res := []BindingType{}
 
-	for _, binding := range []BindingType{OnStartup, Schedule, OnKubernetesEvent, KubernetesValidating} {
+	for _, binding := range []BindingType{OnStartup, Schedule, OnKubernetesEvent, KubernetesValidating, KubernetesMutating, KubernetesConversion} {
 		if c.HasBinding(binding) {
 			res = append(res, binding)
 		}
@@ -479,6 +636,10 @@ func (c *HookConfig) HasBinding(binding BindingType) bool {
 		return len(c.OnKubernetesEvents) > 0
 	case KubernetesValidating:
 		return len(c.KubernetesValidating) > 0
+	case KubernetesMutating:
+		return len(c.KubernetesMutating) > 0
+	case KubernetesConversion:
+		return len(c.KubernetesConversion) > 0
 	}
 	return false
 }
@@ -536,11 +697,147 @@ func (c *HookConfig) ConvertScheduleV1(schV1 ScheduleConfigV1) (ScheduleConfig,
 	} else {
 		res.Queue = schV1.Queue
 	}
+	res.QueuePolicy = ConvertQueuePolicyV1(schV1.QueuePolicy)
+	// Timeout is checked by CheckScheduleV1.
+	res.Timeout, _ = ParseTimeout(schV1.Timeout)
+	res.Retry = ConvertRetryV1(schV1.Retry)
 	res.Group = schV1.Group
 
 	return res, nil
 }
 
+// ConvertQueuePolicyV1 returns an effective queue policy. 'dropOldest' is a default for onOverflow,
+// 'object' is a default for orderBy.
+func ConvertQueuePolicyV1(policyV1 *QueuePolicyV1) *QueuePolicy {
+	if policyV1 == nil {
+		return nil
+	}
+	res := &QueuePolicy{
+		MaxPending:  policyV1.MaxPending,
+		OnOverflow:  policyV1.OnOverflow,
+		Concurrency: policyV1.Concurrency,
+		OrderBy:     policyV1.OrderBy,
+	}
+	if policyV1.Dedupe == DedupeByObject {
+		res.Dedupe = DedupeByObject
+	}
+	if res.OnOverflow == "" {
+		res.OnOverflow = OverflowDropOldest
+	}
+	if res.OrderBy == "" {
+		res.OrderBy = OrderByObject
+	}
+	return res
+}
+
+// ParseTimeout parses a duration of a hook run, an empty string means no timeout.
+func ParseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration")
+	}
+	return d, nil
+}
+
+// BindingTimeout returns a timeout for a schedule or a kubernetes binding or 0 if timeout is not set.
+func (c *HookConfig) BindingTimeout(bindingName string) time.Duration {
+	var res time.Duration
+	for _, cfg := range c.Schedules {
+		if cfg.BindingName == bindingName && cfg.Timeout > res {
+			res = cfg.Timeout
+		}
+	}
+	for _, cfg := range c.OnKubernetesEvents {
+		if cfg.BindingName == bindingName && cfg.Timeout > res {
+			res = cfg.Timeout
+		}
+	}
+	return res
+}
+
+// BindingRetryPolicy returns a retry policy for a schedule or a kubernetes binding or nil if policy is not set.
+func (c *HookConfig) BindingRetryPolicy(bindingName string) *RetryPolicy {
+	for _, cfg := range c.Schedules {
+		if cfg.BindingName == bindingName && cfg.Retry != nil {
+			return cfg.Retry
+		}
+	}
+	for _, cfg := range c.OnKubernetesEvents {
+		if cfg.BindingName == bindingName && cfg.Retry != nil {
+			return cfg.Retry
+		}
+	}
+	return nil
+}
+
+// BindingQueuePolicy returns a queue policy for a schedule or a kubernetes binding or nil if policy is not set.
+func (c *HookConfig) BindingQueuePolicy(bindingName string) *QueuePolicy {
+	for _, cfg := range c.Schedules {
+		if cfg.BindingName == bindingName && cfg.QueuePolicy != nil {
+			return cfg.QueuePolicy
+		}
+	}
+	for _, cfg := range c.OnKubernetesEvents {
+		if cfg.BindingName == bindingName && cfg.QueuePolicy != nil {
+			return cfg.QueuePolicy
+		}
+	}
+	return nil
+}
+
+// ConvertRetryV1 returns an effective retry policy. Delays are checked by CheckRetryV1.
+// 'deadLetter' is a default for onExhausted.
+func ConvertRetryV1(retryV1 *RetryV1) *RetryPolicy {
+	if retryV1 == nil {
+		return nil
+	}
+	res := &RetryPolicy{
+		MaxAttempts: retryV1.MaxAttempts,
+		OnExhausted: retryV1.OnExhausted,
+	}
+	res.InitialDelay, _ = ParseTimeout(retryV1.InitialDelay)
+	res.MaxDelay, _ = ParseTimeout(retryV1.MaxDelay)
+	if res.OnExhausted == "" {
+		res.OnExhausted = ExhaustedDeadLetter
+	}
+	return res
+}
+
+func (c *HookConfig) CheckRetryV1(retryV1 *RetryV1) (allErr error) {
+	if retryV1 == nil {
+		return nil
+	}
+	initialDelay, err := ParseTimeout(retryV1.InitialDelay)
+	if err != nil {
+		allErr = multierror.Append(allErr, fmt.Errorf("retry.initialDelay is invalid: %v", err))
+	}
+	maxDelay, err := ParseTimeout(retryV1.MaxDelay)
+	if err != nil {
+		allErr = multierror.Append(allErr, fmt.Errorf("retry.maxDelay is invalid: %v", err))
+	}
+	if maxDelay > 0 && initialDelay > maxDelay {
+		allErr = multierror.Append(allErr, fmt.Errorf("retry.initialDelay should not be greater than retry.maxDelay"))
+	}
+	return allErr
+}
+
+// CheckQueuePolicyV1 returns an error if concurrency is set for the "main" queue.
+func (c *HookConfig) CheckQueuePolicyV1(queueName string, policyV1 *QueuePolicyV1) error {
+	if policyV1 == nil || policyV1.Concurrency <= 1 {
+		return nil
+	}
+	if queueName == "" || queueName == "main" {
+		return fmt.Errorf("queuePolicy.concurrency is supported only for named queues")
+	}
+	return nil
+}
+
 func (c *HookConfig) CheckScheduleV0(schV0 ScheduleConfigV0) error {
 	_, err := cron.Parse(schV0.Crontab)
 	if err != nil {
@@ -563,6 +860,25 @@ func (c *HookConfig) CheckScheduleV1(schV1 ScheduleConfigV1) (allErr error) {
 		}
 	}
 
+	if schV1.QueuePolicy != nil && schV1.QueuePolicy.Dedupe == DedupeByObject {
+		allErr = multierror.Append(allErr, fmt.Errorf("queuePolicy.dedupe '%s' is not supported for schedule bindings", DedupeByObject))
+	}
+
+	err = c.CheckQueuePolicyV1(schV1.Queue, schV1.QueuePolicy)
+	if err != nil {
+		allErr = multierror.Append(allErr, err)
+	}
+
+	_, err = ParseTimeout(schV1.Timeout)
+	if err != nil {
+		allErr = multierror.Append(allErr, fmt.Errorf("timeout is invalid: %v", err))
+	}
+
+	err = c.CheckRetryV1(schV1.Retry)
+	if err != nil {
+		allErr = multierror.Append(allErr, err)
+	}
+
 	return allErr
 }
 
@@ -602,6 +918,39 @@ func (c *HookConfig) CheckOnKubernetesEventV1(kubeCfg OnKubernetesEventConfigV1,
 		}
 	}
 
+	err := c.CheckQueuePolicyV1(kubeCfg.Queue, kubeCfg.QueuePolicy)
+	if err != nil {
+		allErr = multierror.Append(allErr, err)
+	}
+
+	_, err = ParseTimeout(kubeCfg.Timeout)
+	if err != nil {
+		allErr = multierror.Append(allErr, fmt.Errorf("timeout is invalid: %v", err))
+	}
+
+	err = c.CheckRetryV1(kubeCfg.Retry)
+	if err != nil {
+		allErr = multierror.Append(allErr, err)
+	}
+
+	if kubeCfg.Debounce != "" && kubeCfg.BatchWindow != "" {
+		allErr = multierror.Append(allErr, fmt.Errorf("debounce and batchWindow are mutually exclusive"))
+	}
+	debounce, err := ParseTimeout(kubeCfg.DebouncePeriod())
+	if err != nil {
+		allErr = multierror.Append(allErr, fmt.Errorf("debounce is invalid: %v", err))
+	}
+	maxWait, err := ParseTimeout(kubeCfg.MaxWait)
+	if err != nil {
+		allErr = multierror.Append(allErr, fmt.Errorf("maxWait is invalid: %v", err))
+	}
+	if maxWait > 0 && debounce == 0 {
+		allErr = multierror.Append(allErr, fmt.Errorf("maxWait requires debounce"))
+	}
+	if maxWait > 0 && maxWait < debounce {
+		allErr = multierror.Append(allErr, fmt.Errorf("maxWait should not be less than debounce"))
+	}
+
 	return allErr
 }
 
@@ -678,6 +1027,120 @@ func (c *HookConfig) ConvertValidatingV1(cfgV1 KubernetesValidatingConfigV1) (Va
 	return cfg, nil
 }
 
+func (c *HookConfig) CheckMutatingV1(cfgV1 KubernetesMutatingConfigV1) (allErr error) {
+	var err error
+
+	if len(cfgV1.IncludeSnapshotsFrom) > 0 {
+		err = c.CheckIncludeSnapshots(cfgV1.IncludeSnapshotsFrom...)
+		if err != nil {
+			allErr = multierror.Append(allErr, fmt.Errorf("includeSnapshotsFrom is invalid: %v", err))
+		}
+	}
+
+	if cfgV1.LabelSelector != nil {
+		_, err := kube_events_manager.FormatLabelSelector(cfgV1.LabelSelector)
+		if err != nil {
+			allErr = multierror.Append(allErr, fmt.Errorf("labelSelector is invalid: %v", err))
+		}
+	}
+
+	if cfgV1.Namespace != nil && cfgV1.Namespace.LabelSelector != nil {
+		_, err := kube_events_manager.FormatLabelSelector(cfgV1.Namespace.LabelSelector)
+		if err != nil {
+			allErr = multierror.Append(allErr, fmt.Errorf("namespace.labelSelector is invalid: %v", err))
+		}
+	}
+
+	return allErr
+}
+
+func (c *HookConfig) ConvertMutatingV1(cfgV1 KubernetesMutatingConfigV1) (MutatingConfig, error) {
+	cfg := MutatingConfig{}
+
+	cfg.Group = cfgV1.Group
+	cfg.IncludeSnapshotsFrom = cfgV1.IncludeSnapshotsFrom
+	cfg.BindingName = cfgV1.Name
+
+	DefaultFailurePolicy := v1.Fail
+	DefaultSideEffects := v1.SideEffectClassNone
+	DefaultTimeoutSeconds := int32(10)
+	DefaultReinvocationPolicy := v1.NeverReinvocationPolicy
+
+	webhook := &v1.MutatingWebhook{
+		Name:  cfgV1.Name,
+		Rules: cfgV1.Rules,
+	}
+	if cfgV1.Namespace != nil {
+		webhook.NamespaceSelector = cfgV1.Namespace.LabelSelector
+	}
+	if cfgV1.LabelSelector != nil {
+		webhook.ObjectSelector = cfgV1.LabelSelector
+	}
+	if cfgV1.FailurePolicy != nil {
+		webhook.FailurePolicy = cfgV1.FailurePolicy
+	} else {
+		webhook.FailurePolicy = &DefaultFailurePolicy
+	}
+	if cfgV1.SideEffects != nil {
+		webhook.SideEffects = cfgV1.SideEffects
+	} else {
+		webhook.SideEffects = &DefaultSideEffects
+	}
+	if cfgV1.TimeoutSeconds != nil {
+		webhook.TimeoutSeconds = cfgV1.TimeoutSeconds
+	} else {
+		webhook.TimeoutSeconds = &DefaultTimeoutSeconds
+	}
+	if cfgV1.ReinvocationPolicy != nil {
+		webhook.ReinvocationPolicy = cfgV1.ReinvocationPolicy
+	} else {
+		webhook.ReinvocationPolicy = &DefaultReinvocationPolicy
+	}
+
+	cfg.Webhook = &validating_webhook.MutatingWebhookConfig{
+		MutatingWebhook: webhook,
+	}
+	cfg.Webhook.Metadata.LogLabels = map[string]string{}
+	cfg.Webhook.Metadata.MetricLabels = map[string]string{}
+
+	return cfg, nil
+}
+
+func (c *HookConfig) CheckConversionV1(cfgV1 KubernetesConversionConfigV1) (allErr error) {
+	if len(cfgV1.IncludeSnapshotsFrom) > 0 {
+		err := c.CheckIncludeSnapshots(cfgV1.IncludeSnapshotsFrom...)
+		if err != nil {
+			allErr = multierror.Append(allErr, fmt.Errorf("includeSnapshotsFrom is invalid: %v", err))
+		}
+	}
+
+	// CRD name is "<plural>.<group>".
+	if len(strings.Split(cfgV1.CrdName, ".")) < 3 {
+		allErr = multierror.Append(allErr, fmt.Errorf("crdName '%s' is invalid: should be in form '<plural>.<group>'", cfgV1.CrdName))
+	}
+
+	return allErr
+}
+
+func (c *HookConfig) ConvertConversionV1(cfgV1 KubernetesConversionConfigV1) (ConversionConfig, error) {
+	cfg := ConversionConfig{}
+
+	cfg.Group = cfgV1.Group
+	cfg.IncludeSnapshotsFrom = cfgV1.IncludeSnapshotsFrom
+	cfg.BindingName = cfgV1.Name
+	if cfg.BindingName == "" {
+		cfg.BindingName = cfgV1.CrdName
+	}
+
+	cfg.Webhook = &validating_webhook.ConversionWebhookConfig{
+		CrdName: cfgV1.CrdName,
+	}
+	cfg.Webhook.Metadata.LogLabels = map[string]string{}
+	cfg.Webhook.Metadata.MetricLabels = map[string]string{}
+
+	return cfg, nil
+}
+
 // CheckIncludeSnapshots check if all includes has corresponding kubernetes
 // binding. Rules:
 //
@@ -729,7 +1192,15 @@ func ConvertFloatForBinding(value interface{}, bindingName string) (*float64, er
 	if value == nil {
 		return nil, nil
 	}
-	if floatValue, ok := value.(float64); ok {
+	switch v := value.(type) {
+	case float64:
+		return &v, nil
+	// integers are possible in configs of Go hooks
+	case int:
+		floatValue := float64(v)
+		return &floatValue, nil
+	case int64:
+		floatValue := float64(v)
 		return &floatValue, nil
 	}
 	return nil, fmt.Errorf("binding %s has unsupported value '%v'", bindingName, value)
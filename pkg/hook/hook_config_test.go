@@ -3,9 +3,12 @@ package hook
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
 	"github.com/hashicorp/go-multierror"
 	v1 "k8s.io/api/admissionregistration/v1"
 )
@@ -629,6 +632,246 @@ kubernetesValidating:
     apiVersions: ["v1"]
     resources: ["pods"]
   timeoutSeconds: 32
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetesMutating",
+			`
+configVersion: v1
+kubernetes:
+- name: pods
+  kind: pods
+kubernetesMutating:
+- name: default.example.com
+  rules:
+  - operations: ["CREATE"]
+    apiGroups: [""]
+    apiVersions: ["v1"]
+    resources: ["pods"]
+- name: full.example.com
+  includeSnapshotsFrom: ["pods"]
+  rules:
+  - operations: ["CREATE", "UPDATE"]
+    apiGroups: ["apps"]
+    apiVersions: ["v1"]
+    resources: ["deployments"]
+  failurePolicy: Ignore
+  reinvocationPolicy: IfNeeded
+  timeoutSeconds: 5
+`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+
+				g.Expect(hookConfig.KubernetesValidating).Should(HaveLen(0))
+				g.Expect(hookConfig.KubernetesMutating).Should(HaveLen(2))
+				g.Expect(hookConfig.HasBinding("kubernetesMutating")).To(BeTrue())
+
+				// Section with default values
+				cfg := hookConfig.KubernetesMutating[0]
+				g.Expect(cfg.BindingName).To(Equal("default.example.com"))
+				g.Expect(cfg.Webhook).ShouldNot(BeNil())
+				g.Expect(*cfg.Webhook.FailurePolicy).To(Equal(v1.Fail))
+				g.Expect(*cfg.Webhook.SideEffects).To(Equal(v1.SideEffectClassNone))
+				g.Expect(*cfg.Webhook.TimeoutSeconds).To(Equal(int32(10)))
+				g.Expect(*cfg.Webhook.ReinvocationPolicy).To(Equal(v1.NeverReinvocationPolicy))
+
+				cfg = hookConfig.KubernetesMutating[1]
+				g.Expect(cfg.IncludeSnapshotsFrom).To(Equal([]string{"pods"}))
+				g.Expect(*cfg.Webhook.FailurePolicy).To(Equal(v1.Ignore))
+				g.Expect(*cfg.Webhook.TimeoutSeconds).To(Equal(int32(5)))
+				g.Expect(*cfg.Webhook.ReinvocationPolicy).To(Equal(v1.IfNeededReinvocationPolicy))
+			},
+		},
+		{
+			"v1 kubernetesMutating bad reinvocationPolicy",
+			`
+configVersion: v1
+kubernetesMutating:
+- name: default.example.com
+  rules:
+  - operations: ["CREATE"]
+    apiGroups: [""]
+    apiVersions: ["v1"]
+    resources: ["pods"]
+  reinvocationPolicy: Always
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetesCustomResourceConversion",
+			`
+configVersion: v1
+kubernetes:
+- name: crontabs
+  kind: CronTab
+  apiVersion: stable.example.com/v1
+kubernetesCustomResourceConversion:
+- crdName: crontabs.stable.example.com
+  includeSnapshotsFrom: ["crontabs"]
+- name: backups
+  crdName: backups.stable.example.com
+`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+
+				g.Expect(hookConfig.KubernetesConversion).Should(HaveLen(2))
+				g.Expect(hookConfig.HasBinding("kubernetesCustomResourceConversion")).To(BeTrue())
+
+				cfg := hookConfig.KubernetesConversion[0]
+				g.Expect(cfg.BindingName).To(Equal("crontabs.stable.example.com"))
+				g.Expect(cfg.Webhook.CrdName).To(Equal("crontabs.stable.example.com"))
+				g.Expect(cfg.IncludeSnapshotsFrom).To(Equal([]string{"crontabs"}))
+
+				cfg = hookConfig.KubernetesConversion[1]
+				g.Expect(cfg.BindingName).To(Equal("backups"))
+			},
+		},
+		{
+			"v1 kubernetesCustomResourceConversion duplicated crdName",
+			`
+configVersion: v1
+kubernetesCustomResourceConversion:
+- crdName: crontabs.stable.example.com
+- crdName: crontabs.stable.example.com
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetes with watchMode",
+			`
+configVersion: v1
+kubernetes:
+- name: explicit
+  kind: Secret
+  watchMode: metadataOnly
+- name: auto
+  kind: ConfigMap
+  keepFullObjectsInMemory: false
+  jqFilter: ".metadata.labels"
+- name: full
+  kind: ConfigMap
+  keepFullObjectsInMemory: false
+  jqFilter: ".metadata.labels"
+  watchMode: full
+- name: spec
+  kind: ConfigMap
+  keepFullObjectsInMemory: false
+  jqFilter: ".data"
+- name: keep
+  kind: ConfigMap
+  jqFilter: ".metadata.labels"
+`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				g.Expect(hookConfig.OnKubernetesEvents).To(HaveLen(5))
+				g.Expect(hookConfig.OnKubernetesEvents[0].Monitor.MetadataOnly).To(BeTrue())
+				g.Expect(hookConfig.OnKubernetesEvents[1].Monitor.MetadataOnly).To(BeTrue())
+				g.Expect(hookConfig.OnKubernetesEvents[2].Monitor.MetadataOnly).To(BeFalse())
+				g.Expect(hookConfig.OnKubernetesEvents[3].Monitor.MetadataOnly).To(BeFalse())
+				g.Expect(hookConfig.OnKubernetesEvents[4].Monitor.MetadataOnly).To(BeFalse())
+			},
+		},
+		{
+			"v1 kubernetes with debounce",
+			`
+configVersion: v1
+kubernetes:
+- name: secrets
+  kind: Secret
+  debounce: 5s
+  maxWait: 1m
+`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				g.Expect(hookConfig.OnKubernetesEvents).To(HaveLen(1))
+				g.Expect(hookConfig.OnKubernetesEvents[0].Monitor.Debounce).To(Equal(5 * time.Second))
+				g.Expect(hookConfig.OnKubernetesEvents[0].Monitor.MaxWait).To(Equal(time.Minute))
+			},
+		},
+		{
+			"v1 kubernetes with batchWindow",
+			`
+configVersion: v1
+kubernetes:
+- name: secrets
+  kind: Secret
+  batchWindow: 5s
+  maxWait: 1m
+`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				g.Expect(hookConfig.OnKubernetesEvents).To(HaveLen(1))
+				g.Expect(hookConfig.OnKubernetesEvents[0].Monitor.Debounce).To(Equal(5 * time.Second))
+				g.Expect(hookConfig.OnKubernetesEvents[0].Monitor.MaxWait).To(Equal(time.Minute))
+			},
+		},
+		{
+			"v1 kubernetes with debounce and batchWindow",
+			`
+configVersion: v1
+kubernetes:
+- name: secrets
+  kind: Secret
+  debounce: 5s
+  batchWindow: 5s
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetes with maxWait less than debounce",
+			`
+configVersion: v1
+kubernetes:
+- name: secrets
+  kind: Secret
+  debounce: 5s
+  maxWait: 1s
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetes with maxWait without debounce",
+			`
+configVersion: v1
+kubernetes:
+- name: secrets
+  kind: Secret
+  maxWait: 1m
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetes with bad watchMode",
+			`
+configVersion: v1
+kubernetes:
+- name: pods
+  kind: Pod
+  watchMode: partial
+`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"v1 kubernetesCustomResourceConversion bad crdName",
+			`
+configVersion: v1
+kubernetesCustomResourceConversion:
+- crdName: crontabs
 `,
 			func() {
 				g.Expect(err).Should(HaveOccurred())
@@ -904,6 +1147,170 @@ func Test_HookConfig_V1_Kubernetes_Validate(t *testing.T) {
 				//t.Logf("expected error was: %v\n", err)
 			},
 		},
+		{
+			"queue policy",
+			`{
+              "configVersion":"v1",
+              "kubernetes":[
+                {
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "queuePolicy": {
+                    "dedupe": "byObject",
+                    "maxPending": 10
+                  }
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				policy := hookConfig.OnKubernetesEvents[0].QueuePolicy
+				g.Expect(policy).ShouldNot(BeNil())
+				g.Expect(policy.Dedupe).Should(Equal(DedupeByObject))
+				g.Expect(policy.MaxPending).Should(Equal(10))
+				g.Expect(policy.OnOverflow).Should(Equal(OverflowDropOldest))
+			},
+		},
+		{
+			"bad queue policy",
+			`{
+              "configVersion":"v1",
+              "kubernetes":[
+                {
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "queuePolicy": {
+                    "dedupe": "byName",
+                    "maxPending": 0,
+                    "onOverflow": "dropNewest"
+                  }
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+			},
+		},
+		{
+			"queue concurrency",
+			`{
+              "configVersion":"v1",
+              "kubernetes":[
+                {
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "queue": "cloud-api",
+                  "queuePolicy": {
+                    "concurrency": 4,
+                    "orderBy": "namespace"
+                  }
+                },
+                {
+                  "apiVersion":"v1",
+                  "kind":"Service",
+                  "queuePolicy": {
+                    "concurrency": 4
+                  }
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+				g.Expect(err.Error()).Should(ContainSubstring("only for named queues"))
+			},
+		},
+		{
+			"timeout",
+			`{
+              "configVersion":"v1",
+              "kubernetes":[
+                {
+                  "name":"pods",
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "timeout": "2m"
+                },
+                {
+                  "name":"services",
+                  "apiVersion":"v1",
+                  "kind":"Service"
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				g.Expect(hookConfig.BindingTimeout("pods")).Should(Equal(2 * time.Minute))
+				g.Expect(hookConfig.BindingTimeout("services")).Should(Equal(time.Duration(0)))
+			},
+		},
+		{
+			"bad timeout",
+			`{
+              "configVersion":"v1",
+              "kubernetes":[
+                {
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "timeout": "2 minutes"
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+				g.Expect(err.Error()).Should(ContainSubstring("timeout is invalid"))
+			},
+		},
+		{
+			"retry",
+			`{
+              "configVersion":"v1",
+              "schedule":[
+                {
+                  "name":"every-minute",
+                  "crontab":"* * * * *",
+                  "retry": {"maxAttempts": 3, "onExhausted": "drop"}
+                }
+              ],
+              "kubernetes":[
+                {
+                  "name":"pods",
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "retry": {"maxAttempts": 5, "initialDelay": "10s", "maxDelay": "5m"}
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				g.Expect(hookConfig.BindingRetryPolicy("every-minute")).Should(Equal(&RetryPolicy{
+					MaxAttempts: 3,
+					OnExhausted: ExhaustedDrop,
+				}))
+				g.Expect(hookConfig.BindingRetryPolicy("pods")).Should(Equal(&RetryPolicy{
+					MaxAttempts:  5,
+					InitialDelay: 10 * time.Second,
+					MaxDelay:     5 * time.Minute,
+					OnExhausted:  ExhaustedDeadLetter,
+				}))
+			},
+		},
+		{
+			"bad retry",
+			`{
+              "configVersion":"v1",
+              "kubernetes":[
+                {
+                  "apiVersion":"v1",
+                  "kind":"Pod",
+                  "retry": {"maxAttempts": 5, "initialDelay": "1m", "maxDelay": "10s"}
+                }
+              ]
+            }`,
+			func() {
+				g.Expect(err).Should(HaveOccurred())
+				g.Expect(err.Error()).Should(ContainSubstring("retry.initialDelay should not be greater than retry.maxDelay"))
+			},
+		},
 	}
 
 	for _, test := range tests {
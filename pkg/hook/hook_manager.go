@@ -2,11 +2,11 @@ package hook
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"sort"
-	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -14,21 +14,26 @@ import (
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
 	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
 
-	"github.com/flant/shell-operator/pkg/executor"
 	"github.com/flant/shell-operator/pkg/hook/controller"
 	"github.com/flant/shell-operator/pkg/kube_events_manager"
 	"github.com/flant/shell-operator/pkg/schedule_manager"
+	"github.com/flant/shell-operator/pkg/utils/checksum"
 	utils_file "github.com/flant/shell-operator/pkg/utils/file"
 	"github.com/flant/shell-operator/pkg/validating_webhook"
 )
 
 type HookManager interface {
 	Init() error
+	Reload() (*HooksDiff, error)
+	IsChanged() (bool, error)
 	Run()
+	Stop()
 	WithDirectories(workingDir string, tempDir string)
 	WithKubeEventManager(kube_events_manager.KubeEventsManager)
 	WithScheduleManager(schedule_manager.ScheduleManager)
 	WithWebhookManager(*validating_webhook.WebhookManager)
+	WithRuntime(HookRuntime)
+	WithGoHooks(hooks ...*Hook)
 	WorkingDir() string
 	TempDir() string
 	GetHook(name string) *Hook
@@ -37,6 +42,8 @@ type HookManager interface {
 	HandleKubeEvent(kubeEvent KubeEvent, createTaskFn func(*Hook, controller.BindingExecutionInfo))
 	HandleScheduleEvent(crontab string, createTaskFn func(*Hook, controller.BindingExecutionInfo))
 	HandleValidatingEvent(event ValidatingEvent, createTaskFn func(*Hook, controller.BindingExecutionInfo))
+	HandleMutatingEvent(event MutatingEvent, createTaskFn func(*Hook, controller.BindingExecutionInfo))
+	HandleConversionEvent(event ConversionEvent, createTaskFn func(*Hook, controller.BindingExecutionInfo))
 }
 
 type hookManager struct {
@@ -46,6 +53,8 @@ type hookManager struct {
 	kubeEventsManager kube_events_manager.KubeEventsManager
 	scheduleManager   schedule_manager.ScheduleManager
 	webhookManager    *validating_webhook.WebhookManager
+	runtime           HookRuntime
+	goHooks           []*Hook
 
 	// sorted hook names
 	hookNamesInOrder []string
@@ -54,6 +63,34 @@ type hookManager struct {
 	hooksByName map[string]*Hook
 	// index to search hooks by binding type
 	hooksInOrder map[BindingType][]*Hook
+	// checksums of hook files by hook name
+	checksums map[string]string
+
+	m sync.RWMutex
+
+	// runtimes for hooks with the 'runtime' field that differs from the default runtime
+	runtimes   map[string]HookRuntime
+	runtimesMu sync.Mutex
+}
+
+// HooksDiff describes changes in hooks after Reload.
+type HooksDiff struct {
+	Added   []*Hook
+	Removed []*Hook
+	Updated []HookUpdate
+}
+
+// HookUpdate is a pair of hooks with the same name and different configs.
+// UnchangedKubernetesBindings are 'kubernetes' bindings with equal configs in both hooks.
+// The new hook reuses monitors of these bindings, so their informers are not restarted.
+type HookUpdate struct {
+	Old                         *Hook
+	New                         *Hook
+	UnchangedKubernetesBindings []string
+}
+
+func (d *HooksDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0
 }
 
 // hookManager should implement HookManager
@@ -64,6 +101,9 @@ func NewHookManager() *hookManager {
 		hooksByName:      make(map[string]*Hook),
 		hookNamesInOrder: make([]string, 0),
 		hooksInOrder:     make(map[BindingType][]*Hook),
+		checksums:        make(map[string]string),
+		runtime:          NewExecHookRuntime(),
+		runtimes:         make(map[string]HookRuntime),
 	}
 }
 
@@ -84,6 +124,37 @@ func (hm *hookManager) WithWebhookManager(mgr *validating_webhook.WebhookManager
 	hm.webhookManager = mgr
 }
 
+// WithRuntime sets a default runtime. It is used to get configs from all hooks and to run
+// hooks without the 'runtime' field in the config.
+func (hm *hookManager) WithRuntime(runtime HookRuntime) {
+	hm.runtime = runtime
+}
+
+// hookRuntime returns a runtime by the name from the hook config. Runtimes other than
+// the default one are created on the first use.
+func (hm *hookManager) hookRuntime(name string) (HookRuntime, error) {
+	if name == "" || name == hm.runtime.Name() {
+		return hm.runtime, nil
+	}
+
+	hm.runtimesMu.Lock()
+	defer hm.runtimesMu.Unlock()
+	if runtime, has := hm.runtimes[name]; has {
+		return runtime, nil
+	}
+	runtime, err := NewHookRuntime(name)
+	if err != nil {
+		return nil, err
+	}
+	hm.runtimes[name] = runtime
+	return runtime, nil
+}
+
+// WithGoHooks adds hooks created with NewGoHook. They are registered on Init after hooks from WorkingDir.
+func (hm *hookManager) WithGoHooks(hooks ...*Hook) {
+	hm.goHooks = append(hm.goHooks, hooks...)
+}
+
 func (hm *hookManager) WorkingDir() string {
 	return hm.workingDir
 }
@@ -92,54 +163,244 @@ func (hm *hookManager) TempDir() string {
 	return hm.tempDir
 }
 
-// Init finds executables in WorkingDir, execute them with --config argument and add them into indices.
+// Init finds executables in WorkingDir, gets config from them using the hook runtime and add them into indices.
+// Go hooks are added into indices after executables.
 func (hm *hookManager) Init() error {
 	log.Info("Initialize hooks manager. Search for and load all hooks.")
 
+	hm.m.Lock()
 	hm.hooksInOrder = make(map[BindingType][]*Hook)
 	hm.hooksByName = make(map[string]*Hook)
+	hm.hookNamesInOrder = make([]string, 0)
+	hm.checksums = make(map[string]string)
+	hm.m.Unlock()
+
+	for _, hook := range hm.goHooks {
+		log.WithField("hook", hook.Name).
+			WithField("phase", "config").
+			Infof("Load config for go hook")
+		hm.initHook(hook)
+	}
 
+	_, err := hm.Reload()
+	return err
+}
+
+// Reload searches executables in WorkingDir and loads config from new and changed files.
+// Hooks with changed configs are replaced with new Hook objects. Indices are not changed on error.
+// Long-lived processes of changed and removed hooks are stopped only if all hooks are loaded.
+func (hm *hookManager) Reload() (*HooksDiff, error) {
 	hooksRelativePaths, err := utils_file.RecursiveGetExecutablePaths(hm.workingDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// sort hooks by path
 	sort.Strings(hooksRelativePaths)
 	log.Debugf("  Search hooks in this paths: %+v", hooksRelativePaths)
 
+	hm.m.RLock()
+	oldHooks := hm.hooksByName
+	oldNames := hm.hookNamesInOrder
+	oldChecksums := hm.checksums
+	hm.m.RUnlock()
+
+	diff := &HooksDiff{}
+	hooks := make([]*Hook, 0)
+	checksums := make(map[string]string)
+	// Old hooks to stop after reload and new hooks to stop if reload is failed.
+	stopOld := make([]*Hook, 0)
+	loaded := make([]*Hook, 0)
+	fail := func(err error) (*HooksDiff, error) {
+		for _, hook := range loaded {
+			hook.Runtime.StopHook(hook)
+		}
+		return nil, err
+	}
+
 	for _, hookPath := range hooksRelativePaths {
+		hookName, err := filepath.Rel(hm.workingDir, hookPath)
+		if err != nil {
+			return fail(err)
+		}
+		checksums[hookName], err = checksum.CalculateChecksumOfFile(hookPath)
+		if err != nil {
+			return fail(err)
+		}
+
+		oldHook, hasOld := oldHooks[hookName]
+		if hasOld && oldChecksums[hookName] == checksums[hookName] {
+			hooks = append(hooks, oldHook)
+			continue
+		}
+
 		hook, err := hm.loadHook(hookPath)
 		if err != nil {
-			return err
+			return fail(err)
+		}
+		if hasOld {
+			// Restart long-lived process to run the new file.
+			stopOld = append(stopOld, oldHook)
+		}
+
+		switch {
+		case !hasOld:
+			diff.Added = append(diff.Added, hook)
+		case oldHook.Config.IsEqual(hook.Config):
+			log.WithField("hook", hookName).Infof("Hook file is changed, config is the same")
+			// The new Hook is not used, stop the process started for the config request.
+			hook.Runtime.StopHook(hook)
+			hook = oldHook
+		default:
+			diff.Updated = append(diff.Updated, HookUpdate{
+				Old:                         oldHook,
+				New:                         hook,
+				UnchangedKubernetesBindings: reuseKubernetesMonitors(oldHook, hook),
+			})
+		}
+		if hook != oldHook {
+			loaded = append(loaded, hook)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	for _, hookName := range oldNames {
+		oldHook := oldHooks[hookName]
+		if _, has := checksums[hookName]; has || oldHook.IsGoHook() {
+			continue
+		}
+		stopOld = append(stopOld, oldHook)
+		diff.Removed = append(diff.Removed, oldHook)
+	}
+
+	hooks = append(hooks, hm.goHooks...)
+	err = hm.setHooks(hooks, checksums)
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, hook := range stopOld {
+		hook.Runtime.StopHook(hook)
+	}
+	return diff, nil
+}
+
+// reuseKubernetesMonitors passes monitors of unchanged 'kubernetes' bindings from the old
+// hook to the new hook and returns names of these bindings. Bindings are matched by name,
+// bindings with duplicated names are not reused.
+func reuseKubernetesMonitors(oldHook *Hook, newHook *Hook) []string {
+	if oldHook.Config.V1 == nil || newHook.Config.V1 == nil {
+		return nil
+	}
+	oldIndex := kubernetesBindingIndex(oldHook.Config.OnKubernetesEvents)
+	newIndex := kubernetesBindingIndex(newHook.Config.OnKubernetesEvents)
+
+	res := make([]string, 0)
+	for j, cfg := range newHook.Config.OnKubernetesEvents {
+		i, has := oldIndex[cfg.BindingName]
+		if !has || i < 0 || newIndex[cfg.BindingName] != j {
+			continue
+		}
+		if !reflect.DeepEqual(oldHook.Config.V1.OnKubernetesEvent[i], newHook.Config.V1.OnKubernetesEvent[j]) {
+			continue
+		}
+		// The hook controller of the new hook uses the same slice of configs.
+		newHook.Config.OnKubernetesEvents[j].Monitor = oldHook.Config.OnKubernetesEvents[i].Monitor
+		res = append(res, cfg.BindingName)
+	}
+	return res
+}
+
+// kubernetesBindingIndex returns indices of bindings by name, -1 for duplicated names.
+func kubernetesBindingIndex(configs []OnKubernetesEventConfig) map[string]int {
+	index := make(map[string]int)
+	for i, cfg := range configs {
+		if _, has := index[cfg.BindingName]; has {
+			index[cfg.BindingName] = -1
+			continue
 		}
+		index[cfg.BindingName] = i
+	}
+	return index
+}
+
+// IsChanged returns true if executables in WorkingDir are added, removed or changed since last Reload.
+func (hm *hookManager) IsChanged() (bool, error) {
+	hooksRelativePaths, err := utils_file.RecursiveGetExecutablePaths(hm.workingDir)
+	if err != nil {
+		return false, err
+	}
 
-		// register hook in indices
+	hm.m.RLock()
+	oldChecksums := hm.checksums
+	hm.m.RUnlock()
+
+	if len(hooksRelativePaths) != len(oldChecksums) {
+		return true, nil
+	}
+	for _, hookPath := range hooksRelativePaths {
+		hookName, err := filepath.Rel(hm.workingDir, hookPath)
+		if err != nil {
+			return false, err
+		}
+		sum, err := checksum.CalculateChecksumOfFile(hookPath)
+		if err != nil {
+			return false, err
+		}
+		if oldChecksums[hookName] != sum {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setHooks replaces indices.
+func (hm *hookManager) setHooks(hooks []*Hook, checksums map[string]string) error {
+	hooksByName := make(map[string]*Hook)
+	hooksInOrder := make(map[BindingType][]*Hook)
+	hookNamesInOrder := make([]string, 0, len(hooks))
+
+	for _, hook := range hooks {
+		if _, has := hooksByName[hook.Name]; has {
+			return fmt.Errorf("hook '%s': hook with the same name already exists", hook.Name)
+		}
 		for _, binding := range hook.Config.Bindings() {
-			hm.hooksInOrder[binding] = append(hm.hooksInOrder[binding], hook)
+			hooksInOrder[binding] = append(hooksInOrder[binding], hook)
 		}
-		hm.hooksByName[hook.Name] = hook
-		hm.hookNamesInOrder = append(hm.hookNamesInOrder, hook.Name)
+		hooksByName[hook.Name] = hook
+		hookNamesInOrder = append(hookNamesInOrder, hook.Name)
 	}
 
+	hm.m.Lock()
+	defer hm.m.Unlock()
+	hm.hooksByName = hooksByName
+	hm.hooksInOrder = hooksInOrder
+	hm.hookNamesInOrder = hookNamesInOrder
+	hm.checksums = checksums
 	return nil
 }
 
-// TODO move --config execution to a Hook method
 func (hm *hookManager) loadHook(hookPath string) (hook *Hook, err error) {
 	hookName, err := filepath.Rel(hm.workingDir, hookPath)
 	if err != nil {
 		return nil, err
 	}
 	hook = NewHook(hookName, hookPath)
+	hook.WithRuntime(hm.runtime)
+	created := hook
+	defer func() {
+		// Stop a long-lived process started for the config request of the failed hook.
+		if err != nil {
+			hm.runtime.StopHook(created)
+		}
+	}()
 
 	hookEntry := log.WithField("hook", hook.Name).
 		WithField("phase", "config")
 
 	hookEntry.Infof("Load config from '%s'", hookPath)
 
-	envs := []string{}
-	configOutput, err := hm.execCommandOutput(hook.Name, hm.workingDir, hookPath, envs, []string{"--config"})
+	configOutput, err := hm.runtime.Config(hook, hm.workingDir)
 	if err != nil {
 		hookEntry.Errorf("Hook config output:\n%s", string(configOutput))
 		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
@@ -147,12 +408,30 @@ func (hm *hookManager) loadHook(hookPath string) (hook *Hook, err error) {
 		}
 		return nil, fmt.Errorf("cannot get config for hook '%s': %s", hookPath, err)
 	}
+	hookEntry.Debugf("Hook config output:\n%s", string(configOutput))
 
 	_, err = hook.WithConfig(configOutput)
 	if err != nil {
 		return nil, fmt.Errorf("creating hook '%s': %s", hookName, err.Error())
 	}
 
+	runtime, err := hm.hookRuntime(hook.Config.Runtime)
+	if err != nil {
+		return nil, fmt.Errorf("creating hook '%s': %s", hookName, err.Error())
+	}
+	if runtime != hm.runtime {
+		// A long-lived process of the default runtime is not needed after the config request.
+		hm.runtime.StopHook(hook)
+		hook.WithRuntime(runtime)
+	}
+
+	hm.initHook(hook)
+
+	return hook, nil
+}
+
+// initHook sets log and metric labels for bindings and creates a HookController.
+func (hm *hookManager) initHook(hook *Hook) {
 	// Add hook info as log labels, update MetricLabels
 	for _, kubeCfg := range hook.GetConfig().OnKubernetesEvents {
 		kubeCfg.Monitor.Metadata.LogLabels["hook"] = hook.Name
@@ -170,39 +449,36 @@ func (hm *hookManager) loadHook(hookPath string) (hook *Hook, err error) {
 		}
 		validatingCfg.Webhook.UpdateIds("", validatingCfg.BindingName)
 	}
+	for _, mutatingCfg := range hook.GetConfig().KubernetesMutating {
+		mutatingCfg.Webhook.Metadata.LogLabels["hook"] = hook.Name
+		mutatingCfg.Webhook.Metadata.MetricLabels = map[string]string{
+			"hook":    hook.Name,
+			"binding": mutatingCfg.BindingName,
+		}
+		mutatingCfg.Webhook.UpdateIds("", mutatingCfg.BindingName)
+	}
+	for _, conversionCfg := range hook.GetConfig().KubernetesConversion {
+		conversionCfg.Webhook.Metadata.LogLabels["hook"] = hook.Name
+		conversionCfg.Webhook.Metadata.MetricLabels = map[string]string{
+			"hook":    hook.Name,
+			"binding": conversionCfg.BindingName,
+		}
+		conversionCfg.Webhook.UpdateIds()
+	}
 
 	hookCtrl := controller.NewHookController()
 	hookCtrl.InitKubernetesBindings(hook.GetConfig().OnKubernetesEvents, hm.kubeEventsManager)
 	hookCtrl.InitScheduleBindings(hook.GetConfig().Schedules, hm.scheduleManager)
 	hookCtrl.InitValidatingBindings(hook.GetConfig().KubernetesValidating, hm.webhookManager)
+	hookCtrl.InitMutatingBindings(hook.GetConfig().KubernetesMutating, hm.webhookManager)
+	hookCtrl.InitConversionBindings(hook.GetConfig().KubernetesConversion, hm.webhookManager)
 
 	hook.WithHookController(hookCtrl)
 	hook.WithTmpDir(hm.TempDir())
 
-	hookEntry.Infof("Loaded config: %s", hook.GetConfigDescription())
-
-	return hook, nil
-}
-
-func (hm *hookManager) execCommandOutput(hookName string, dir string, entrypoint string, envs []string, args []string) ([]byte, error) {
-	envs = append(os.Environ(), envs...)
-	cmd := executor.MakeCommand(dir, entrypoint, args, envs)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-
-	debugEntry := log.WithField("hook", hookName).
-		WithField("cmd", strings.Join(cmd.Args, " "))
-
-	debugEntry.Debugf("Executing hook in %s", cmd.Dir)
-
-	output, err := executor.Output(cmd)
-	if err != nil {
-		return output, err
-	}
-
-	debugEntry.Debugf("output:\n%s", string(output))
-
-	return output, nil
+	log.WithField("hook", hook.Name).
+		WithField("phase", "config").
+		Infof("Loaded config: %s", hook.GetConfigDescription())
 }
 
 // HookManager has no events for now.
@@ -210,7 +486,20 @@ func (hm *hookManager) Run() {
 	panic("implement me")
 }
 
+// Stop terminates long-lived hook processes.
+func (hm *hookManager) Stop() {
+	hm.runtime.Stop()
+
+	hm.runtimesMu.Lock()
+	defer hm.runtimesMu.Unlock()
+	for _, runtime := range hm.runtimes {
+		runtime.Stop()
+	}
+}
+
 func (hm *hookManager) GetHook(name string) *Hook {
+	hm.m.RLock()
+	defer hm.m.RUnlock()
 	hook, exists := hm.hooksByName[name]
 	if exists {
 		return hook
@@ -221,11 +510,15 @@ func (hm *hookManager) GetHook(name string) *Hook {
 }
 
 func (hm *hookManager) GetHookNames() []string {
+	hm.m.RLock()
+	defer hm.m.RUnlock()
 	return hm.hookNamesInOrder
 }
 
 func (hm *hookManager) GetHooksInOrder(bindingType BindingType) ([]string, error) {
+	hm.m.RLock()
 	hooks, ok := hm.hooksInOrder[bindingType]
+	hm.m.RUnlock()
 	if !ok {
 		return []string{}, nil
 	}
@@ -239,6 +532,8 @@ func (hm *hookManager) GetHooksInOrder(bindingType BindingType) ([]string, error
 			}
 		}
 
+		// sort a copy, the index can be used concurrently
+		hooks = append([]*Hook{}, hooks...)
 		sort.Slice(hooks[:], func(i, j int) bool {
 			return hooks[i].Config.OnStartup.Order < hooks[j].Config.OnStartup.Order
 		})
@@ -295,3 +590,31 @@ func (hm *hookManager) HandleValidatingEvent(event ValidatingEvent, createTaskFn
 		}
 	}
 }
+
+func (hm *hookManager) HandleMutatingEvent(event MutatingEvent, createTaskFn func(*Hook, controller.BindingExecutionInfo)) {
+	mHooks, _ := hm.GetHooksInOrder(KubernetesMutating)
+	for _, hookName := range mHooks {
+		h := hm.GetHook(hookName)
+		if h.HookController.CanHandleMutatingEvent(event) {
+			h.HookController.HandleMutatingEvent(event, func(info controller.BindingExecutionInfo) {
+				if createTaskFn != nil {
+					createTaskFn(h, info)
+				}
+			})
+		}
+	}
+}
+
+func (hm *hookManager) HandleConversionEvent(event ConversionEvent, createTaskFn func(*Hook, controller.BindingExecutionInfo)) {
+	cHooks, _ := hm.GetHooksInOrder(KubernetesConversion)
+	for _, hookName := range cHooks {
+		h := hm.GetHook(hookName)
+		if h.HookController.CanHandleConversionEvent(event) {
+			h.HookController.HandleConversionEvent(event, func(info controller.BindingExecutionInfo) {
+				if createTaskFn != nil {
+					createTaskFn(h, info)
+				}
+			})
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package hook
 
 import (
+	"context"
 	"github.com/flant/shell-operator/pkg/hook/controller"
 	"io/ioutil"
 	"os"
@@ -10,6 +11,8 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
 	"github.com/flant/shell-operator/pkg/validating_webhook"
 	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
 )
@@ -106,3 +109,173 @@ func TestHookController_HandleValidatingEvent(t *testing.T) {
 	g.Expect(infoList).Should(HaveLen(1))
 
 }
+
+func Test_HookManager_Reload(t *testing.T) {
+	g := NewWithT(t)
+
+	hooksDir, err := ioutil.TempDir("", "hook_manager_reload")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(hooksDir)
+
+	writeHook := func(name string, config string, comment string) {
+		content := "#!/usr/bin/env bash\n# " + comment + "\nif [[ $1 == \"--config\" ]] ; then\n  echo '" + config + "'\nfi\n"
+		err := ioutil.WriteFile(filepath.Join(hooksDir, name), []byte(content), 0755)
+		g.Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	writeHook("changed.sh", `{"configVersion":"v1","schedule":[{"crontab":"* * * * *"}]}`, "")
+	writeHook("same-config.sh", `{"configVersion":"v1","onStartup":1}`, "")
+	writeHook("removed.sh", `{"configVersion":"v1","onStartup":2}`, "")
+	writeHook("unchanged.sh", `{"configVersion":"v1","onStartup":3}`, "")
+
+	hm, rmFn := newHookManager(t, hooksDir)
+	defer rmFn()
+	g.Expect(hm.Init()).ShouldNot(HaveOccurred())
+	g.Expect(hm.GetHookNames()).Should(HaveLen(4))
+
+	changed, err := hm.IsChanged()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(changed).Should(BeFalse())
+
+	unchanged := hm.GetHook("unchanged.sh")
+	sameConfig := hm.GetHook("same-config.sh")
+
+	writeHook("changed.sh", `{"configVersion":"v1","schedule":[{"crontab":"*/5 * * * *"}]}`, "")
+	writeHook("same-config.sh", `{"configVersion":"v1","onStartup":1}`, "new content")
+	g.Expect(os.Remove(filepath.Join(hooksDir, "removed.sh"))).ShouldNot(HaveOccurred())
+	writeHook("added.sh", `{"configVersion":"v1","onStartup":4}`, "")
+
+	changed, err = hm.IsChanged()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(changed).Should(BeTrue())
+
+	diff, err := hm.Reload()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(diff.Added).Should(HaveLen(1))
+	g.Expect(diff.Added[0].Name).Should(Equal("added.sh"))
+	g.Expect(diff.Removed).Should(HaveLen(1))
+	g.Expect(diff.Removed[0].Name).Should(Equal("removed.sh"))
+	g.Expect(diff.Updated).Should(HaveLen(1))
+	g.Expect(diff.Updated[0].New.Name).Should(Equal("changed.sh"))
+	g.Expect(diff.Updated[0].New.Config.Schedules[0].ScheduleEntry.Crontab).Should(Equal("*/5 * * * *"))
+
+	g.Expect(hm.GetHookNames()).Should(Equal([]string{"added.sh", "changed.sh", "same-config.sh", "unchanged.sh"}))
+	g.Expect(hm.GetHook("unchanged.sh")).Should(BeIdenticalTo(unchanged))
+	g.Expect(hm.GetHook("same-config.sh")).Should(BeIdenticalTo(sameConfig))
+
+	// Bad config should not change hooks.
+	writeHook("unchanged.sh", `{"configVersion":"v1","onStartup":"bad"}`, "")
+	_, err = hm.Reload()
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(hm.GetHook("unchanged.sh")).Should(BeIdenticalTo(unchanged))
+	g.Expect(hm.GetHookNames()).Should(HaveLen(4))
+}
+
+func Test_HookManager_HookRuntime(t *testing.T) {
+	g := NewWithT(t)
+
+	hooksDir, err := ioutil.TempDir("", "hook_manager_runtime")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(hooksDir)
+
+	execHook := "#!/usr/bin/env bash\nif [[ $1 == \"--config\" ]] ; then\n  echo '{\"configVersion\":\"v1\",\"onStartup\":1}'\nfi\n"
+	g.Expect(ioutil.WriteFile(filepath.Join(hooksDir, "exec.sh"), []byte(execHook), 0755)).Should(Succeed())
+	// A hook for the worker runtime returns its config with '--config' for the default runtime.
+	workerHook := "#!/usr/bin/env bash\nif [[ $1 == \"--config\" ]] ; then\n  echo '{\"configVersion\":\"v1\",\"onStartup\":2,\"runtime\":\"worker\"}'\n  exit 0\nfi\nwhile read -r request; do\n  echo '{\"metrics\":[{\"name\":\"runs\",\"add\":1}]}'\ndone\n"
+	g.Expect(ioutil.WriteFile(filepath.Join(hooksDir, "worker.sh"), []byte(workerHook), 0755)).Should(Succeed())
+
+	hm, rmFn := newHookManager(t, hooksDir)
+	defer rmFn()
+	defer hm.Stop()
+	g.Expect(hm.Init()).Should(Succeed())
+
+	g.Expect(hm.GetHook("exec.sh").Runtime.Name()).Should(Equal(ExecRuntime))
+	worker := hm.GetHook("worker.sh")
+	g.Expect(worker.Runtime.Name()).Should(Equal(WorkerRuntime))
+
+	result, err := worker.Run(context.Background(), OnStartup, []BindingContext{{Binding: "onStartup"}}, map[string]string{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(result.Metrics).Should(HaveLen(1))
+}
+
+func Test_HookManager_Reload_WorkerProcess(t *testing.T) {
+	g := NewWithT(t)
+
+	hooksDir, err := ioutil.TempDir("", "hook_manager_reload")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(hooksDir)
+
+	writeHook := func(name string, content string) {
+		err := ioutil.WriteFile(filepath.Join(hooksDir, name), []byte(content), 0755)
+		g.Expect(err).ShouldNot(HaveOccurred())
+	}
+	// A worker reports its pid in metrics.
+	workerHook := func(comment string) string {
+		return "#!/usr/bin/env bash\n# " + comment + "\nif [[ $1 == \"--config\" ]] ; then\n  echo '{\"configVersion\":\"v1\",\"onStartup\":1,\"runtime\":\"worker\"}'\n  exit 0\nfi\nwhile read -r request; do\n  echo '{\"metrics\":[{\"name\":\"runs\",\"add\":1,\"labels\":{\"pid\":\"'$$'\"}}]}'\ndone\n"
+	}
+	execHook := func(config string) string {
+		return "#!/usr/bin/env bash\nif [[ $1 == \"--config\" ]] ; then\n  echo '" + config + "'\nfi\n"
+	}
+
+	writeHook("worker.sh", workerHook(""))
+	writeHook("z-exec.sh", execHook(`{"configVersion":"v1","onStartup":2}`))
+
+	hm, rmFn := newHookManager(t, hooksDir)
+	defer rmFn()
+	defer hm.Stop()
+	g.Expect(hm.Init()).Should(Succeed())
+
+	workerPid := func() string {
+		result, err := hm.GetHook("worker.sh").Run(context.Background(), OnStartup, []BindingContext{{Binding: "onStartup"}}, map[string]string{})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(result.Metrics).Should(HaveLen(1))
+		return result.Metrics[0].Labels["pid"]
+	}
+	pid := workerPid()
+
+	// Failed reload keeps the process of the registered hook, z-exec.sh is loaded after worker.sh.
+	writeHook("worker.sh", workerHook("new content"))
+	writeHook("z-exec.sh", execHook(`{"configVersion":"v1","onStartup":"bad"}`))
+	_, err = hm.Reload()
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(workerPid()).Should(Equal(pid))
+
+	// The process is restarted to run the new file.
+	writeHook("z-exec.sh", execHook(`{"configVersion":"v1","onStartup":2}`))
+	_, err = hm.Reload()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(workerPid()).ShouldNot(Equal(pid))
+}
+
+func Test_HookManager_Reload_UnchangedKubernetesBindings(t *testing.T) {
+	g := NewWithT(t)
+
+	hooksDir, err := ioutil.TempDir("", "hook_manager_reload")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(hooksDir)
+
+	writeHook := func(config string) {
+		content := "#!/usr/bin/env bash\nif [[ $1 == \"--config\" ]] ; then\n  echo '" + config + "'\nfi\n"
+		err := ioutil.WriteFile(filepath.Join(hooksDir, "hook.sh"), []byte(content), 0755)
+		g.Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	writeHook(`{"configVersion":"v1","kubernetes":[{"name":"pods","apiVersion":"v1","kind":"Pod"},{"name":"cms","apiVersion":"v1","kind":"ConfigMap"}]}`)
+
+	hm, rmFn := newHookManager(t, hooksDir)
+	defer rmFn()
+	g.Expect(hm.Init()).ShouldNot(HaveOccurred())
+	old := hm.GetHook("hook.sh")
+
+	writeHook(`{"configVersion":"v1","kubernetes":[{"name":"pods","apiVersion":"v1","kind":"Pod"},{"name":"cms","apiVersion":"v1","kind":"ConfigMap","executeHookOnSynchronization":false}]}`)
+
+	diff, err := hm.Reload()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(diff.Updated).Should(HaveLen(1))
+	g.Expect(diff.Updated[0].UnchangedKubernetesBindings).Should(Equal([]string{"pods"}))
+
+	// Monitor of the unchanged binding is reused.
+	updated := hm.GetHook("hook.sh")
+	g.Expect(updated.Config.OnKubernetesEvents[0].Monitor).Should(BeIdenticalTo(old.Config.OnKubernetesEvents[0].Monitor))
+	g.Expect(updated.Config.OnKubernetesEvents[1].Monitor.Metadata.MonitorId).ShouldNot(Equal(old.Config.OnKubernetesEvents[1].Monitor.Metadata.MonitorId))
+}
@@ -0,0 +1,242 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/flant/shell-operator/pkg/app"
+	"github.com/flant/shell-operator/pkg/executor"
+)
+
+const (
+	ExecRuntime   = "exec"
+	WorkerRuntime = "worker"
+)
+
+// HookRuntime gets configuration from hooks and runs them.
+type HookRuntime interface {
+	// Name returns a name of the runtime for the 'runtime' field in the hook config.
+	Name() string
+	// Config returns hook configuration in YAML or JSON format. dir is a working directory of the hook manager.
+	// The request should be terminated after app.HookConfigTimeout.
+	Config(h *Hook, dir string) ([]byte, error)
+	// Run executes the hook with the binding context from files.BindingContextPath.
+	// Results should be written into output files. The hook should be terminated
+	// with executor.TimeoutError if timeout is not zero and is exceeded.
+	Run(h *Hook, files *HookFiles, timeout time.Duration, logLabels map[string]string) (*executor.CmdUsage, error)
+	// StopHook terminates a long-lived process of the hook, e.g. when hook file is changed.
+	StopHook(h *Hook)
+	// Stop terminates long-lived hook processes.
+	Stop()
+}
+
+func NewHookRuntime(name string) (HookRuntime, error) {
+	switch name {
+	case ExecRuntime, "":
+		return NewExecHookRuntime(), nil
+	case WorkerRuntime:
+		return NewWorkerHookRuntime(), nil
+	}
+	return nil, fmt.Errorf("unknown hook runtime '%s'", name)
+}
+
+// ExecHookRuntime runs a hook executable for each request.
+// Configuration is an output of 'hook --config', results are returned via files.
+type ExecHookRuntime struct{}
+
+var _ HookRuntime = &ExecHookRuntime{}
+
+func NewExecHookRuntime() *ExecHookRuntime {
+	return &ExecHookRuntime{}
+}
+
+func (r *ExecHookRuntime) Name() string {
+	return ExecRuntime
+}
+
+// Config runs 'hook --config' in the working directory of the hook manager.
+func (r *ExecHookRuntime) Config(h *Hook, dir string) ([]byte, error) {
+	cmd := executor.MakeCommand(dir, h.Path, []string{"--config"}, os.Environ())
+	return executor.OutputWithTimeout(cmd, app.HookConfigTimeout)
+}
+
+func (r *ExecHookRuntime) Run(h *Hook, files *HookFiles, timeout time.Duration, logLabels map[string]string) (*executor.CmdUsage, error) {
+	envs := append(os.Environ(), files.Envs()...)
+	cmd := executor.MakeCommand(path.Dir(h.Path), h.Path, []string{}, envs)
+	return executor.RunAndLogLinesWithTimeout(cmd, logLabels, timeout)
+}
+
+func (r *ExecHookRuntime) StopHook(h *Hook) {}
+
+func (r *ExecHookRuntime) Stop() {}
+
+// WorkerHookRuntime keeps a long-lived process for each hook and talks to it
+// over stdin and stdout with JSON lines. See WorkerRequest and WorkerResponse.
+type WorkerHookRuntime struct {
+	m sync.Mutex
+	// workers are stored by hook objects: a new Hook for a changed file
+	// gets its own process while the old one is still running.
+	workers map[*Hook]*executor.Worker
+}
+
+var _ HookRuntime = &WorkerHookRuntime{}
+
+func NewWorkerHookRuntime() *WorkerHookRuntime {
+	return &WorkerHookRuntime{
+		workers: make(map[*Hook]*executor.Worker),
+	}
+}
+
+// WorkerRequest is sent to the worker process. Type is "config" or "run".
+type WorkerRequest struct {
+	Type           string          `json:"type"`
+	BindingContext json.RawMessage `json:"bindingContext,omitempty"`
+	Traceparent    string          `json:"traceparent,omitempty"`
+}
+
+// WorkerResponse is read from the worker process. Fields have the same format
+// as contents of files for the exec runtime. Metrics and KubernetesPatch are arrays.
+type WorkerResponse struct {
+	Config             json.RawMessage   `json:"config,omitempty"`
+	Metrics            []json.RawMessage `json:"metrics,omitempty"`
+	ValidatingResponse json.RawMessage   `json:"validatingResponse,omitempty"`
+	MutatingResponse   json.RawMessage   `json:"mutatingResponse,omitempty"`
+	ConversionResponse json.RawMessage   `json:"conversionResponse,omitempty"`
+	KubernetesPatch    []json.RawMessage `json:"kubernetesPatch,omitempty"`
+	Error              string            `json:"error,omitempty"`
+}
+
+func (r *WorkerHookRuntime) worker(h *Hook) *executor.Worker {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	w, ok := r.workers[h]
+	if !ok {
+		envs := append(os.Environ(), fmt.Sprintf("SHELL_OPERATOR_HOOK_RUNTIME=%s", WorkerRuntime))
+		w = executor.NewWorker(path.Dir(h.Path), h.Path, []string{}, envs)
+		w.WithLogLabels(map[string]string{"hook": h.Name})
+		r.workers[h] = w
+	}
+	return w
+}
+
+func (r *WorkerHookRuntime) Name() string {
+	return WorkerRuntime
+}
+
+// Config returns the 'config' field of the response. It can be a JSON object or a string with YAML.
+// The worker process is started in the directory of the hook for all requests, so dir is not used.
+func (r *WorkerHookRuntime) Config(h *Hook, dir string) ([]byte, error) {
+	var resp WorkerResponse
+	err := r.worker(h).RequestWithTimeout(WorkerRequest{Type: "config"}, &resp, app.HookConfigTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("worker error: %s", resp.Error)
+	}
+
+	if isNullJson(resp.Config) {
+		return []byte{}, nil
+	}
+	var configText string
+	if json.Unmarshal(resp.Config, &configText) == nil {
+		return []byte(configText), nil
+	}
+	return resp.Config, nil
+}
+
+// Run sends the binding context to the worker and writes response fields into output files.
+// The worker process is terminated on timeout and is restarted on the next request.
+func (r *WorkerHookRuntime) Run(h *Hook, files *HookFiles, timeout time.Duration, logLabels map[string]string) (*executor.CmdUsage, error) {
+	bindingContext, err := ioutil.ReadFile(files.BindingContextPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp WorkerResponse
+	err = r.worker(h).RequestWithTimeout(WorkerRequest{Type: "run", BindingContext: bindingContext, Traceparent: files.Traceparent}, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("worker error: %s", resp.Error)
+	}
+
+	return nil, writeWorkerResponse(files, resp)
+}
+
+// writeWorkerResponse writes response fields into output files. Null fields and
+// null items of arrays are skipped, so files are empty as if the field is absent.
+func writeWorkerResponse(files *HookFiles, resp WorkerResponse) error {
+	err := writeJsonLines(files.MetricsPath, resp.Metrics)
+	if err != nil {
+		return err
+	}
+	err = writeJsonLines(files.KubernetesPatchPath, resp.KubernetesPatch)
+	if err != nil {
+		return err
+	}
+	err = writeJsonFile(files.ValidatingResponsePath, resp.ValidatingResponse)
+	if err != nil {
+		return err
+	}
+	err = writeJsonFile(files.MutatingResponsePath, resp.MutatingResponse)
+	if err != nil {
+		return err
+	}
+	return writeJsonFile(files.ConversionResponsePath, resp.ConversionResponse)
+}
+
+// StopHook stops the worker process of the hook. A new process is started on the next request.
+func (r *WorkerHookRuntime) StopHook(h *Hook) {
+	r.m.Lock()
+	w, ok := r.workers[h]
+	delete(r.workers, h)
+	r.m.Unlock()
+
+	if ok {
+		w.Stop()
+	}
+}
+
+// Stop stops all worker processes.
+func (r *WorkerHookRuntime) Stop() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for _, w := range r.workers {
+		w.Stop()
+	}
+}
+
+func writeJsonFile(filePath string, item json.RawMessage) error {
+	if isNullJson(item) {
+		item = nil
+	}
+	return ioutil.WriteFile(filePath, item, 0644)
+}
+
+func writeJsonLines(filePath string, items []json.RawMessage) error {
+	data := []byte{}
+	for _, item := range items {
+		if isNullJson(item) {
+			continue
+		}
+		data = append(data, item...)
+		data = append(data, '\n')
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+// isNullJson returns true for an empty or a null JSON value.
+func isNullJson(item json.RawMessage) bool {
+	item = bytes.TrimSpace(item)
+	return len(item) == 0 || bytes.Equal(item, []byte("null"))
+}
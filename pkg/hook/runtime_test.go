@@ -0,0 +1,131 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/app"
+	"github.com/flant/shell-operator/pkg/executor"
+	"github.com/flant/shell-operator/pkg/hook/controller"
+)
+
+func Test_WorkerHookRuntime(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "hook_runtime")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	hookPath, _ := filepath.Abs("testdata/hook_runtime/worker.sh")
+
+	runtime := NewWorkerHookRuntime()
+	defer runtime.Stop()
+
+	h := NewHook("worker.sh", hookPath)
+	h.WithRuntime(runtime)
+	h.WithTmpDir(tmpDir)
+	h.WithHookController(controller.NewHookController())
+
+	configOutput, err := runtime.Config(h, tmpDir)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	_, err = h.WithConfig(configOutput)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(h.Config.HasBinding(OnStartup)).Should(BeTrue())
+
+	bc := []BindingContext{{Binding: "onStartup"}}
+
+	result, err := h.Run(context.Background(), OnStartup, bc, map[string]string{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(result.Metrics).Should(HaveLen(1))
+	g.Expect(result.ValidatingResponse).ShouldNot(BeNil())
+	g.Expect(result.ValidatingResponse.Allowed).Should(BeTrue())
+	g.Expect(result.MutatingResponse).Should(BeNil())
+	g.Expect(result.KubernetesPatchOperations).Should(HaveLen(1))
+
+	// The same process should handle the next run.
+	pid := result.Metrics[0].Labels["pid"]
+	result, err = h.Run(context.Background(), OnStartup, bc, map[string]string{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(result.Metrics[0].Labels["pid"]).Should(Equal(pid))
+}
+
+func Test_WriteWorkerResponse_NullFields(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "hook_runtime")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	files := &HookFiles{
+		MetricsPath:            filepath.Join(tmpDir, "metrics"),
+		ValidatingResponsePath: filepath.Join(tmpDir, "validating"),
+		MutatingResponsePath:   filepath.Join(tmpDir, "mutating"),
+		ConversionResponsePath: filepath.Join(tmpDir, "conversion"),
+		KubernetesPatchPath:    filepath.Join(tmpDir, "kubernetes_patch"),
+	}
+
+	var resp WorkerResponse
+	err = json.Unmarshal([]byte(`{
+  "metrics": [null, {"name":"metric"}],
+  "validatingResponse": null,
+  "mutatingResponse": null,
+  "conversionResponse": null,
+  "kubernetesPatch": null
+}`), &resp)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(writeWorkerResponse(files, resp)).Should(Succeed())
+
+	expected := map[string]string{
+		files.MetricsPath:            "{\"name\":\"metric\"}\n",
+		files.ValidatingResponsePath: "",
+		files.MutatingResponsePath:   "",
+		files.ConversionResponsePath: "",
+		files.KubernetesPatchPath:    "",
+	}
+	for filePath, content := range expected {
+		data, err := ioutil.ReadFile(filePath)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(string(data)).Should(Equal(content), filePath)
+	}
+}
+
+func Test_ExecHookRuntime_Config(t *testing.T) {
+	g := NewWithT(t)
+
+	hooksDir, err := ioutil.TempDir("", "hook_runtime")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(hooksDir)
+
+	// The hook reads a file relative to the hooks directory.
+	g.Expect(os.Mkdir(filepath.Join(hooksDir, "sub"), 0755)).Should(Succeed())
+	hookPath := filepath.Join(hooksDir, "sub", "hook.sh")
+	content := "#!/usr/bin/env bash\nif [[ $1 == \"--config\" ]] ; then\n  cat sub/config.json\nfi\n"
+	g.Expect(ioutil.WriteFile(hookPath, []byte(content), 0755)).Should(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(hooksDir, "sub", "config.json"), []byte(`{"configVersion":"v1","onStartup":1}`), 0644)).Should(Succeed())
+
+	runtime := NewExecHookRuntime()
+	h := NewHook("sub/hook.sh", hookPath)
+
+	configOutput, err := runtime.Config(h, hooksDir)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(configOutput)).Should(ContainSubstring("onStartup"))
+
+	// The hook is terminated if it does not return a config in time.
+	defer func(d time.Duration) { app.HookConfigTimeout = d }(app.HookConfigTimeout)
+	app.HookConfigTimeout = 100 * time.Millisecond
+	content = "#!/usr/bin/env bash\nsleep 10\n"
+	g.Expect(ioutil.WriteFile(hookPath, []byte(content), 0755)).Should(Succeed())
+	_, err = runtime.Config(h, hooksDir)
+	g.Expect(executor.IsTimeoutError(err)).Should(BeTrue())
+}
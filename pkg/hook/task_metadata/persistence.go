@@ -0,0 +1,140 @@
+package task_metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+// HookTaskCodec serializes HookRun tasks for 'kubernetes' and 'schedule' bindings.
+// Other tasks are created on start and are not persisted.
+type HookTaskCodec struct{}
+
+func NewHookTaskCodec() *HookTaskCodec {
+	return &HookTaskCodec{}
+}
+
+type persistedTask struct {
+	Id             string
+	Type           task.TaskType
+	LogLabels      map[string]string
+	FailureCount   int
+	FailureMessage string
+	QueueName      string
+	QueuedAt       time.Time
+	Metadata       persistedHookMetadata
+}
+
+type persistedHookMetadata struct {
+	HookName       string
+	Binding        string
+	Group          string
+	BindingType    BindingType
+	BindingContext []persistedBindingContext
+	AllowFailure   bool
+}
+
+// BindingContext and ObjectAndFilterResult are marshaled in a format for hooks.
+// These types have no MarshalJSON methods to keep all fields.
+type plainBindingContext BindingContext
+type plainObject ObjectAndFilterResult
+
+type persistedBindingContext struct {
+	plainBindingContext
+	Objects []plainObject
+}
+
+func IsPersistentTask(t task.Task) bool {
+	if t.GetType() != HookRun {
+		return false
+	}
+	hookMeta, ok := t.GetMetadata().(HookMetadata)
+	if !ok {
+		return false
+	}
+	return hookMeta.BindingType == OnKubernetesEvent || hookMeta.BindingType == Schedule
+}
+
+func (c *HookTaskCodec) Encode(t task.Task) ([]byte, error) {
+	if !IsPersistentTask(t) {
+		return nil, nil
+	}
+	hookMeta := t.GetMetadata().(HookMetadata)
+
+	pt := persistedTask{
+		Id:           t.GetId(),
+		Type:         t.GetType(),
+		LogLabels:    t.GetLogLabels(),
+		FailureCount: t.GetFailureCount(),
+		QueueName:    t.GetQueueName(),
+		QueuedAt:     t.GetQueuedAt(),
+		Metadata: persistedHookMetadata{
+			HookName:     hookMeta.HookName,
+			Binding:      hookMeta.Binding,
+			Group:        hookMeta.Group,
+			BindingType:  hookMeta.BindingType,
+			AllowFailure: hookMeta.AllowFailure,
+		},
+	}
+	if baseTask, ok := t.(*task.BaseTask); ok {
+		pt.FailureMessage = baseTask.FailureMessage
+	}
+
+	for _, bc := range hookMeta.BindingContext {
+		pbc := persistedBindingContext{
+			plainBindingContext: plainBindingContext(bc),
+		}
+		// Snapshots are updated before execution.
+		pbc.Snapshots = nil
+		for _, obj := range bc.Objects {
+			pbc.Objects = append(pbc.Objects, plainObject(obj))
+		}
+		pt.Metadata.BindingContext = append(pt.Metadata.BindingContext, pbc)
+	}
+
+	return json.Marshal(pt)
+}
+
+func (c *HookTaskCodec) Decode(data []byte) (task.Task, error) {
+	var pt persistedTask
+	err := json.Unmarshal(data, &pt)
+	if err != nil {
+		return nil, err
+	}
+	if pt.Type != HookRun {
+		return nil, fmt.Errorf("unexpected task type '%s'", pt.Type)
+	}
+
+	hookMeta := HookMetadata{
+		HookName:     pt.Metadata.HookName,
+		Binding:      pt.Metadata.Binding,
+		Group:        pt.Metadata.Group,
+		BindingType:  pt.Metadata.BindingType,
+		AllowFailure: pt.Metadata.AllowFailure,
+	}
+	for _, pbc := range pt.Metadata.BindingContext {
+		bc := BindingContext(pbc.plainBindingContext)
+		bc.Objects = nil
+		for _, obj := range pbc.Objects {
+			bc.Objects = append(bc.Objects, ObjectAndFilterResult(obj))
+		}
+		hookMeta.BindingContext = append(hookMeta.BindingContext, bc)
+	}
+
+	t := task.NewTask(pt.Type).
+		WithLogLabels(pt.LogLabels).
+		WithQueueName(pt.QueueName).
+		WithMetadata(hookMeta)
+	t.Id = pt.Id
+	t.LogLabels["task.id"] = pt.Id
+	t.FailureCount = pt.FailureCount
+	t.FailureMessage = pt.FailureMessage
+	t.QueuedAt = pt.QueuedAt
+	return t, nil
+}
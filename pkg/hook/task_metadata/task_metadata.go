@@ -15,6 +15,8 @@ const (
 	HookRun                  task.TaskType = "HookRun"
 	EnableKubernetesBindings task.TaskType = "EnableKubernetesBindings"
 	EnableScheduleBindings   task.TaskType = "EnableScheduleBindings"
+	// a task to reload hooks from the hooks directory
+	ReloadHooks task.TaskType = "ReloadHooks"
 )
 
 type HookNameAccessor interface {
@@ -31,7 +33,8 @@ type HookMetadata struct {
 	Group          string
 	BindingType    BindingType
 	BindingContext []BindingContext
-	AllowFailure   bool //Task considered as 'ok' if hook failed. False by default. Can be true for some schedule hooks.
+	AllowFailure   bool         //Task considered as 'ok' if hook failed. False by default. Can be true for some schedule hooks.
+	QueuePolicy    *QueuePolicy // A policy for pending tasks of the binding, it is applied when task is queued.
 }
 
 var _ HookNameAccessor = HookMetadata{}
@@ -1,16 +1,20 @@
 package task_metadata
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	. "github.com/flant/shell-operator/pkg/hook/binding_context"
 	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
 
 	"github.com/flant/shell-operator/pkg/task"
 	"github.com/flant/shell-operator/pkg/task/dump"
 	"github.com/flant/shell-operator/pkg/task/queue"
+	"github.com/flant/shell-operator/pkg/task/store"
 )
 
 func Test_HookMetadata_Access(t *testing.T) {
@@ -80,3 +84,90 @@ func Test_HookMetadata_QueueDump_Task_Description(t *testing.T) {
 	g.Expect(queueDump).Should(ContainSubstring(":schedule:"), "Queue dump should show schedule binding.")
 	g.Expect(queueDump).Should(ContainSubstring("group=monitor_pods"), "Queue dump should show group name.")
 }
+
+func Test_HookTaskCodec_PersistAndRestore(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := ObjectAndFilterResult{
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": "pod-1", "namespace": "default"},
+		}},
+		FilterResult: `"pod-1"`,
+	}
+	obj.Metadata.ResourceId = "default/Pod/pod-1"
+	obj.Metadata.Checksum = "123"
+	obj.Metadata.JqFilter = ".metadata.name"
+
+	bc := BindingContext{
+		Binding:    "pods",
+		Type:       TypeEvent,
+		WatchEvent: WatchEventDeleted,
+		Objects:    []ObjectAndFilterResult{obj},
+		Snapshots:  map[string][]ObjectAndFilterResult{"pods": {obj}},
+	}
+	bc.Metadata.BindingType = OnKubernetesEvent
+	bc.Metadata.JqFilter = ".metadata.name"
+
+	kubeTask := task.NewTask(HookRun).
+		WithQueueName("pods-queue").
+		WithLogLabels(map[string]string{"hook": "hook1.sh"}).
+		WithMetadata(HookMetadata{
+			HookName:       "hook1.sh",
+			Binding:        "pods",
+			BindingType:    OnKubernetesEvent,
+			BindingContext: []BindingContext{bc},
+			AllowFailure:   true,
+		})
+	kubeTask.UpdateFailureMessage("exit code 1")
+	kubeTask.IncrementFailureCount()
+
+	taskStore := store.NewMemoryTaskStore()
+	tqs := queue.NewTaskQueueSet()
+	tqs.WithContext(context.Background())
+	tqs.WithPersistence(taskStore, NewHookTaskCodec())
+	tqs.NewNamedQueue("main", nil)
+	tqs.NewNamedQueue("pods-queue", nil)
+
+	// Tasks created on start are not persisted.
+	tqs.GetByName("main").AddLast(task.NewTask(EnableKubernetesBindings).
+		WithMetadata(HookMetadata{HookName: "hook1.sh"}))
+	tqs.GetByName("pods-queue").AddLast(kubeTask)
+
+	g.Expect(tqs.Persist()).Should(Succeed())
+	g.Expect(taskStore.Load()).ShouldNot(BeEmpty())
+
+	restored, err := tqs.Restore()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(restored).Should(HaveLen(1))
+	g.Expect(restored["pods-queue"]).Should(HaveLen(1))
+
+	rt := restored["pods-queue"][0]
+	g.Expect(rt.GetId()).Should(Equal(kubeTask.GetId()))
+	g.Expect(rt.GetQueueName()).Should(Equal("pods-queue"))
+	g.Expect(rt.GetFailureCount()).Should(Equal(1))
+	g.Expect(rt.GetLogLabels()).Should(HaveKeyWithValue("hook", "hook1.sh"))
+	g.Expect(rt.GetDescription()).Should(Equal(kubeTask.GetDescription()))
+
+	hm := HookMetadataAccessor(rt)
+	g.Expect(hm.HookName).Should(Equal("hook1.sh"))
+	g.Expect(hm.AllowFailure).Should(BeTrue())
+	g.Expect(hm.BindingContext).Should(HaveLen(1))
+	rbc := hm.BindingContext[0]
+	g.Expect(rbc.Binding).Should(Equal("pods"))
+	g.Expect(rbc.Type).Should(Equal(TypeEvent))
+	g.Expect(rbc.WatchEvent).Should(Equal(WatchEventDeleted))
+	g.Expect(rbc.Metadata.BindingType).Should(Equal(OnKubernetesEvent))
+	g.Expect(rbc.Metadata.JqFilter).Should(Equal(".metadata.name"))
+	g.Expect(rbc.Snapshots).Should(BeNil())
+	g.Expect(rbc.Objects).Should(HaveLen(1))
+	g.Expect(rbc.Objects[0].Metadata).Should(Equal(obj.Metadata))
+	g.Expect(rbc.Objects[0].FilterResult).Should(Equal(obj.FilterResult))
+	g.Expect(rbc.Objects[0].Object.GetName()).Should(Equal("pod-1"))
+
+	// Nothing is saved without changes.
+	g.Expect(taskStore.Save(nil)).Should(Succeed())
+	g.Expect(tqs.Persist()).Should(Succeed())
+	g.Expect(taskStore.Load()).Should(BeNil())
+}
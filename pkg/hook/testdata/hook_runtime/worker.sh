@@ -0,0 +1,10 @@
+#!/usr/bin/env bash
+
+# A worker that answers with the same responses and reports its pid in metrics.
+while read -r request; do
+  if [[ "$request" == *'"type":"config"'* ]]; then
+    echo '{"config":"configVersion: v1\nonStartup: 10\n"}'
+  else
+    echo '{"metrics":[{"name":"runs","add":1,"labels":{"pid":"'$$'"}}],"validatingResponse":{"allowed":true},"kubernetesPatch":[{"operation":"Delete","kind":"Pod","name":"pod-1"}]}'
+  fi
+done
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	. "github.com/flant/shell-operator/pkg/schedule_manager/types"
 
 	"github.com/flant/shell-operator/pkg/kube_events_manager"
@@ -14,8 +16,17 @@ const (
 	OnStartup            BindingType = "onStartup"
 	OnKubernetesEvent    BindingType = "kubernetes"
 	KubernetesValidating BindingType = "kubernetesValidating"
+	KubernetesMutating   BindingType = "kubernetesMutating"
+	KubernetesConversion BindingType = "kubernetesCustomResourceConversion"
 )
 
+// IsWebhookBinding returns true for bindings that handle requests from the API server.
+func IsWebhookBinding(bindingType BindingType) bool {
+	return bindingType == KubernetesValidating ||
+		bindingType == KubernetesMutating ||
+		bindingType == KubernetesConversion
+}
+
 // Types for effective binding configs
 type CommonBindingConfig struct {
 	BindingName  string
@@ -32,6 +43,9 @@ type ScheduleConfig struct {
 	ScheduleEntry        ScheduleEntry
 	IncludeSnapshotsFrom []string
 	Queue                string
+	QueuePolicy          *QueuePolicy
+	Timeout              time.Duration
+	Retry                *RetryPolicy
 	Group                string
 }
 
@@ -40,6 +54,9 @@ type OnKubernetesEventConfig struct {
 	Monitor                      *kube_events_manager.MonitorConfig
 	IncludeSnapshotsFrom         []string
 	Queue                        string
+	QueuePolicy                  *QueuePolicy
+	Timeout                      time.Duration
+	Retry                        *RetryPolicy
 	Group                        string
 	ExecuteHookOnSynchronization bool
 	WaitForSynchronization       bool
@@ -54,3 +71,64 @@ type ValidatingConfig struct {
 	IncludeSnapshotsFrom []string
 	Group                string
 }
+
+type MutatingConfig struct {
+	CommonBindingConfig
+
+	Webhook *validating_webhook.MutatingWebhookConfig
+
+	IncludeSnapshotsFrom []string
+	Group                string
+}
+
+type ConversionConfig struct {
+	CommonBindingConfig
+
+	Webhook *validating_webhook.ConversionWebhookConfig
+
+	IncludeSnapshotsFrom []string
+	Group                string
+}
+
+const (
+	WatchModeFull         = "full"
+	WatchModeMetadataOnly = "metadataOnly"
+)
+
+const (
+	DedupeByObject     = "byObject"
+	OverflowDropOldest = "dropOldest"
+	OverflowCompact    = "compact"
+	OrderByObject      = "object"
+	OrderByNamespace   = "namespace"
+)
+
+// QueuePolicy is applied to pending tasks of a binding when a new task is queued.
+type QueuePolicy struct {
+	// Dedupe is DedupeByObject to keep only the latest pending event for each object.
+	Dedupe string
+	// MaxPending is a limit for pending tasks of the binding, 0 means no limit.
+	MaxPending int
+	// OnOverflow is OverflowDropOldest or OverflowCompact.
+	OnOverflow string
+	// Concurrency is a number of tasks that a named queue can handle at once.
+	Concurrency int
+	// OrderBy is OrderByObject or OrderByNamespace: events with equal key are handled in order.
+	OrderBy string
+}
+
+const (
+	ExhaustedDrop       = "drop"
+	ExhaustedDeadLetter = "deadLetter"
+)
+
+// RetryPolicy limits retries of failed hook runs.
+type RetryPolicy struct {
+	// MaxAttempts is a number of hook runs before the task is given up on, 0 means no limit.
+	MaxAttempts int
+	// InitialDelay and MaxDelay are bounds for exponential backoff, 0 means queue defaults.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// OnExhausted is ExhaustedDrop or ExhaustedDeadLetter.
+	OnExhausted string
+}
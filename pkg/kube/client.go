@@ -17,6 +17,7 @@ import (
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
@@ -48,6 +49,7 @@ type KubernetesClient interface {
 
 	DefaultNamespace() string
 	Dynamic() dynamic.Interface
+	Metadata() metadata.Interface
 
 	APIResourceList(apiVersion string) ([]*metav1.APIResourceList, error)
 	APIResource(apiVersion string, kind string) (*metav1.APIResource, error)
@@ -62,10 +64,13 @@ func NewFakeKubernetesClient() KubernetesClient {
 	scheme := runtime.NewScheme()
 	objs := []runtime.Object{}
 
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(scheme, objs...)
 	return &kubernetesClient{
 		Interface:        fake.NewSimpleClientset(),
 		defaultNamespace: "default",
-		dynamicClient:    fakedynamic.NewSimpleDynamicClient(scheme, objs...),
+		dynamicClient:    dynamicClient,
+		// Metadata client reads objects from the fake dynamic client to keep one storage.
+		metadataClient: NewMetadataFromDynamic(dynamicClient),
 	}
 }
 
@@ -77,6 +82,7 @@ type kubernetesClient struct {
 	configPath       string
 	defaultNamespace string
 	dynamicClient    dynamic.Interface
+	metadataClient   metadata.Interface
 	qps              float32
 	burst            int
 	server           string
@@ -112,6 +118,10 @@ func (c *kubernetesClient) Dynamic() dynamic.Interface {
 	return c.dynamicClient
 }
 
+func (c *kubernetesClient) Metadata() metadata.Interface {
+	return c.metadataClient
+}
+
 func (c *kubernetesClient) Init() error {
 	logEntry := log.WithField("operator.component", "KubernetesAPIClient")
 
@@ -180,6 +190,11 @@ func (c *kubernetesClient) Init() error {
 		return err
 	}
 
+	c.metadataClient, err = metadata.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
 	if c.metricStorage != nil {
 		RegisterKubernetesClientMetrics(c.metricStorage)
 		metrics.Register(
@@ -0,0 +1,129 @@
+package event_recorder
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+const DefaultComponent = "shell-operator"
+
+// Reasons of published Events.
+const (
+	ReasonHookFailed           = "HookFailed"
+	ReasonHookRetriesExhausted = "HookRetriesExhausted"
+	ReasonAdmissionDenied      = "AdmissionDenied"
+)
+
+// EventRecorder publishes core/v1 Events about hook runs.
+// Events are rate limited and aggregated by the client-go EventBroadcaster.
+type EventRecorder struct {
+	KubeClient   kube.KubernetesClient
+	Component    string
+	PodNamespace string
+	PodName      string
+
+	podRef      *v1.ObjectReference
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+}
+
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{
+		Component: DefaultComponent,
+	}
+}
+
+func (r *EventRecorder) WithKubeClient(client kube.KubernetesClient) {
+	r.KubeClient = client
+}
+
+func (r *EventRecorder) WithComponent(component string) {
+	r.Component = component
+}
+
+// WithPod sets a Pod of the operator. Events without an involved object are attached to it.
+func (r *EventRecorder) WithPod(namespace string, name string) {
+	r.PodNamespace = namespace
+	r.PodName = name
+}
+
+// Init starts sending Events to the API server.
+func (r *EventRecorder) Init() error {
+	host, _ := os.Hostname()
+	if r.PodName == "" {
+		r.PodName = host
+	}
+	if r.PodNamespace == "" {
+		r.PodNamespace = r.KubeClient.DefaultNamespace()
+	}
+
+	r.podRef = &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  r.PodNamespace,
+		Name:       r.PodName,
+	}
+	// kubectl describe searches Events by uid, so try to get it.
+	pod, err := r.KubeClient.CoreV1().Pods(r.PodNamespace).Get(r.PodName, metav1.GetOptions{})
+	if err != nil {
+		log.Warnf("Get Pod '%s/%s' for Events: %v", r.PodNamespace, r.PodName, err)
+	} else {
+		r.podRef.UID = pod.UID
+	}
+
+	r.broadcaster = record.NewBroadcaster()
+	r.broadcaster.StartRecordingToSink(&eventSink{client: r.KubeClient})
+	r.recorder = r.broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{
+		Component: r.Component,
+		Host:      host,
+	})
+	return nil
+}
+
+// PodRef returns a reference to the operator Pod.
+func (r *EventRecorder) PodRef() *v1.ObjectReference {
+	return r.podRef
+}
+
+// Eventf publishes an Event for the involved object or for the operator Pod if ref is nil.
+func (r *EventRecorder) Eventf(ref *v1.ObjectReference, eventType string, reason string, messageFmt string, args ...interface{}) {
+	if r == nil || r.recorder == nil {
+		return
+	}
+	if ref == nil {
+		ref = r.podRef
+	}
+	r.recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+// Stop stops sending Events. Events in the internal queue may be lost.
+func (r *EventRecorder) Stop() {
+	if r == nil || r.broadcaster == nil {
+		return
+	}
+	r.broadcaster.Shutdown()
+}
+
+// eventSink writes Events into namespaces of involved objects.
+type eventSink struct {
+	client kube.KubernetesClient
+}
+
+func (s *eventSink) Create(event *v1.Event) (*v1.Event, error) {
+	return s.client.CoreV1().Events(event.Namespace).CreateWithEventNamespace(event)
+}
+
+func (s *eventSink) Update(event *v1.Event) (*v1.Event, error) {
+	return s.client.CoreV1().Events(event.Namespace).UpdateWithEventNamespace(event)
+}
+
+func (s *eventSink) Patch(event *v1.Event, data []byte) (*v1.Event, error) {
+	return s.client.CoreV1().Events(event.Namespace).PatchWithEventNamespace(event, data)
+}
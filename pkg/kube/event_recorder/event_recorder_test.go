@@ -0,0 +1,52 @@
+package event_recorder
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+func Test_EventRecorder_Eventf(t *testing.T) {
+	g := NewWithT(t)
+
+	client := kube.NewFakeKubernetesClient()
+	_, err := client.CoreV1().Pods("default").Create(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shell-operator-0", UID: "pod-uid"},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	r := NewEventRecorder()
+	r.WithKubeClient(client)
+	r.WithPod("default", "shell-operator-0")
+	g.Expect(r.Init()).Should(Succeed())
+	defer r.Stop()
+	g.Expect(r.PodRef().UID).Should(BeEquivalentTo("pod-uid"))
+
+	cm := &v1.ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "app", Name: "settings", UID: "cm-uid"}
+	r.Eventf(cm, v1.EventTypeWarning, ReasonHookFailed, "Hook '%s' failed", "hook.sh")
+	// No involved object, the Event is attached to the Pod.
+	r.Eventf(nil, v1.EventTypeWarning, ReasonHookFailed, "Hook '%s' failed", "schedule.sh")
+
+	events := func() []v1.Event {
+		list, err := client.CoreV1().Events("").List(metav1.ListOptions{})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		return list.Items
+	}
+	g.Eventually(events, 5*time.Second, 50*time.Millisecond).Should(HaveLen(2))
+
+	byObject := map[string]v1.Event{}
+	for _, ev := range events() {
+		byObject[ev.InvolvedObject.Kind+"/"+ev.InvolvedObject.Name] = ev
+	}
+	g.Expect(byObject).Should(HaveKey("ConfigMap/settings"))
+	g.Expect(byObject["ConfigMap/settings"].Namespace).Should(Equal("app"))
+	g.Expect(byObject["ConfigMap/settings"].Reason).Should(Equal(ReasonHookFailed))
+	g.Expect(byObject["ConfigMap/settings"].Source.Component).Should(Equal(DefaultComponent))
+	g.Expect(byObject).Should(HaveKey("Pod/shell-operator-0"))
+	g.Expect(byObject["Pod/shell-operator-0"].Message).Should(Equal("Hook 'schedule.sh' failed"))
+}
@@ -0,0 +1,105 @@
+package kube
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+)
+
+// NewMetadataFromDynamic returns a metadata client that reads full objects with
+// the dynamic client and strips them to PartialObjectMetadata. It is used with
+// the fake dynamic client, real clients should use metadata.NewForConfig.
+func NewMetadataFromDynamic(client dynamic.Interface) metadata.Interface {
+	return &dynamicMetadataClient{client: client}
+}
+
+type dynamicMetadataClient struct {
+	client dynamic.Interface
+}
+
+func (c *dynamicMetadataClient) Resource(resource schema.GroupVersionResource) metadata.Getter {
+	return &dynamicMetadataResourceClient{client: c.client.Resource(resource)}
+}
+
+type dynamicMetadataResourceClient struct {
+	client    dynamic.NamespaceableResourceInterface
+	namespace string
+}
+
+func (c *dynamicMetadataResourceClient) Namespace(ns string) metadata.ResourceInterface {
+	ret := *c
+	ret.namespace = ns
+	return &ret
+}
+
+func (c *dynamicMetadataResourceClient) resource() dynamic.ResourceInterface {
+	if c.namespace == "" {
+		return c.client
+	}
+	return c.client.Namespace(c.namespace)
+}
+
+func (c *dynamicMetadataResourceClient) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	return c.resource().Delete(name, options, subresources...)
+}
+
+func (c *dynamicMetadataResourceClient) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return c.resource().DeleteCollection(options, listOptions)
+}
+
+func (c *dynamicMetadataResourceClient) Get(name string, options metav1.GetOptions, subresources ...string) (*metav1.PartialObjectMetadata, error) {
+	obj, err := c.resource().Get(name, options, subresources...)
+	if err != nil {
+		return nil, err
+	}
+	return PartialObjectMetadata(obj), nil
+}
+
+func (c *dynamicMetadataResourceClient) List(opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	list, err := c.resource().List(opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &metav1.PartialObjectMetadataList{
+		Items: make([]metav1.PartialObjectMetadata, 0, len(list.Items)),
+	}
+	res.SetResourceVersion(list.GetResourceVersion())
+	res.SetContinue(list.GetContinue())
+	for i := range list.Items {
+		res.Items = append(res.Items, *PartialObjectMetadata(&list.Items[i]))
+	}
+	return res, nil
+}
+
+func (c *dynamicMetadataResourceClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	w, err := c.resource().Watch(opts)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(ev watch.Event) (watch.Event, bool) {
+		if obj, ok := ev.Object.(*unstructured.Unstructured); ok {
+			ev.Object = PartialObjectMetadata(obj)
+		}
+		return ev, true
+	}), nil
+}
+
+func (c *dynamicMetadataResourceClient) Patch(name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*metav1.PartialObjectMetadata, error) {
+	obj, err := c.resource().Patch(name, pt, data, options, subresources...)
+	if err != nil {
+		return nil, err
+	}
+	return PartialObjectMetadata(obj), nil
+}
+
+// PartialObjectMetadata returns metadata of the object.
+func PartialObjectMetadata(obj *unstructured.Unstructured) *metav1.PartialObjectMetadata {
+	res := &metav1.PartialObjectMetadata{}
+	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), res)
+	return res
+}
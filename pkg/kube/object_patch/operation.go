@@ -0,0 +1,165 @@
+package object_patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type OperationType string
+
+const (
+	Create             OperationType = "Create"
+	CreateOrUpdate     OperationType = "CreateOrUpdate"
+	Delete             OperationType = "Delete"
+	DeleteInBackground OperationType = "DeleteInBackground"
+	DeleteNonCascading OperationType = "DeleteNonCascading"
+	MergePatch         OperationType = "MergePatch"
+	JSONPatch          OperationType = "JSONPatch"
+	JQPatch            OperationType = "JQPatch"
+)
+
+// OperationSpec is an operation from the $KUBERNETES_PATCH_PATH file.
+//
+// Create operations use apiVersion, kind, namespace and name from the 'object' field.
+// Other operations require explicit 'kind' and 'name' fields.
+type OperationSpec struct {
+	Operation   OperationType `json:"operation"`
+	ApiVersion  string        `json:"apiVersion,omitempty"`
+	Kind        string        `json:"kind,omitempty"`
+	Namespace   string        `json:"namespace,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Subresource string        `json:"subresource,omitempty"`
+
+	Object     map[string]interface{} `json:"object,omitempty"`
+	MergePatch map[string]interface{} `json:"mergePatch,omitempty"`
+	JSONPatch  []interface{}          `json:"jsonPatch,omitempty"`
+	JQFilter   string                 `json:"jqFilter,omitempty"`
+
+	// IgnoreMissingObject prevents an error if the object to patch is not found.
+	IgnoreMissingObject bool `json:"ignoreMissingObject,omitempty"`
+}
+
+func (o OperationSpec) String() string {
+	parts := []string{"operation=" + string(o.Operation)}
+
+	if o.ApiVersion != "" {
+		parts = append(parts, "apiVersion="+o.ApiVersion)
+	}
+	if o.Kind != "" {
+		parts = append(parts, "kind="+o.Kind)
+	}
+	if o.Namespace != "" {
+		parts = append(parts, "namespace="+o.Namespace)
+	}
+	if o.Name != "" {
+		parts = append(parts, "name="+o.Name)
+	}
+	if o.Subresource != "" {
+		parts = append(parts, "subresource="+o.Subresource)
+	}
+
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func OperationsFromReader(r io.Reader) ([]OperationSpec, error) {
+	var operations = make([]OperationSpec, 0)
+
+	dec := json.NewDecoder(r)
+	for {
+		var spec OperationSpec
+		if err := dec.Decode(&spec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		operations = append(operations, spec)
+	}
+
+	return operations, nil
+}
+
+func OperationsFromBytes(data []byte) ([]OperationSpec, error) {
+	return OperationsFromReader(bytes.NewReader(data))
+}
+
+func OperationsFromFile(filePath string) ([]OperationSpec, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", filePath, err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return OperationsFromBytes(data)
+}
+
+func ValidateOperations(ops []OperationSpec) error {
+	var opsErrs *multierror.Error
+
+	for _, op := range ops {
+		err := ValidateOperationSpec(op)
+		if err != nil {
+			opsErrs = multierror.Append(opsErrs, err)
+		}
+	}
+
+	return opsErrs.ErrorOrNil()
+}
+
+func ValidateOperationSpec(op OperationSpec) error {
+	var opErrs *multierror.Error
+
+	switch op.Operation {
+	case Create, CreateOrUpdate:
+		if len(op.Object) == 0 {
+			opErrs = multierror.Append(opErrs, fmt.Errorf("'object' is required for '%s': %s", op.Operation, op))
+			break
+		}
+		obj := &unstructured.Unstructured{Object: op.Object}
+		if obj.GetAPIVersion() == "" {
+			opErrs = multierror.Append(opErrs, fmt.Errorf("'object.apiVersion' is required for '%s': %s", op.Operation, op))
+		}
+		if obj.GetKind() == "" {
+			opErrs = multierror.Append(opErrs, fmt.Errorf("'object.kind' is required for '%s': %s", op.Operation, op))
+		}
+		if obj.GetName() == "" {
+			opErrs = multierror.Append(opErrs, fmt.Errorf("'object.metadata.name' is required for '%s': %s", op.Operation, op))
+		}
+	case Delete, DeleteInBackground, DeleteNonCascading, MergePatch, JSONPatch, JQPatch:
+		if op.Kind == "" {
+			opErrs = multierror.Append(opErrs, fmt.Errorf("'kind' is required for '%s': %s", op.Operation, op))
+		}
+		if op.Name == "" {
+			opErrs = multierror.Append(opErrs, fmt.Errorf("'name' is required for '%s': %s", op.Operation, op))
+		}
+	case "":
+		opErrs = multierror.Append(opErrs, fmt.Errorf("'operation' is required: %s", op))
+	default:
+		opErrs = multierror.Append(opErrs, fmt.Errorf("unsupported operation '%s': %s", op.Operation, op))
+	}
+
+	if op.Operation == MergePatch && len(op.MergePatch) == 0 {
+		opErrs = multierror.Append(opErrs, fmt.Errorf("'mergePatch' is required for '%s': %s", op.Operation, op))
+	}
+	if op.Operation == JSONPatch && len(op.JSONPatch) == 0 {
+		opErrs = multierror.Append(opErrs, fmt.Errorf("'jsonPatch' is required for '%s': %s", op.Operation, op))
+	}
+	if op.Operation == JQPatch && op.JQFilter == "" {
+		opErrs = multierror.Append(opErrs, fmt.Errorf("'jqFilter' is required for '%s': %s", op.Operation, op))
+	}
+
+	if op.Subresource != "" && op.Operation != MergePatch && op.Operation != JSONPatch && op.Operation != JQPatch {
+		opErrs = multierror.Append(opErrs, fmt.Errorf("'subresource' is supported only for patch operations: %s", op))
+	}
+
+	return opErrs.ErrorOrNil()
+}
@@ -0,0 +1,111 @@
+package object_patch
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_OperationsFromBytes(t *testing.T) {
+	g := NewWithT(t)
+
+	input := `
+{"operation":"Create", "object":{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"default"}}}
+{"operation":"MergePatch", "kind":"ConfigMap", "namespace":"default", "name":"cm", "mergePatch":{"data":{"foo":"bar"}}}
+{"operation":"Delete", "kind":"Pod", "namespace":"default", "name":"pod-1"}
+`
+
+	ops, err := OperationsFromBytes([]byte(input))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(ops).Should(HaveLen(3))
+	g.Expect(ops[0].Operation).To(Equal(Create))
+	g.Expect(ops[1].MergePatch).Should(HaveKey("data"))
+	g.Expect(ops[2].Name).To(Equal("pod-1"))
+
+	g.Expect(ValidateOperations(ops)).ShouldNot(HaveOccurred())
+}
+
+func Test_ValidateOperationSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    OperationSpec
+		isValid bool
+	}{
+		{
+			"create without object",
+			OperationSpec{Operation: Create},
+			false,
+		},
+		{
+			"create without name",
+			OperationSpec{Operation: Create, Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"namespace": "default"},
+			}},
+			false,
+		},
+		{
+			"create or update without kind",
+			OperationSpec{Operation: CreateOrUpdate, Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"metadata":   map[string]interface{}{"name": "cm"},
+			}},
+			false,
+		},
+		{
+			"create without apiVersion",
+			OperationSpec{Operation: Create, Object: map[string]interface{}{
+				"kind":     "ConfigMap",
+				"metadata": map[string]interface{}{"name": "cm"},
+			}},
+			false,
+		},
+		{
+			"create",
+			OperationSpec{Operation: Create, Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "cm"},
+			}},
+			true,
+		},
+		{
+			"delete without name",
+			OperationSpec{Operation: Delete, Kind: "Pod"},
+			false,
+		},
+		{
+			"merge patch without patch",
+			OperationSpec{Operation: MergePatch, Kind: "Pod", Name: "pod-1"},
+			false,
+		},
+		{
+			"jq patch for status",
+			OperationSpec{Operation: JQPatch, Kind: "Pod", Name: "pod-1", Subresource: "status", JQFilter: ".status.phase = \"Running\""},
+			true,
+		},
+		{
+			"subresource for delete",
+			OperationSpec{Operation: Delete, Kind: "Pod", Name: "pod-1", Subresource: "status"},
+			false,
+		},
+		{
+			"unknown operation",
+			OperationSpec{Operation: "Replace", Kind: "Pod", Name: "pod-1"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := ValidateOperationSpec(tt.spec)
+			if tt.isValid {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(err).Should(HaveOccurred())
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package object_patch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/flant/shell-operator/pkg/app"
+	"github.com/flant/shell-operator/pkg/jq"
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+// ObjectPatcher applies operations from hooks to Kubernetes objects
+// using a dynamic client.
+type ObjectPatcher struct {
+	kubeClient kube.KubernetesClient
+}
+
+func NewObjectPatcher(kubeClient kube.KubernetesClient) *ObjectPatcher {
+	return &ObjectPatcher{
+		kubeClient: kubeClient,
+	}
+}
+
+// ExecuteOperation applies one operation. Specs should be validated with ValidateOperationSpec.
+func (o *ObjectPatcher) ExecuteOperation(spec OperationSpec) error {
+	return o.executeOperation(spec, false)
+}
+
+// ExecuteRetriedOperation applies one operation for a retried hook run. Create
+// is successful for an existing object, because the object may be created by
+// the previous run of the hook.
+func (o *ObjectPatcher) ExecuteRetriedOperation(spec OperationSpec) error {
+	return o.executeOperation(spec, true)
+}
+
+func (o *ObjectPatcher) executeOperation(spec OperationSpec, retried bool) error {
+	log.Debugf("Execute kubernetes patch operation %s", spec)
+
+	switch spec.Operation {
+	case Create:
+		err := o.create(spec)
+		if retried && errors.IsAlreadyExists(err) {
+			log.Debugf("Object for %s is created by the previous run", spec)
+			return nil
+		}
+		return err
+	case CreateOrUpdate:
+		return o.createOrUpdate(spec)
+	case Delete:
+		return o.delete(spec, metav1.DeletePropagationForeground)
+	case DeleteInBackground:
+		return o.delete(spec, metav1.DeletePropagationBackground)
+	case DeleteNonCascading:
+		return o.delete(spec, metav1.DeletePropagationOrphan)
+	case MergePatch:
+		return o.patch(spec, types.MergePatchType, spec.MergePatch)
+	case JSONPatch:
+		return o.patch(spec, types.JSONPatchType, spec.JSONPatch)
+	case JQPatch:
+		return o.jqPatch(spec)
+	}
+
+	return fmt.Errorf("unsupported operation '%s'", spec.Operation)
+}
+
+func (o *ObjectPatcher) resourceFor(apiVersion, kind, namespace string) (dynamic.ResourceInterface, error) {
+	gvr, err := o.kubeClient.GroupVersionResource(apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+	return o.kubeClient.Dynamic().Resource(gvr).Namespace(namespace), nil
+}
+
+func (o *ObjectPatcher) create(spec OperationSpec) error {
+	obj := &unstructured.Unstructured{Object: spec.Object}
+
+	resource, err := o.resourceFor(obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	_, err = resource.Create(obj, metav1.CreateOptions{})
+	return err
+}
+
+func (o *ObjectPatcher) createOrUpdate(spec OperationSpec) error {
+	obj := &unstructured.Unstructured{Object: spec.Object}
+
+	resource, err := o.resourceFor(obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	existing, err := resource.Get(obj.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = resource.Create(obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	_, err = resource.Update(obj, metav1.UpdateOptions{})
+	return err
+}
+
+func (o *ObjectPatcher) delete(spec OperationSpec, propagation metav1.DeletionPropagation) error {
+	resource, err := o.resourceFor(spec.ApiVersion, spec.Kind, spec.Namespace)
+	if err != nil {
+		return err
+	}
+
+	err = resource.Delete(spec.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (o *ObjectPatcher) patch(spec OperationSpec, patchType types.PatchType, patch interface{}) error {
+	resource, err := o.resourceFor(spec.ApiVersion, spec.Kind, spec.Namespace)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = resource.Patch(spec.Name, patchType, data, metav1.PatchOptions{}, subresources(spec)...)
+	if errors.IsNotFound(err) && spec.IgnoreMissingObject {
+		return nil
+	}
+	return err
+}
+
+// jqPatch gets the object, applies jqFilter to it and updates the object with the result.
+func (o *ObjectPatcher) jqPatch(spec OperationSpec) error {
+	resource, err := o.resourceFor(spec.ApiVersion, spec.Kind, spec.Namespace)
+	if err != nil {
+		return err
+	}
+
+	obj, err := resource.Get(spec.Name, metav1.GetOptions{}, subresources(spec)...)
+	if errors.IsNotFound(err) && spec.IgnoreMissingObject {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	filtered, err := jq.ApplyJqFilter(spec.JQFilter, data, app.JqLibraryPath)
+	if err != nil {
+		return fmt.Errorf("apply jqFilter: %v", err)
+	}
+
+	patched := &unstructured.Unstructured{}
+	err = patched.UnmarshalJSON([]byte(filtered))
+	if err != nil {
+		return fmt.Errorf("jqFilter result is not an object: %v", err)
+	}
+
+	_, err = resource.Update(patched, metav1.UpdateOptions{}, subresources(spec)...)
+	return err
+}
+
+func subresources(spec OperationSpec) []string {
+	if spec.Subresource == "" {
+		return nil
+	}
+	return []string{spec.Subresource}
+}
@@ -0,0 +1,119 @@
+package object_patch
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/shell-operator/pkg/kube/fake"
+)
+
+func Test_ObjectPatcher_ExecuteOperation(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := fake.NewFakeCluster()
+	patcher := NewObjectPatcher(cluster.KubeClient)
+	gvr := cluster.MustFindGVR("v1", "ConfigMap")
+
+	getConfigMapData := func() map[string]interface{} {
+		obj, err := cluster.KubeClient.Dynamic().Resource(*gvr).Namespace("default").Get("cm", metav1.GetOptions{})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		data, _ := obj.Object["data"].(map[string]interface{})
+		return data
+	}
+
+	ops, err := OperationsFromBytes([]byte(`
+{"operation":"Create", "object":{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"default"},"data":{"foo":"bar"}}}
+`))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(patcher.ExecuteOperation(ops[0])).ShouldNot(HaveOccurred())
+	g.Expect(getConfigMapData()).Should(HaveKeyWithValue("foo", "bar"))
+
+	// Create should fail for existing object.
+	g.Expect(patcher.ExecuteOperation(ops[0])).Should(HaveOccurred())
+	// Object may be created by the previous run of a retried hook.
+	g.Expect(patcher.ExecuteRetriedOperation(ops[0])).ShouldNot(HaveOccurred())
+	g.Expect(getConfigMapData()).Should(HaveKeyWithValue("foo", "bar"))
+
+	err = patcher.ExecuteOperation(OperationSpec{
+		Operation: CreateOrUpdate,
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "cm", "namespace": "default"},
+			"data":       map[string]interface{}{"foo": "baz"},
+		},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(getConfigMapData()).Should(HaveKeyWithValue("foo", "baz"))
+
+	err = patcher.ExecuteOperation(OperationSpec{
+		Operation:  MergePatch,
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  "default",
+		Name:       "cm",
+		MergePatch: map[string]interface{}{"data": map[string]interface{}{"merge": "patch"}},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(getConfigMapData()).Should(HaveKeyWithValue("merge", "patch"))
+
+	err = patcher.ExecuteOperation(OperationSpec{
+		Operation:  JSONPatch,
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  "default",
+		Name:       "cm",
+		JSONPatch: []interface{}{
+			map[string]interface{}{"op": "replace", "path": "/data/foo", "value": "json"},
+		},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(getConfigMapData()).Should(HaveKeyWithValue("foo", "json"))
+
+	err = patcher.ExecuteOperation(OperationSpec{
+		Operation:  JQPatch,
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  "default",
+		Name:       "cm",
+		JQFilter:   `.data.jq = "patch"`,
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(getConfigMapData()).Should(HaveKeyWithValue("jq", "patch"))
+
+	// Patch for missing object.
+	missing := OperationSpec{
+		Operation:  MergePatch,
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  "default",
+		Name:       "missing",
+		MergePatch: map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}},
+	}
+	g.Expect(patcher.ExecuteOperation(missing)).Should(HaveOccurred())
+	missing.IgnoreMissingObject = true
+	g.Expect(patcher.ExecuteOperation(missing)).ShouldNot(HaveOccurred())
+
+	err = patcher.ExecuteOperation(OperationSpec{
+		Operation:  Delete,
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  "default",
+		Name:       "cm",
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	_, err = cluster.KubeClient.Dynamic().Resource(*gvr).Namespace("default").Get("cm", metav1.GetOptions{})
+	g.Expect(err).Should(HaveOccurred())
+
+	// Delete is idempotent.
+	err = patcher.ExecuteOperation(OperationSpec{
+		Operation:  Delete,
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  "default",
+		Name:       "cm",
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+}
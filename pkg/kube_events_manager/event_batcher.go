@@ -0,0 +1,193 @@
+package kube_events_manager
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+// eventBatcher buffers KubeEvents of a monitor and emits one KubeEvent with all changed
+// objects when there are no new events for the debounce period or when maxWait is passed
+// since the first buffered event. Events for the same object are merged:
+// Added+Modified is Added, Modified+Deleted is Deleted, Deleted+Added is Modified
+// and an object that is Added and Deleted in one batch is dropped.
+type eventBatcher struct {
+	debounce time.Duration
+	maxWait  time.Duration
+	emit     func(ev KubeEvent)
+
+	mu          sync.Mutex
+	monitorId   string
+	ids         []string
+	events      map[string]batchedObject
+	first       time.Time
+	spanContext trace.SpanContext
+	timer       *time.Timer
+	stopped     bool
+
+	// emitMu keeps the order of batches if a new batch is ready while the previous one is emitting.
+	emitMu sync.Mutex
+}
+
+type batchedObject struct {
+	watchEvent WatchEventType
+	object     ObjectAndFilterResult
+}
+
+func newEventBatcher(debounce time.Duration, maxWait time.Duration, emit func(ev KubeEvent)) *eventBatcher {
+	return &eventBatcher{
+		debounce: debounce,
+		maxWait:  maxWait,
+		emit:     emit,
+		events:   make(map[string]batchedObject),
+	}
+}
+
+// Add puts objects from the event into the batch and postpones the emit.
+func (b *eventBatcher) Add(ev KubeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return
+	}
+
+	now := time.Now()
+	if b.first.IsZero() {
+		b.first = now
+		b.monitorId = ev.MonitorId
+		b.spanContext = ev.SpanContext
+	}
+
+	for i, obj := range ev.Objects {
+		if len(ev.WatchEvents) == 0 {
+			break
+		}
+		watchEvent := ev.WatchEvents[len(ev.WatchEvents)-1]
+		if i < len(ev.WatchEvents) {
+			watchEvent = ev.WatchEvents[i]
+		}
+		b.add(watchEvent, obj)
+	}
+
+	delay := b.debounce
+	if b.maxWait > 0 {
+		if rest := b.first.Add(b.maxWait).Sub(now); rest < delay {
+			delay = rest
+		}
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(delay, b.flush)
+	} else {
+		b.timer.Reset(delay)
+	}
+}
+
+func (b *eventBatcher) add(watchEvent WatchEventType, obj ObjectAndFilterResult) {
+	id := obj.Metadata.ResourceId
+	prev, has := b.events[id]
+	if !has {
+		b.ids = append(b.ids, id)
+		b.events[id] = batchedObject{watchEvent: watchEvent, object: obj}
+		return
+	}
+
+	merged, keep := MergeWatchEvents(prev.watchEvent, watchEvent)
+	if !keep {
+		delete(b.events, id)
+		for i := range b.ids {
+			if b.ids[i] == id {
+				b.ids = append(b.ids[:i], b.ids[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	b.events[id] = batchedObject{watchEvent: merged, object: obj}
+}
+
+// MergeWatchEvents returns an event for two consecutive events of the same object.
+// It returns false if the object was created and deleted.
+func MergeWatchEvents(prev WatchEventType, next WatchEventType) (WatchEventType, bool) {
+	switch {
+	case next == WatchEventDeleted && prev == WatchEventAdded:
+		return "", false
+	case next == WatchEventDeleted:
+		return WatchEventDeleted, true
+	case prev == WatchEventAdded:
+		return WatchEventAdded, true
+	default:
+		return WatchEventModified, true
+	}
+}
+
+// flush emits buffered objects as one KubeEvent.
+func (b *eventBatcher) flush() {
+	b.emitMu.Lock()
+	defer b.emitMu.Unlock()
+
+	b.mu.Lock()
+	ev, ok := b.takeBatch()
+	b.mu.Unlock()
+
+	if ok {
+		b.emit(ev)
+	}
+}
+
+// Pause emits buffered objects without waiting for the timer and ignores
+// new events. It is called on shutdown, so buffered events are queued
+// before the queues are saved.
+func (b *eventBatcher) Pause() {
+	b.emitMu.Lock()
+	defer b.emitMu.Unlock()
+
+	b.mu.Lock()
+	ev, ok := b.takeBatch()
+	b.stopped = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.mu.Unlock()
+
+	if ok {
+		b.emit(ev)
+	}
+}
+
+// takeBatch returns buffered objects as one KubeEvent and resets the buffer.
+// It returns false if there are no objects. b.mu should be locked.
+func (b *eventBatcher) takeBatch() (KubeEvent, bool) {
+	if b.stopped || len(b.ids) == 0 {
+		b.first = time.Time{}
+		return KubeEvent{}, false
+	}
+	ev := KubeEvent{
+		MonitorId:   b.monitorId,
+		WatchEvents: make([]WatchEventType, 0, len(b.ids)),
+		Objects:     make([]ObjectAndFilterResult, 0, len(b.ids)),
+		SpanContext: b.spanContext,
+	}
+	for _, id := range b.ids {
+		ev.WatchEvents = append(ev.WatchEvents, b.events[id].watchEvent)
+		ev.Objects = append(ev.Objects, b.events[id].object)
+	}
+	b.ids = nil
+	b.events = make(map[string]batchedObject)
+	b.first = time.Time{}
+	return ev, true
+}
+
+// Stop drops buffered events.
+func (b *eventBatcher) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.ids = nil
+	b.events = make(map[string]batchedObject)
+}
@@ -0,0 +1,186 @@
+package kube_events_manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/shell-operator/pkg/kube/fake"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+func Test_MergeWatchEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		prev     WatchEventType
+		next     WatchEventType
+		expected WatchEventType
+		keep     bool
+	}{
+		{WatchEventAdded, WatchEventModified, WatchEventAdded, true},
+		{WatchEventAdded, WatchEventDeleted, "", false},
+		{WatchEventModified, WatchEventModified, WatchEventModified, true},
+		{WatchEventModified, WatchEventDeleted, WatchEventDeleted, true},
+		{WatchEventDeleted, WatchEventAdded, WatchEventModified, true},
+	}
+
+	for _, tt := range tests {
+		merged, keep := MergeWatchEvents(tt.prev, tt.next)
+		g.Expect(keep).Should(Equal(tt.keep), "%s+%s", tt.prev, tt.next)
+		g.Expect(merged).Should(Equal(tt.expected), "%s+%s", tt.prev, tt.next)
+	}
+}
+
+func podEvent(watchEvent WatchEventType, name string, filterResult string) KubeEvent {
+	obj := ObjectAndFilterResult{FilterResult: filterResult}
+	obj.Metadata.ResourceId = "default/Pod/" + name
+	return KubeEvent{
+		MonitorId:   "pods",
+		WatchEvents: []WatchEventType{watchEvent},
+		Objects:     []ObjectAndFilterResult{obj},
+	}
+}
+
+func Test_EventBatcher_Debounce(t *testing.T) {
+	g := NewWithT(t)
+
+	var m sync.Mutex
+	emitted := make([]KubeEvent, 0)
+	received := func() []KubeEvent {
+		m.Lock()
+		defer m.Unlock()
+		return append([]KubeEvent{}, emitted...)
+	}
+	batcher := newEventBatcher(100*time.Millisecond, 0, func(ev KubeEvent) {
+		m.Lock()
+		defer m.Unlock()
+		emitted = append(emitted, ev)
+	})
+
+	batcher.Add(podEvent(WatchEventAdded, "pod-1", "1"))
+	batcher.Add(podEvent(WatchEventModified, "pod-1", "2"))
+	batcher.Add(podEvent(WatchEventAdded, "pod-2", "1"))
+	batcher.Add(podEvent(WatchEventDeleted, "pod-2", "1"))
+	batcher.Add(podEvent(WatchEventModified, "pod-3", "1"))
+
+	g.Eventually(received, time.Second, 10*time.Millisecond).Should(HaveLen(1))
+	ev := received()[0]
+	g.Expect(ev.MonitorId).Should(Equal("pods"))
+	g.Expect(ev.WatchEvents).Should(Equal([]WatchEventType{WatchEventAdded, WatchEventModified}))
+	g.Expect(ev.Objects).Should(HaveLen(2))
+	g.Expect(ev.Objects[0].Metadata.ResourceId).Should(Equal("default/Pod/pod-1"))
+	g.Expect(ev.Objects[0].FilterResult).Should(Equal("2"))
+	g.Expect(ev.Objects[1].Metadata.ResourceId).Should(Equal("default/Pod/pod-3"))
+
+	// Stopped batcher drops events.
+	batcher.Add(podEvent(WatchEventModified, "pod-1", "3"))
+	batcher.Stop()
+	g.Consistently(received, 300*time.Millisecond, 50*time.Millisecond).Should(HaveLen(1))
+}
+
+func Test_EventBatcher_MaxWait(t *testing.T) {
+	g := NewWithT(t)
+
+	emitted := make(chan KubeEvent, 10)
+	batcher := newEventBatcher(200*time.Millisecond, 300*time.Millisecond, func(ev KubeEvent) {
+		emitted <- ev
+	})
+	defer batcher.Stop()
+
+	// Events come more often than debounce, so the batch is emitted after maxWait.
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	start := time.Now()
+	go func() {
+		for {
+			batcher.Add(podEvent(WatchEventModified, "pod-1", "1"))
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}()
+
+	var ev KubeEvent
+	g.Eventually(emitted, time.Second).Should(Receive(&ev))
+	g.Expect(time.Since(start)).Should(BeNumerically("<", 600*time.Millisecond))
+	g.Expect(ev.Objects).Should(HaveLen(1))
+}
+
+func Test_EventBatcher_Pause(t *testing.T) {
+	g := NewWithT(t)
+
+	emitted := make(chan KubeEvent, 10)
+	batcher := newEventBatcher(time.Hour, 0, func(ev KubeEvent) {
+		emitted <- ev
+	})
+
+	batcher.Add(podEvent(WatchEventAdded, "pod-1", "1"))
+	batcher.Add(podEvent(WatchEventModified, "pod-2", "1"))
+
+	// Buffered events are emitted before Pause returns.
+	batcher.Pause()
+	g.Expect(emitted).Should(HaveLen(1))
+	ev := <-emitted
+	g.Expect(ev.WatchEvents).Should(Equal([]WatchEventType{WatchEventAdded, WatchEventModified}))
+	g.Expect(ev.Objects).Should(HaveLen(2))
+
+	// Paused batcher ignores new events.
+	batcher.Add(podEvent(WatchEventModified, "pod-1", "2"))
+	batcher.Pause()
+	g.Expect(emitted).Should(BeEmpty())
+}
+
+func Test_KubeEventsManager_PauseHandleEvents_Debounce(t *testing.T) {
+	g := NewWithT(t)
+
+	fc := fake.NewFakeCluster()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := NewKubeEventsManager()
+	mgr.WithContext(ctx)
+	mgr.WithKubeClient(fc.KubeClient)
+
+	monitorConfig := &MonitorConfig{
+		ApiVersion: "v1",
+		Kind:       "Pod",
+		NamespaceSelector: &NamespaceSelector{
+			NameSelector: &NameSelector{MatchNames: []string{"default"}},
+		},
+		Debounce: time.Hour,
+	}
+	monitorConfig.WithEventTypes(nil)
+	monitorConfig.Metadata.MonitorId = "pods"
+	monitorConfig.Metadata.DebugName = "pods"
+
+	_, err := mgr.AddMonitor(monitorConfig)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	mgr.Start()
+
+	fc.CreateSimpleNamespaced("default", "Pod", "pod-1")
+	batcher := mgr.Monitors["pods"].(*monitor).batcher
+	g.Eventually(func() int {
+		batcher.mu.Lock()
+		defer batcher.mu.Unlock()
+		return len(batcher.ids)
+	}, 5*time.Second, 10*time.Millisecond).Should(Equal(1))
+
+	// Buffered events are not lost on shutdown.
+	paused := make(chan struct{})
+	go func() {
+		mgr.PauseHandleEvents()
+		close(paused)
+	}()
+	var ev KubeEvent
+	g.Eventually(mgr.Ch(), 5*time.Second).Should(Receive(&ev))
+	g.Eventually(paused, 5*time.Second).Should(BeClosed())
+	g.Expect(ev.MonitorId).Should(Equal("pods"))
+	g.Expect(ev.Objects).Should(HaveLen(1))
+	g.Expect(ev.Objects[0].Metadata.ResourceId).Should(ContainSubstring("pod-1"))
+}
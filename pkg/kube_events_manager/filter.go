@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"runtime/trace"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/flant/shell-operator/pkg/app"
@@ -26,6 +27,13 @@ func ApplyFilter(jqFilter string, filterFn func(obj *unstructured.Unstructured)
 	}
 	res.Metadata.JqFilter = jqFilter
 	res.Metadata.ResourceId = ResourceId(obj)
+	res.Metadata.ObjectRef = corev1.ObjectReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}
 
 	data, err := json.Marshal(obj)
 	if err != nil {
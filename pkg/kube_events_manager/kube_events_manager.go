@@ -9,13 +9,16 @@ import (
 	"github.com/flant/shell-operator/pkg/kube"
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
 	"github.com/flant/shell-operator/pkg/metric_storage"
+	"github.com/flant/shell-operator/pkg/tracing"
 )
 
 type KubeEventsManager interface {
 	WithContext(ctx context.Context)
 	WithMetricStorage(mstor *metric_storage.MetricStorage)
 	WithKubeClient(client kube.KubernetesClient)
+	WithSnapshotStore(store *SnapshotStore)
 	AddMonitor(monitorConfig *MonitorConfig) (*KubeEvent, error)
+	MakeKubeEvent(monitor Monitor, ev ...KubeEvent) *KubeEvent
 	HasMonitor(monitorId string) bool
 	GetMonitor(monitorId string) Monitor
 	StartMonitor(monitorId string)
@@ -24,6 +27,7 @@ type KubeEventsManager interface {
 	StopMonitor(configId string) error
 	Ch() chan KubeEvent
 	PauseHandleEvents()
+	SaveSnapshots()
 }
 
 // kubeEventsManager is a main implementation of KubeEventsManager.
@@ -34,6 +38,10 @@ type kubeEventsManager struct {
 	KubeEventCh chan KubeEvent
 
 	KubeClient kube.KubernetesClient
+	// SnapshotStore is not nil if caches of informers are saved on disk.
+	SnapshotStore *SnapshotStore
+	// InformerFactory shares informers between monitors that watch the same resources.
+	InformerFactory *SharedInformerFactory
 
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -46,14 +54,16 @@ var _ KubeEventsManager = &kubeEventsManager{}
 // NewKubeEventsManager returns an implementation of KubeEventsManager.
 var NewKubeEventsManager = func() *kubeEventsManager {
 	em := &kubeEventsManager{
-		Monitors:    make(map[string]Monitor),
-		KubeEventCh: make(chan KubeEvent, 1),
+		Monitors:        make(map[string]Monitor),
+		KubeEventCh:     make(chan KubeEvent, 1),
+		InformerFactory: NewSharedInformerFactory(),
 	}
 	return em
 }
 
 func (mgr *kubeEventsManager) WithContext(ctx context.Context) {
 	mgr.ctx, mgr.cancel = context.WithCancel(ctx)
+	mgr.InformerFactory.WithContext(mgr.ctx)
 }
 
 func (mgr *kubeEventsManager) WithMetricStorage(mstor *metric_storage.MetricStorage) {
@@ -64,6 +74,10 @@ func (mgr *kubeEventsManager) WithKubeClient(client kube.KubernetesClient) {
 	mgr.KubeClient = client
 }
 
+func (mgr *kubeEventsManager) WithSnapshotStore(store *SnapshotStore) {
+	mgr.SnapshotStore = store
+}
+
 // AddMonitor creates a monitor with informers and return a KubeEvent with existing objects.
 // TODO cleanup informers in case of error
 // TODO use Context to stop informers
@@ -74,11 +88,17 @@ func (mgr *kubeEventsManager) AddMonitor(monitorConfig *MonitorConfig) (*KubeEve
 	monitor.WithKubeClient(mgr.KubeClient)
 	monitor.WithMetricStorage(mgr.metricStorage)
 	monitor.WithConfig(monitorConfig)
+	monitor.WithSnapshotStore(mgr.SnapshotStore)
+	monitor.WithSharedInformerFactory(mgr.InformerFactory)
 	monitor.WithKubeEventCb(func(ev KubeEvent) {
 		defer trace.StartRegion(context.Background(), "EmitKubeEvent").End()
 		outEvent := mgr.MakeKubeEvent(monitor, ev)
 		if outEvent != nil {
+			// The span includes a time to wait for the events handler.
+			_, span := tracing.Start(ev.SpanContext, "EmitKubeEvent")
+			outEvent.SpanContext = span.SpanContext()
 			mgr.KubeEventCh <- *outEvent
+			span.End()
 		}
 	})
 
@@ -160,3 +180,14 @@ func (mgr *kubeEventsManager) PauseHandleEvents() {
 		monitor.PauseHandleEvents()
 	}
 }
+
+// SaveSnapshots writes caches of informers to the snapshot store. It should be called
+// after PauseHandleEvents on shutdown to resume watches from the saved state on start.
+func (mgr *kubeEventsManager) SaveSnapshots() {
+	if mgr.SnapshotStore == nil {
+		return
+	}
+	for _, monitor := range mgr.Monitors {
+		monitor.SaveSnapshots()
+	}
+}
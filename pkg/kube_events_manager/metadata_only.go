@@ -0,0 +1,111 @@
+package kube_events_manager
+
+import (
+	"strings"
+)
+
+// Top level fields of PartialObjectMetadata.
+var metadataOnlyFields = map[string]bool{
+	"metadata":   true,
+	"apiVersion": true,
+	"kind":       true,
+}
+
+// Keywords that do not read the input.
+var metadataOnlyKeywords = map[string]bool{
+	"null":  true,
+	"true":  true,
+	"false": true,
+	"and":   true,
+	"or":    true,
+}
+
+// IsMetadataOnlyJqFilter returns true if the jq filter reads only .metadata, .apiVersion
+// and .kind fields. The check is conservative: paths, object construction and
+// boolean operators are allowed, but functions, variables and paths applied to
+// piped values are not, so filters like '.metadata | keys' are treated as full.
+func IsMetadataOnlyJqFilter(filter string) bool {
+	s := strings.TrimSpace(filter)
+	if s == "" {
+		return false
+	}
+
+	hasPath := false
+	// A previous significant character to distinguish a root path from a path continuation.
+	var prev byte
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			// Skip a string literal, string interpolation is not supported.
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					if j+1 < len(s) && s[j+1] == '(' {
+						return false
+					}
+					j++
+				}
+			}
+			if j >= len(s) {
+				return false
+			}
+			i = j + 1
+			prev = c
+		case c == '.':
+			// Recursive descent reads all fields.
+			if i+1 < len(s) && s[i+1] == '.' {
+				return false
+			}
+			field := readIdent(s, i+1)
+			if !isPathContinuation(prev) {
+				if !metadataOnlyFields[field] {
+					return false
+				}
+				hasPath = true
+			}
+			i += 1 + len(field)
+			prev = 'a'
+		case isIdentStart(c):
+			word := readIdent(s, i)
+			i += len(word)
+			// A key in an object construction.
+			next := strings.TrimLeft(s[i:], " \t\r\n")
+			if !metadataOnlyKeywords[word] && !strings.HasPrefix(next, ":") {
+				return false
+			}
+			// A path after a keyword starts from the root: '.metadata.name and .spec'.
+			prev = ' '
+		case c == '$' || c == '#' || c == '@':
+			// Variables, comments and formats.
+			return false
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		default:
+			i++
+			prev = c
+		}
+	}
+	return hasPath
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func readIdent(s string, start int) string {
+	end := start
+	for end < len(s) && isIdentChar(s[end]) {
+		end++
+	}
+	return s[start:end]
+}
+
+// isPathContinuation returns true if '.' after prev continues a path, e.g. '.metadata.name' or '.[0].name'.
+func isPathContinuation(prev byte) bool {
+	return isIdentChar(prev) || prev == ']' || prev == '?' || prev == '"' || prev == ')'
+}
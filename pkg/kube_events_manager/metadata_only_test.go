@@ -0,0 +1,86 @@
+package kube_events_manager
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/flant/shell-operator/pkg/kube/fake"
+)
+
+func Test_IsMetadataOnlyJqFilter(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{`.metadata.labels`, true},
+		{`.metadata.name`, true},
+		{`.kind`, true},
+		{`.metadata.labels["app"]`, true},
+		{`.metadata.labels."app.kubernetes.io/name"`, true},
+		{`{name: .metadata.name, labels: .metadata.labels}`, true},
+		{`[.metadata.name, .metadata.namespace]`, true},
+		{`.metadata.ownerReferences[0].name`, true},
+		{`.metadata.deletionTimestamp != null`, true},
+		{`.metadata.name and .metadata.namespace`, true},
+		{``, false},
+		{`.`, false},
+		{`.spec`, false},
+		{`.data`, false},
+		{`..`, false},
+		{`.metadata | keys`, false},
+		{`.metadata.name and .spec`, false},
+		{`{name: .metadata.name, replicas: .spec.replicas}`, false},
+		{`.metadata as $m | .spec`, false},
+		{`"\(.spec.replicas)"`, false},
+		{`.metadata.name | .spec`, false},
+	}
+
+	for _, tt := range tests {
+		g.Expect(IsMetadataOnlyJqFilter(tt.filter)).Should(Equal(tt.expected), "filter: %s", tt.filter)
+	}
+}
+
+func Test_ResourceInformer_MetadataOnly(t *testing.T) {
+	g := NewWithT(t)
+
+	fc := fake.NewFakeCluster()
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace("default")
+	cm.SetName("cm-1")
+	cm.SetLabels(map[string]string{"app": "test"})
+	g.Expect(unstructured.SetNestedField(cm.Object, "value", "data", "key")).Should(Succeed())
+	_, err := fc.KubeClient.Dynamic().Resource(*fc.MustFindGVR("v1", "ConfigMap")).Namespace("default").Create(cm, metav1.CreateOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	monitor := &MonitorConfig{
+		ApiVersion:              "v1",
+		Kind:                    "ConfigMap",
+		KeepFullObjectsInMemory: true,
+		MetadataOnly:            true,
+	}
+	monitor.WithEventTypes(nil)
+	monitor.Metadata.DebugName = "configmaps"
+
+	informer := NewResourceInformer(monitor).(*resourceInformer)
+	informer.WithKubeClient(fc.KubeClient)
+	informer.WithNamespace("default")
+	informer.WithContext(context.Background())
+	g.Expect(informer.CreateSharedInformer()).Should(Succeed())
+
+	objects := informer.GetExistedObjects()
+	g.Expect(objects).Should(HaveLen(1))
+	obj := objects[0].Object
+	g.Expect(obj.GetAPIVersion()).Should(Equal("v1"))
+	g.Expect(obj.GetKind()).Should(Equal("ConfigMap"))
+	g.Expect(obj.GetLabels()).Should(Equal(map[string]string{"app": "test"}))
+	g.Expect(obj.Object).ShouldNot(HaveKey("data"))
+	g.Expect(objects[0].Metadata.ResourceId).Should(Equal("default/ConfigMap/cm-1"))
+}
@@ -19,10 +19,13 @@ type Monitor interface {
 	WithMetricStorage(mstor *metric_storage.MetricStorage)
 	WithConfig(config *MonitorConfig)
 	WithKubeEventCb(eventCb func(KubeEvent))
+	WithSnapshotStore(store *SnapshotStore)
+	WithSharedInformerFactory(factory *SharedInformerFactory)
 	CreateInformers() error
 	Start(context.Context)
 	Stop()
 	PauseHandleEvents()
+	SaveSnapshots()
 	GetExistedObjects() []ObjectAndFilterResult
 	GetConfig() *MonitorConfig
 }
@@ -49,6 +52,13 @@ type monitor struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	metricStorage *metric_storage.MetricStorage
+	snapshotStore *SnapshotStore
+	// informerFactory is not nil if informers are shared between monitors
+	informerFactory *SharedInformerFactory
+	// batcher is not nil if events are debounced
+	batcher *eventBatcher
+	// started is set on Start, informers are started once
+	started bool
 }
 
 var NewMonitor = func() Monitor {
@@ -84,6 +94,14 @@ func (m *monitor) WithKubeEventCb(eventCb func(KubeEvent)) {
 	m.eventCb = eventCb
 }
 
+func (m *monitor) WithSnapshotStore(store *SnapshotStore) {
+	m.snapshotStore = store
+}
+
+func (m *monitor) WithSharedInformerFactory(factory *SharedInformerFactory) {
+	m.informerFactory = factory
+}
+
 // CreateInformers creates all informers and
 // a namespace informer if namespace.labelSelector is defined.
 // If MonitorConfig.NamespaceSelector.MatchNames is defined, then
@@ -95,6 +113,13 @@ func (m *monitor) CreateInformers() error {
 		WithField("binding.name", m.Config.Metadata.DebugName)
 
 	logEntry.Debugf("Create Informers Config: %+v", m.Config)
+
+	// Informers pass events to the batcher, it emits them to eventCb.
+	if m.Config.Debounce > 0 && m.batcher == nil {
+		m.batcher = newEventBatcher(m.Config.Debounce, m.Config.MaxWait, m.eventCb)
+		m.eventCb = m.batcher.Add
+	}
+
 	nsNames := m.Config.Namespaces()
 	if len(nsNames) > 0 {
 		logEntry.Debugf("create static ResourceInformers")
@@ -107,6 +132,7 @@ func (m *monitor) CreateInformers() error {
 			}
 			informers, err := m.CreateInformersForNamespace(nsName)
 			if err != nil {
+				m.stopStaticInformers()
 				return err
 			}
 			m.ResourceInformers = append(m.ResourceInformers, informers...)
@@ -171,6 +197,7 @@ func (m *monitor) CreateInformers() error {
 			},
 		)
 		if err != nil {
+			m.stopStaticInformers()
 			return fmt.Errorf("create namespace informer: %v", err)
 		}
 		for nsName := range m.NamespaceInformer.GetExistedObjects() {
@@ -233,9 +260,19 @@ func (m *monitor) CreateInformersForNamespace(namespace string) (informers []Res
 		informer.WithNamespace(namespace)
 		informer.WithName(objName)
 		informer.WithKubeEventCb(m.eventCb)
+		if m.snapshotStore != nil {
+			informer.WithSnapshotStore(m.snapshotStore)
+		}
+		if m.informerFactory != nil {
+			informer.WithSharedInformerFactory(m.informerFactory)
+		}
 
 		err := informer.CreateSharedInformer()
 		if err != nil {
+			// Release shared informers of created informers.
+			for _, created := range informers {
+				created.Stop()
+			}
 			return nil, err
 		}
 
@@ -244,8 +281,22 @@ func (m *monitor) CreateInformersForNamespace(namespace string) (informers []Res
 	return informers, nil
 }
 
+// stopStaticInformers releases shared informers of created static informers
+// if the monitor cannot be created.
+func (m *monitor) stopStaticInformers() {
+	for _, informer := range m.ResourceInformers {
+		informer.Stop()
+	}
+	m.ResourceInformers = make([]ResourceInformer, 0)
+}
+
 // Start calls Run on all informers.
 func (m *monitor) Start(parentCtx context.Context) {
+	// A monitor can be reused by a reloaded hook.
+	if m.started {
+		return
+	}
+	m.started = true
 	m.ctx, m.cancel = context.WithCancel(parentCtx)
 
 	for _, informer := range m.ResourceInformers {
@@ -266,8 +317,23 @@ func (m *monitor) Start(parentCtx context.Context) {
 	}
 }
 
-// Stop stops all informers
+// Stop stops all informers. Static informers get the context before Start,
+// so they are stopped explicitly to release shared informers.
 func (m *monitor) Stop() {
+	for _, informer := range m.ResourceInformers {
+		informer.Stop()
+	}
+	for _, informers := range m.VaryingInformers {
+		for _, informer := range informers {
+			informer.Stop()
+		}
+	}
+	if m.NamespaceInformer != nil {
+		m.NamespaceInformer.Stop()
+	}
+	if m.batcher != nil {
+		m.batcher.Stop()
+	}
 	m.cancel()
 }
 
@@ -289,4 +355,21 @@ func (m *monitor) PauseHandleEvents() {
 		m.NamespaceInformer.PauseHandleEvents()
 	}
 
+	// Informers are paused, emit buffered events to not lose them on shutdown.
+	if m.batcher != nil {
+		m.batcher.Pause()
+	}
+}
+
+// SaveSnapshots writes caches of all informers to the snapshot store.
+func (m *monitor) SaveSnapshots() {
+	for _, informer := range m.ResourceInformers {
+		informer.SaveSnapshot()
+	}
+
+	for _, informers := range m.VaryingInformers {
+		for _, informer := range informers {
+			informer.SaveSnapshot()
+		}
+	}
 }
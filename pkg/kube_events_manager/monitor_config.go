@@ -1,6 +1,8 @@
 package kube_events_manager
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -29,7 +31,12 @@ type MonitorConfig struct {
 	LogEntry                *log.Entry
 	Mode                    KubeEventMode
 	KeepFullObjectsInMemory bool
+	MetadataOnly            bool
 	FilterFunc              func(obj *unstructured.Unstructured) (result string, err error)
+	// Debounce enables batching of events: one KubeEvent is emitted when there are
+	// no new events for Debounce period, but not later than MaxWait after the first event.
+	Debounce time.Duration
+	MaxWait  time.Duration
 }
 
 func (c *MonitorConfig) WithEventTypes(types []WatchEventType) *MonitorConfig {
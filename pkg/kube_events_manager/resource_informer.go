@@ -8,15 +8,19 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/flant/shell-operator/pkg/kube"
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
 	"github.com/flant/shell-operator/pkg/metric_storage"
+	"github.com/flant/shell-operator/pkg/tracing"
 	"github.com/flant/shell-operator/pkg/utils/measure"
 )
 
@@ -28,12 +32,15 @@ type ResourceInformer interface {
 	WithNamespace(string)
 	WithName(string)
 	WithKubeEventCb(eventCb func(KubeEvent))
+	WithSnapshotStore(store *SnapshotStore)
+	WithSharedInformerFactory(factory *SharedInformerFactory)
 	CreateSharedInformer() error
 	GetExistedObjects() []ObjectAndFilterResult
 	CachedObjectsBytes() int64
 	Start()
 	Stop()
 	PauseHandleEvents()
+	SaveSnapshot()
 }
 
 type resourceInformer struct {
@@ -61,6 +68,29 @@ type resourceInformer struct {
 
 	// a flag to stop handle events after Stop()
 	stopped bool
+
+	snapshotStore *SnapshotStore
+	snapshotKey   string
+	// Objects from the restored snapshot are passed to the informer as stubs
+	// with names only. Informer keeps pointers to list items, so stubs are compared by pointer.
+	snapshotStubs map[string]*unstructured.Unstructured
+	// A list of stubs for the first List to start the Watch from the stored resourceVersion.
+	restoredList *unstructured.UnstructuredList
+	// resourceVersion of the last handled event, it is saved with CachedObjects.
+	lastResourceVersion string
+	snapshotChanged     bool
+
+	// apiVersion and kind for PartialObjectMetadata objects in MetadataOnly mode.
+	metadataApiVersion string
+	metadataKind       string
+
+	// An informer is shared with other resourceInformers with the same GVR, namespace and selectors.
+	informerFactory *SharedInformerFactory
+	shared          *sharedInformer
+	releaseOnce     sync.Once
+
+	// handleLock is held while an event is handled, PauseHandleEvents waits for the running handler.
+	handleLock sync.RWMutex
 }
 
 // resourceInformer should implement ResourceInformer
@@ -99,6 +129,16 @@ func (ei *resourceInformer) WithKubeEventCb(eventCb func(KubeEvent)) {
 	ei.eventCb = eventCb
 }
 
+// WithSnapshotStore enables saving of cached objects on disk.
+func (ei *resourceInformer) WithSnapshotStore(store *SnapshotStore) {
+	ei.snapshotStore = store
+}
+
+// WithSharedInformerFactory enables sharing of informers between bindings.
+func (ei *resourceInformer) WithSharedInformerFactory(factory *SharedInformerFactory) {
+	ei.informerFactory = factory
+}
+
 func (ei *resourceInformer) EventCb(ev KubeEvent) {
 	if ei.eventCb != nil {
 		ei.eventCb(ev)
@@ -144,20 +184,222 @@ func (ei *resourceInformer) CreateSharedInformer() (err error) {
 	ei.ListOptions = metav1.ListOptions{}
 	tweakListOptions(&ei.ListOptions)
 
+	if ei.Monitor.MetadataOnly {
+		apiResource, err := ei.KubeClient.APIResource(ei.Monitor.ApiVersion, ei.Monitor.Kind)
+		if err != nil {
+			return fmt.Errorf("get kind for apiVersion '%s' kind '%s': %v", ei.Monitor.ApiVersion, ei.Monitor.Kind, err)
+		}
+		ei.metadataApiVersion = ei.GroupVersionResource.GroupVersion().String()
+		ei.metadataKind = apiResource.Kind
+		log.Debugf("%s: watch metadata only for '%s'", ei.Monitor.Metadata.DebugName, ei.GroupVersionResource.String())
+	}
+
+	// Go hooks are not cached on disk: a change of FilterFunc cannot be detected.
+	if ei.snapshotStore != nil && ei.Monitor.FilterFunc == nil {
+		ei.snapshotKey = SnapshotKey(ei.Monitor, ei.Namespace, ei.Name)
+	}
+	restored := ei.RestoreSnapshot()
+
 	// create informer with add, update, delete callbacks
-	informer := dynamicinformer.NewFilteredDynamicInformer(ei.KubeClient.Dynamic(), ei.GroupVersionResource, ei.Namespace, resyncPeriod, indexers, tweakListOptions)
-	informer.Informer().AddEventHandler(ei)
-	ei.SharedInformer = informer.Informer()
+	newInformer := func() cache.SharedIndexInformer {
+		listWatch := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				// The first List returns restored objects to start the Watch from the stored resourceVersion.
+				// Informer lists again if the Watch returns 410 Gone.
+				if list := ei.takeRestoredList(); list != nil {
+					return list, nil
+				}
+				tweakListOptions(&options)
+				return ei.listObjects(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				tweakListOptions(&options)
+				return ei.watchObjects(options)
+			},
+		}
+		return cache.NewSharedIndexInformer(listWatch, &unstructured.Unstructured{}, resyncPeriod, indexers)
+	}
+
+	// Informers restored from snapshots are not shared: their caches have their own resourceVersion.
+	if ei.informerFactory != nil && !restored && ei.snapshotKey == "" {
+		key := SharedInformerKey(ei.GroupVersionResource, ei.Namespace, fmtLabelSelector, fmtFieldSelector, ei.Monitor.MetadataOnly)
+		ei.shared = ei.informerFactory.Subscribe(key, newInformer)
+		ei.SharedInformer = ei.shared.informer
+	} else {
+		ei.SharedInformer = newInformer()
+		ei.SharedInformer.AddEventHandler(ei)
+	}
+
+	if restored {
+		return nil
+	}
 
 	err = ei.LoadExistedObjects()
 	if err != nil {
 		log.Errorf("load existing objects: %v", err)
+		ei.releaseSharedInformer()
 		return err
 	}
 
 	return nil
 }
 
+// listObjects lists full objects or metadata of objects. Metadata is converted to Unstructured
+// to use the same cache and filters in both modes.
+func (ei *resourceInformer) listObjects(options metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if !ei.Monitor.MetadataOnly {
+		return ei.KubeClient.Dynamic().Resource(ei.GroupVersionResource).Namespace(ei.Namespace).List(options)
+	}
+
+	metaList, err := ei.KubeClient.Metadata().Resource(ei.GroupVersionResource).Namespace(ei.Namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{
+		Items: make([]unstructured.Unstructured, 0, len(metaList.Items)),
+	}
+	list.SetResourceVersion(metaList.GetResourceVersion())
+	list.SetContinue(metaList.GetContinue())
+	for i := range metaList.Items {
+		obj, err := ei.metadataToUnstructured(&metaList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, *obj)
+	}
+	return list, nil
+}
+
+func (ei *resourceInformer) watchObjects(options metav1.ListOptions) (watch.Interface, error) {
+	if !ei.Monitor.MetadataOnly {
+		return ei.KubeClient.Dynamic().Resource(ei.GroupVersionResource).Namespace(ei.Namespace).Watch(options)
+	}
+
+	w, err := ei.KubeClient.Metadata().Resource(ei.GroupVersionResource).Namespace(ei.Namespace).Watch(options)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(ev watch.Event) (watch.Event, bool) {
+		if partial, ok := ev.Object.(*metav1.PartialObjectMetadata); ok {
+			obj, err := ei.metadataToUnstructured(partial)
+			if err != nil {
+				log.Errorf("%s: convert metadata of '%s': %v", ei.Monitor.Metadata.DebugName, partial.GetName(), err)
+				return ev, false
+			}
+			ev.Object = obj
+		}
+		return ev, true
+	}), nil
+}
+
+// metadataToUnstructured sets apiVersion and kind of the watched resource,
+// PartialObjectMetadata has its own type meta.
+func (ei *resourceInformer) metadataToUnstructured(partial *metav1.PartialObjectMetadata) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(partial)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetAPIVersion(ei.metadataApiVersion)
+	obj.SetKind(ei.metadataKind)
+	return obj, nil
+}
+
+// RestoreSnapshot fills the cache with objects from the snapshot store.
+// It returns false if there is no snapshot for the informer.
+func (ei *resourceInformer) RestoreSnapshot() bool {
+	if ei.snapshotKey == "" {
+		return false
+	}
+	snapshot, err := ei.snapshotStore.Load(ei.snapshotKey)
+	if err != nil {
+		log.Errorf("%s: restore snapshot: %v", ei.Monitor.Metadata.DebugName, err)
+		_ = ei.snapshotStore.Remove(ei.snapshotKey)
+		return false
+	}
+	if snapshot == nil || snapshot.ResourceVersion == "" {
+		return false
+	}
+
+	ei.cacheLock.Lock()
+	defer ei.cacheLock.Unlock()
+	list := &unstructured.UnstructuredList{
+		Items: make([]unstructured.Unstructured, len(snapshot.Objects)),
+	}
+	list.SetResourceVersion(snapshot.ResourceVersion)
+	ei.snapshotStubs = make(map[string]*unstructured.Unstructured, len(snapshot.Objects))
+	for i, snapshotObj := range snapshot.Objects {
+		obj := snapshotObj.ObjectAndFilterResult()
+		ei.CachedObjects[obj.Metadata.ResourceId] = obj
+
+		stub := &list.Items[i]
+		stub.SetAPIVersion(obj.Metadata.ObjectRef.APIVersion)
+		stub.SetKind(obj.Metadata.ObjectRef.Kind)
+		stub.SetNamespace(obj.Metadata.ObjectRef.Namespace)
+		stub.SetName(obj.Metadata.ObjectRef.Name)
+		stub.SetUID(obj.Metadata.ObjectRef.UID)
+		ei.snapshotStubs[obj.Metadata.ResourceId] = stub
+	}
+	ei.restoredList = list
+	ei.lastResourceVersion = snapshot.ResourceVersion
+
+	log.Infof("%s: restored %d '%s' objects from snapshot with resourceVersion %s",
+		ei.Monitor.Metadata.DebugName, len(snapshot.Objects), ei.Monitor.Kind, snapshot.ResourceVersion)
+
+	ei.metricStorage.GaugeSet("{PREFIX}kube_snapshot_objects", float64(len(ei.CachedObjects)), ei.Monitor.Metadata.MetricLabels)
+	ei.metricStorage.GaugeSet("{PREFIX}kube_snapshot_bytes", float64(ObjectAndFilterResults(ei.CachedObjects).Bytes()), ei.Monitor.Metadata.MetricLabels)
+	return true
+}
+
+// takeRestoredList returns stubs of restored objects once.
+func (ei *resourceInformer) takeRestoredList() *unstructured.UnstructuredList {
+	ei.cacheLock.Lock()
+	defer ei.cacheLock.Unlock()
+	list := ei.restoredList
+	ei.restoredList = nil
+	return list
+}
+
+// isSnapshotStub returns true if obj is a stub for the restored object.
+func (ei *resourceInformer) isSnapshotStub(obj *unstructured.Unstructured, resourceId string) bool {
+	ei.cacheLock.RLock()
+	defer ei.cacheLock.RUnlock()
+	stub, has := ei.snapshotStubs[resourceId]
+	return has && stub == obj
+}
+
+// SaveSnapshot writes cached objects and the last resourceVersion to the snapshot store.
+func (ei *resourceInformer) SaveSnapshot() {
+	if ei.snapshotKey == "" {
+		return
+	}
+	ei.cacheLock.RLock()
+	if !ei.snapshotChanged || ei.lastResourceVersion == "" {
+		ei.cacheLock.RUnlock()
+		return
+	}
+	snapshot := &InformerSnapshot{
+		ResourceVersion: ei.lastResourceVersion,
+		Objects:         make([]SnapshotObject, 0, len(ei.CachedObjects)),
+	}
+	for _, obj := range ei.CachedObjects {
+		snapshot.Objects = append(snapshot.Objects, NewSnapshotObject(obj))
+	}
+	ei.cacheLock.RUnlock()
+
+	err := ei.snapshotStore.Save(ei.snapshotKey, snapshot)
+	if err != nil {
+		log.Errorf("%s: save snapshot: %v", ei.Monitor.Metadata.DebugName, err)
+		return
+	}
+
+	ei.cacheLock.Lock()
+	if ei.lastResourceVersion == snapshot.ResourceVersion {
+		ei.snapshotChanged = false
+	}
+	ei.cacheLock.Unlock()
+}
+
 // TODO we need locks between HandleEvent and GetExistedObjects
 func (ei *resourceInformer) GetExistedObjects() []ObjectAndFilterResult {
 	ei.cacheLock.RLock()
@@ -179,14 +421,25 @@ func (ei *resourceInformer) CachedObjectsBytes() int64 {
 // fills Checksum map with checksums of existing objects.
 func (ei *resourceInformer) LoadExistedObjects() error {
 	defer trace.StartRegion(context.Background(), "LoadExistedObjects").End()
-	objList, err := ei.KubeClient.Dynamic().
-		Resource(ei.GroupVersionResource).
-		Namespace(ei.Namespace).
-		List(ei.ListOptions)
+	var objList *unstructured.UnstructuredList
+	var err error
+	if ei.shared != nil {
+		objList, err = ei.shared.ListExistedObjects(func() (*unstructured.UnstructuredList, error) {
+			return ei.listObjects(ei.ListOptions)
+		})
+	} else {
+		objList, err = ei.listObjects(ei.ListOptions)
+	}
 	if err != nil {
 		log.Errorf("%s: initial list resources of kind '%s': %v", ei.Monitor.Metadata.DebugName, ei.Monitor.Kind, err)
 		return err
 	}
+	if objList != nil {
+		ei.cacheLock.Lock()
+		ei.lastResourceVersion = objList.GetResourceVersion()
+		ei.snapshotChanged = true
+		ei.cacheLock.Unlock()
+	}
 
 	if objList == nil || len(objList.Items) == 0 {
 		log.Debugf("%s: Got no existing '%s' resources", ei.Monitor.Metadata.DebugName, ei.Monitor.Kind)
@@ -255,9 +508,11 @@ func (ei *resourceInformer) OnDelete(obj interface{}) {
 
 // HandleKubeEvent register object in cache. Pass object to callback if object's checksum is changed.
 // TODO refactor: pass KubeEvent as argument
-// TODO add delay to merge Added and Modified events (node added and then labels applied — one hook run on Added+Modified is enough)
+// Added and Modified events are merged for bindings with 'debounce', see eventBatcher.
 //func (ei *resourceInformer) HandleKubeEvent(obj *unstructured.Unstructured, objectId string, filterResult string, newChecksum string, eventType WatchEventType) {
 func (ei *resourceInformer) HandleWatchEvent(object interface{}, eventType WatchEventType) {
+	ei.handleLock.RLock()
+	defer ei.handleLock.RUnlock()
 	// check if stop
 	if ei.stopped {
 		return
@@ -268,33 +523,65 @@ func (ei *resourceInformer) HandleWatchEvent(object interface{}, eventType Watch
 	})()
 	defer trace.StartRegion(context.Background(), "HandleWatchEvent").End()
 
-	if staleObj, stale := object.(cache.DeletedFinalStateUnknown); stale {
+	staleObj, stale := object.(cache.DeletedFinalStateUnknown)
+	if stale {
 		object = staleObj.Obj
 	}
 	var obj = object.(*unstructured.Unstructured)
 
 	resourceId := ResourceId(obj)
 
+	// Restored objects are already in cache. A stub is deleted if the object
+	// was deleted while the operator was stopped.
+	isStub := ei.isSnapshotStub(obj, resourceId)
+	if isStub && eventType != WatchEventDeleted {
+		return
+	}
+	// A stale object has an old resourceVersion.
+	resourceVersion := obj.GetResourceVersion()
+	if stale {
+		resourceVersion = ""
+	}
+
+	// A new trace is started for each watch event.
+	_, span := tracing.Tracer().Start(context.Background(), "HandleWatchEvent")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("monitor.id", ei.Monitor.Metadata.MonitorId),
+		attribute.String("watch.event", string(eventType)),
+		attribute.String("resource.id", resourceId),
+	)
+
 	// Always calculate checksum and update cache, because we need actual state in CachedObjects
 
 	var objFilterRes *ObjectAndFilterResult
 	var err error
-	func() {
-		defer measure.Duration(func(d time.Duration) {
-			ei.metricStorage.HistogramObserve("{PREFIX}kube_jq_filter_duration_seconds", d.Seconds(), ei.Monitor.Metadata.MetricLabels)
-		})()
-		objFilterRes, err = ApplyFilter(ei.Monitor.JqFilter, ei.Monitor.FilterFunc, obj)
-	}()
-	if err != nil {
-		log.Errorf("%s: WATCH %s: %s",
-			ei.Monitor.Metadata.DebugName,
-			eventType,
-			err)
-		return
-	}
+	if isStub {
+		ei.cacheLock.RLock()
+		objFilterRes = ei.CachedObjects[resourceId]
+		ei.cacheLock.RUnlock()
+		if objFilterRes == nil {
+			return
+		}
+	} else {
+		func() {
+			defer measure.Duration(func(d time.Duration) {
+				ei.metricStorage.HistogramObserve("{PREFIX}kube_jq_filter_duration_seconds", d.Seconds(), ei.Monitor.Metadata.MetricLabels)
+			})()
+			objFilterRes, err = ApplyFilter(ei.Monitor.JqFilter, ei.Monitor.FilterFunc, obj)
+		}()
+		if err != nil {
+			log.Errorf("%s: WATCH %s: %s",
+				ei.Monitor.Metadata.DebugName,
+				eventType,
+				err)
+			span.SetStatus(codes.Error, err.Error())
+			return
+		}
 
-	if !ei.Monitor.KeepFullObjectsInMemory {
-		objFilterRes.RemoveFullObject()
+		if !ei.Monitor.KeepFullObjectsInMemory {
+			objFilterRes.RemoveFullObject()
+		}
 	}
 
 	// Do not fire Added or Modified if object is in cache and its checksum is equal to the newChecksum.
@@ -317,16 +604,22 @@ func (ei *resourceInformer) HandleWatchEvent(object interface{}, eventType Watch
 			skipEvent = true
 		}
 		ei.CachedObjects[resourceId] = objFilterRes
+		// The stub is replaced with the actual object in the informer.
+		delete(ei.snapshotStubs, resourceId)
+		ei.updateResourceVersion(resourceVersion)
 		ei.metricStorage.GaugeSet("{PREFIX}kube_snapshot_objects", float64(len(ei.CachedObjects)), ei.Monitor.Metadata.MetricLabels)
 		ei.metricStorage.GaugeSet("{PREFIX}kube_snapshot_bytes", float64(ObjectAndFilterResults(ei.CachedObjects).Bytes()), ei.Monitor.Metadata.MetricLabels)
 		ei.cacheLock.Unlock()
 		if skipEvent {
+			span.SetAttributes(attribute.Bool("skipped", true))
 			return
 		}
 
 	case WatchEventDeleted:
 		ei.cacheLock.Lock()
 		delete(ei.CachedObjects, resourceId)
+		delete(ei.snapshotStubs, resourceId)
+		ei.updateResourceVersion(resourceVersion)
 		ei.metricStorage.GaugeSet("{PREFIX}kube_snapshot_objects", float64(len(ei.CachedObjects)), ei.Monitor.Metadata.MetricLabels)
 		ei.metricStorage.GaugeSet("{PREFIX}kube_snapshot_bytes", float64(ObjectAndFilterResults(ei.CachedObjects).Bytes()), ei.Monitor.Metadata.MetricLabels)
 		ei.cacheLock.Unlock()
@@ -347,10 +640,20 @@ func (ei *resourceInformer) HandleWatchEvent(object interface{}, eventType Watch
 			MonitorId:   ei.Monitor.Metadata.MonitorId,
 			WatchEvents: []WatchEventType{eventType},
 			Objects:     []ObjectAndFilterResult{*objFilterRes},
+			SpanContext: span.SpanContext(),
 		})
 	}
 }
 
+// updateResourceVersion remembers a version of the last handled event. Events are handled
+// in order, so the cache is consistent with this version. Should be called under cacheLock.
+func (ei *resourceInformer) updateResourceVersion(resourceVersion string) {
+	if resourceVersion != "" {
+		ei.lastResourceVersion = resourceVersion
+	}
+	ei.snapshotChanged = true
+}
+
 func (ei *resourceInformer) adjustFieldSelector(selector *FieldSelector, objName string) *FieldSelector {
 	var selectorCopy *FieldSelector
 
@@ -391,25 +694,72 @@ func (ei *resourceInformer) ShouldFireEvent(checkEvent WatchEventType) bool {
 
 func (ei *resourceInformer) Start() {
 	log.Debugf("%s: RUN resource informer", ei.Monitor.Metadata.DebugName)
+	if ei.shared != nil {
+		ei.shared.AddHandler(ei)
+		ei.shared.Run()
+	}
+
 	stopCh := make(chan struct{}, 1)
 	go func() {
 		<-ei.ctx.Done()
 		ei.stopped = true
+		ei.releaseSharedInformer()
 		close(stopCh)
 	}()
 
+	if ei.snapshotKey != "" {
+		go ei.saveSnapshotPeriodically(stopCh)
+	}
+
+	if ei.shared != nil {
+		<-stopCh
+		return
+	}
 	ei.SharedInformer.Run(stopCh)
 }
 
+// releaseSharedInformer unsubscribes from the shared informer, it is stopped with the last subscriber.
+func (ei *resourceInformer) releaseSharedInformer() {
+	if ei.shared == nil {
+		return
+	}
+	ei.releaseOnce.Do(func() {
+		ei.informerFactory.Release(ei.shared, ei)
+	})
+}
+
+func (ei *resourceInformer) saveSnapshotPeriodically(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(ei.snapshotStore.SaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			ei.handleLock.RLock()
+			stopped := ei.stopped
+			ei.handleLock.RUnlock()
+			if !stopped {
+				ei.SaveSnapshot()
+			}
+		}
+	}
+}
+
 func (ei *resourceInformer) Stop() {
 	log.Debugf("%s: STOP resource informer", ei.Monitor.Metadata.DebugName)
 	if ei.cancel != nil {
 		ei.cancel()
 	}
 	ei.stopped = true
+	ei.releaseSharedInformer()
 }
 
+// PauseHandleEvents stops handling of new events and waits until the running handler
+// passes its event to the callback. Events should be consumed to not block the handler.
 func (ei *resourceInformer) PauseHandleEvents() {
 	log.Debugf("%s: PAUSE resource informer", ei.Monitor.Metadata.DebugName)
+	ei.handleLock.Lock()
 	ei.stopped = true
+	ei.handleLock.Unlock()
 }
@@ -0,0 +1,238 @@
+package kube_events_manager
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SharedInformerFactory keeps one informer for resourceInformers with equal GVR, namespace
+// and selectors. Each resourceInformer applies its own jqFilter and keeps its own
+// checksums on top of the shared informer, so one Watch and one object store serve
+// all bindings that watch the same resources.
+type SharedInformerFactory struct {
+	ctx       context.Context
+	informers map[string]*sharedInformer
+	mu        sync.Mutex
+}
+
+func NewSharedInformerFactory() *SharedInformerFactory {
+	return &SharedInformerFactory{
+		ctx:       context.Background(),
+		informers: make(map[string]*sharedInformer),
+	}
+}
+
+func (f *SharedInformerFactory) WithContext(ctx context.Context) {
+	f.ctx = ctx
+}
+
+// Len returns a number of running and pending shared informers.
+func (f *SharedInformerFactory) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.informers)
+}
+
+// Subscribe returns a shared informer for the key. newInformer is called
+// to create an informer for the first subscriber.
+func (f *SharedInformerFactory) Subscribe(key string, newInformer func() cache.SharedIndexInformer) *sharedInformer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, has := f.informers[key]
+	if !has {
+		ctx, cancel := context.WithCancel(f.ctx)
+		s = &sharedInformer{
+			key:      key,
+			informer: newInformer(),
+			ctx:      ctx,
+			cancel:   cancel,
+		}
+		s.informer.AddEventHandler(s)
+		f.informers[key] = s
+	}
+	s.subscribers++
+	log.Debugf("shared informer '%s': %d subscribers", key, s.subscribers)
+	return s
+}
+
+// Release unsubscribes the resourceInformer. The shared informer is stopped
+// when there are no subscribers.
+func (f *SharedInformerFactory) Release(s *sharedInformer, ei *resourceInformer) {
+	s.RemoveHandler(ei)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s.subscribers--
+	if s.subscribers > 0 {
+		return
+	}
+	log.Debugf("shared informer '%s': no subscribers, stop", s.key)
+	s.cancel()
+	if f.informers[s.key] == s {
+		delete(f.informers, s.key)
+	}
+}
+
+// SharedInformerKey returns a key to share an informer. Selectors should be formatted.
+func SharedInformerKey(gvr schema.GroupVersionResource, namespace string, labelSelector string, fieldSelector string, metadataOnly bool) string {
+	return strings.Join([]string{
+		gvr.Group, gvr.Version, gvr.Resource,
+		namespace,
+		labelSelector,
+		fieldSelector,
+		strconv.FormatBool(metadataOnly),
+	}, "|")
+}
+
+// sharedInformer passes events from the informer to all subscribed resourceInformers.
+type sharedInformer struct {
+	key      string
+	informer cache.SharedIndexInformer
+
+	// a number of resourceInformers that use this informer, guarded by the factory lock
+	subscribers int
+
+	// started resourceInformers that receive events
+	handlers     []*resourceInformer
+	handlersLock sync.RWMutex
+	// Handlers are called without handlersLock: they may block on sending a KubeEvent.
+	// distributeLock keeps the order of events for a new handler.
+	distributeLock sync.Mutex
+	// objects from the first List are reused by subscribers until the informer is started
+	initialList *unstructured.UnstructuredList
+	started     bool
+	// listLock makes subscribers wait for the running List instead of listing again.
+	// It is not held by event handlers.
+	listLock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ListExistedObjects returns objects from the informer store if it is synced or a result of list.
+// A result of list is reused by other subscribers while the informer is not started.
+// handlersLock is not held during the List to not block events for other subscribers.
+func (s *sharedInformer) ListExistedObjects(list func() (*unstructured.UnstructuredList, error)) (*unstructured.UnstructuredList, error) {
+	s.listLock.Lock()
+	defer s.listLock.Unlock()
+
+	s.handlersLock.RLock()
+	started := s.started
+	initialList := s.initialList
+	s.handlersLock.RUnlock()
+
+	if started && s.informer.HasSynced() {
+		return s.storeList(), nil
+	}
+	if initialList != nil {
+		return initialList, nil
+	}
+
+	objList, err := list()
+	if err != nil {
+		return nil, err
+	}
+	s.handlersLock.Lock()
+	if !s.started {
+		s.initialList = objList
+	}
+	s.handlersLock.Unlock()
+	return objList, nil
+}
+
+func (s *sharedInformer) storeList() *unstructured.UnstructuredList {
+	items := s.informer.GetStore().List()
+	objList := &unstructured.UnstructuredList{
+		Items: make([]unstructured.Unstructured, 0, len(items)),
+	}
+	objList.SetResourceVersion(s.informer.LastSyncResourceVersion())
+	for _, item := range items {
+		if obj, ok := item.(*unstructured.Unstructured); ok {
+			objList.Items = append(objList.Items, *obj)
+		}
+	}
+	return objList
+}
+
+// AddHandler starts passing events to the resourceInformer. Objects from the store of
+// the running informer are passed as Added events to catch up changes since the
+// initial list, unchanged objects are skipped by checksum.
+func (s *sharedInformer) AddHandler(ei *resourceInformer) {
+	s.distributeLock.Lock()
+	defer s.distributeLock.Unlock()
+
+	s.handlersLock.Lock()
+	s.handlers = append(s.handlers, ei)
+	var objs []interface{}
+	if s.started {
+		objs = s.informer.GetStore().List()
+	}
+	s.handlersLock.Unlock()
+
+	for _, obj := range objs {
+		ei.OnAdd(obj)
+	}
+}
+
+func (s *sharedInformer) RemoveHandler(ei *resourceInformer) {
+	s.handlersLock.Lock()
+	defer s.handlersLock.Unlock()
+	for i, h := range s.handlers {
+		if h == ei {
+			s.handlers = append(s.handlers[:i], s.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Run starts the informer once.
+func (s *sharedInformer) Run() {
+	s.handlersLock.Lock()
+	defer s.handlersLock.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.initialList = nil
+	go s.informer.Run(s.ctx.Done())
+}
+
+func (s *sharedInformer) OnAdd(obj interface{}) {
+	s.distribute(func(ei *resourceInformer) {
+		ei.OnAdd(obj)
+	})
+}
+
+func (s *sharedInformer) OnUpdate(oldObj, newObj interface{}) {
+	s.distribute(func(ei *resourceInformer) {
+		ei.OnUpdate(oldObj, newObj)
+	})
+}
+
+func (s *sharedInformer) OnDelete(obj interface{}) {
+	s.distribute(func(ei *resourceInformer) {
+		ei.OnDelete(obj)
+	})
+}
+
+func (s *sharedInformer) distribute(fn func(ei *resourceInformer)) {
+	s.distributeLock.Lock()
+	defer s.distributeLock.Unlock()
+
+	s.handlersLock.RLock()
+	handlers := make([]*resourceInformer, len(s.handlers))
+	copy(handlers, s.handlers)
+	s.handlersLock.RUnlock()
+
+	for _, ei := range handlers {
+		fn(ei)
+	}
+}
@@ -0,0 +1,172 @@
+package kube_events_manager
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flant/shell-operator/pkg/kube/fake"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+func Test_KubeEventsManager_SharedInformers(t *testing.T) {
+	g := NewWithT(t)
+
+	fc := fake.NewFakeCluster()
+	fc.CreateSimpleNamespaced("default", "Pod", "pod-1")
+
+	var lists int32
+	dynamicClient := fc.KubeClient.Dynamic().(*fakedynamic.FakeDynamicClient)
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&lists, 1)
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := NewKubeEventsManager()
+	mgr.WithContext(ctx)
+	mgr.WithKubeClient(fc.KubeClient)
+
+	newMonitor := func(id string, labelSelector *metav1.LabelSelector) *MonitorConfig {
+		monitor := &MonitorConfig{
+			ApiVersion: "v1",
+			Kind:       "Pod",
+			NamespaceSelector: &NamespaceSelector{
+				NameSelector: &NameSelector{MatchNames: []string{"default"}},
+			},
+			LabelSelector: labelSelector,
+			FilterFunc: func(obj *unstructured.Unstructured) (string, error) {
+				return `"` + id + "/" + obj.GetName() + `"`, nil
+			},
+		}
+		monitor.WithEventTypes(nil)
+		monitor.Metadata.MonitorId = id
+		monitor.Metadata.DebugName = id
+		return monitor
+	}
+
+	// Each binding applies its own filter to shared objects.
+	for _, id := range []string{"first", "second"} {
+		ev, err := mgr.AddMonitor(newMonitor(id, nil))
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(ev.Objects).Should(HaveLen(1))
+		g.Expect(ev.Objects[0].FilterResult).Should(Equal(`"` + id + `/pod-1"`))
+	}
+	_, err := mgr.AddMonitor(newMonitor("labeled", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	// Monitors with equal selectors share one informer and the initial list.
+	g.Expect(mgr.InformerFactory.Len()).Should(Equal(2))
+	g.Expect(atomic.LoadInt32(&lists)).Should(Equal(int32(2)))
+
+	mgr.Start()
+
+	fc.CreateSimpleNamespaced("default", "Pod", "pod-2")
+
+	results := map[string]string{}
+	g.Eventually(func() map[string]string {
+		select {
+		case ev := <-mgr.Ch():
+			results[ev.MonitorId] = ev.Objects[0].FilterResult
+		default:
+		}
+		return results
+	}, 5*time.Second, 10*time.Millisecond).Should(Equal(map[string]string{
+		"first":  `"first/pod-2"`,
+		"second": `"second/pod-2"`,
+	}))
+
+	// The shared informer is stopped with the last subscriber.
+	g.Expect(mgr.StopMonitor("first")).Should(Succeed())
+	g.Expect(mgr.InformerFactory.Len()).Should(Equal(2))
+	g.Expect(mgr.StopMonitor("second")).Should(Succeed())
+	g.Expect(mgr.InformerFactory.Len()).Should(Equal(1))
+}
+
+func Test_KubeEventsManager_SharedInformers_ReleaseOnError(t *testing.T) {
+	g := NewWithT(t)
+
+	fc := fake.NewFakeCluster()
+	fc.CreateSimpleNamespaced("default", "Pod", "pod-1")
+
+	dynamicClient := fc.KubeClient.Dynamic().(*fakedynamic.FakeDynamicClient)
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "broken" {
+			return true, nil, fmt.Errorf("list is forbidden")
+		}
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := NewKubeEventsManager()
+	mgr.WithContext(ctx)
+	mgr.WithKubeClient(fc.KubeClient)
+
+	monitor := &MonitorConfig{
+		ApiVersion: "v1",
+		Kind:       "Pod",
+		NamespaceSelector: &NamespaceSelector{
+			NameSelector: &NameSelector{MatchNames: []string{"default", "broken"}},
+		},
+	}
+	monitor.WithEventTypes(nil)
+	monitor.Metadata.MonitorId = "monitor"
+	monitor.Metadata.DebugName = "monitor"
+
+	_, err := mgr.AddMonitor(monitor)
+	g.Expect(err).Should(HaveOccurred())
+
+	// Informer for the "default" namespace is released.
+	g.Expect(mgr.InformerFactory.Len()).Should(Equal(0))
+}
+
+func Test_SharedInformer_ListExistedObjects(t *testing.T) {
+	g := NewWithT(t)
+
+	s := &sharedInformer{}
+
+	var lists int32
+	listStarted := make(chan struct{})
+	unblockList := make(chan struct{})
+	list := func() (*unstructured.UnstructuredList, error) {
+		if atomic.AddInt32(&lists, 1) == 1 {
+			close(listStarted)
+		}
+		<-unblockList
+		return &unstructured.UnstructuredList{}, nil
+	}
+
+	results := make(chan *unstructured.UnstructuredList, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			objList, err := s.ListExistedObjects(list)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			results <- objList
+		}()
+	}
+	<-listStarted
+
+	// Handlers lock is not held during the List.
+	added := make(chan struct{})
+	go func() {
+		s.AddHandler(&resourceInformer{})
+		close(added)
+	}()
+	g.Eventually(added, 5*time.Second).Should(BeClosed())
+
+	close(unblockList)
+	first, second := <-results, <-results
+	g.Expect(first).Should(BeIdenticalTo(second))
+	g.Expect(atomic.LoadInt32(&lists)).Should(Equal(int32(1)))
+}
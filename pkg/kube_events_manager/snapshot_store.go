@@ -0,0 +1,186 @@
+package kube_events_manager
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+	utils_checksum "github.com/flant/shell-operator/pkg/utils/checksum"
+)
+
+const DefaultSnapshotSaveInterval = time.Minute
+
+// SnapshotStore keeps cached objects of informers on disk. An informer restores
+// its cache on start and resumes a Watch from the stored resourceVersion instead of a full List.
+type SnapshotStore struct {
+	Dir          string
+	SaveInterval time.Duration
+}
+
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{
+		Dir:          dir,
+		SaveInterval: DefaultSnapshotSaveInterval,
+	}
+}
+
+func (s *SnapshotStore) WithSaveInterval(interval time.Duration) *SnapshotStore {
+	if interval > 0 {
+		s.SaveInterval = interval
+	}
+	return s
+}
+
+// InformerSnapshot is a content of cache of a resourceInformer.
+type InformerSnapshot struct {
+	ResourceVersion string           `json:"resourceVersion"`
+	Objects         []SnapshotObject `json:"objects"`
+}
+
+// SnapshotObject is a persisted ObjectAndFilterResult. ObjectAndFilterResult
+// has a custom MarshalJSON for binding contexts and drops metadata.
+type SnapshotObject struct {
+	ResourceId   string                     `json:"resourceId"`
+	Checksum     string                     `json:"checksum"`
+	JqFilter     string                     `json:"jqFilter,omitempty"`
+	RemoveObject bool                       `json:"removeObject,omitempty"`
+	ObjectRef    corev1.ObjectReference     `json:"objectRef"`
+	Object       *unstructured.Unstructured `json:"object,omitempty"`
+	FilterResult string                     `json:"filterResult,omitempty"`
+	ObjectBytes  int64                      `json:"objectBytes,omitempty"`
+}
+
+func NewSnapshotObject(obj *ObjectAndFilterResult) SnapshotObject {
+	return SnapshotObject{
+		ResourceId:   obj.Metadata.ResourceId,
+		Checksum:     obj.Metadata.Checksum,
+		JqFilter:     obj.Metadata.JqFilter,
+		RemoveObject: obj.Metadata.RemoveObject,
+		ObjectRef:    obj.Metadata.ObjectRef,
+		Object:       obj.Object,
+		FilterResult: obj.FilterResult,
+		ObjectBytes:  obj.ObjectBytes,
+	}
+}
+
+func (o SnapshotObject) ObjectAndFilterResult() *ObjectAndFilterResult {
+	res := &ObjectAndFilterResult{
+		Object:       o.Object,
+		FilterResult: o.FilterResult,
+		ObjectBytes:  o.ObjectBytes,
+	}
+	res.Metadata.ResourceId = o.ResourceId
+	res.Metadata.Checksum = o.Checksum
+	res.Metadata.JqFilter = o.JqFilter
+	res.Metadata.RemoveObject = o.RemoveObject
+	res.Metadata.ObjectRef = o.ObjectRef
+	return res
+}
+
+// Load returns a snapshot for the key or nil if there is no snapshot.
+func (s *SnapshotStore) Load(key string) (*InformerSnapshot, error) {
+	f, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot '%s': %v", key, err)
+	}
+	defer zr.Close()
+
+	snapshot := &InformerSnapshot{}
+	err = json.NewDecoder(zr).Decode(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("decode snapshot '%s': %v", key, err)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot into a temporary file and renames it to not leave a partial file on crash.
+func (s *SnapshotStore) Save(key string, snapshot *InformerSnapshot) error {
+	err := os.MkdirAll(s.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(s.Dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	zw := gzip.NewWriter(f)
+	err = json.NewEncoder(zw).Encode(snapshot)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("encode snapshot '%s': %v", key, err)
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+// Remove deletes a snapshot, e.g. a broken one.
+func (s *SnapshotStore) Remove(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *SnapshotStore) path(key string) string {
+	return filepath.Join(s.Dir, key+".json.gz")
+}
+
+// SnapshotKey returns a stable key for the informer. Monitor id is random
+// on every start, so the key is calculated from the hook name and the binding configuration.
+// Snapshots of a changed binding are not restored.
+func SnapshotKey(monitor *MonitorConfig, namespace string, name string) string {
+	data, _ := json.Marshal(struct {
+		Hook                    string
+		Binding                 string
+		ApiVersion              string
+		Kind                    string
+		Namespace               string
+		Name                    string
+		LabelSelector           *metav1.LabelSelector
+		FieldSelector           *FieldSelector
+		JqFilter                string
+		KeepFullObjectsInMemory bool
+		MetadataOnly            bool
+	}{
+		Hook:                    monitor.Metadata.LogLabels["hook"],
+		Binding:                 monitor.Metadata.DebugName,
+		ApiVersion:              monitor.ApiVersion,
+		Kind:                    monitor.Kind,
+		Namespace:               namespace,
+		Name:                    name,
+		LabelSelector:           monitor.LabelSelector,
+		FieldSelector:           monitor.FieldSelector,
+		JqFilter:                monitor.JqFilter,
+		KeepFullObjectsInMemory: monitor.KeepFullObjectsInMemory,
+		MetadataOnly:            monitor.MetadataOnly,
+	})
+	return utils_checksum.CalculateChecksum(string(data))
+}
@@ -0,0 +1,190 @@
+package kube_events_manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flant/shell-operator/pkg/kube/fake"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+func podsMonitor() *MonitorConfig {
+	monitor := &MonitorConfig{
+		ApiVersion:              "v1",
+		Kind:                    "Pod",
+		KeepFullObjectsInMemory: true,
+	}
+	monitor.WithEventTypes(nil)
+	monitor.Metadata.MonitorId = "pods"
+	monitor.Metadata.DebugName = "pods"
+	monitor.Metadata.LogLabels = map[string]string{"hook": "hook.sh"}
+	return monitor
+}
+
+func Test_SnapshotStore_SaveAndLoad(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := ioutil.TempDir("", "snapshots")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	store := NewSnapshotStore(dir)
+
+	snapshot, err := store.Load("absent")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(snapshot).Should(BeNil())
+
+	obj := &ObjectAndFilterResult{FilterResult: `{"a":1}`}
+	obj.Metadata.ResourceId = "default/Pod/pod-1"
+	obj.Metadata.Checksum = "123"
+	obj.Metadata.ObjectRef.Name = "pod-1"
+	obj.RemoveFullObject()
+
+	err = store.Save("key", &InformerSnapshot{
+		ResourceVersion: "42",
+		Objects:         []SnapshotObject{NewSnapshotObject(obj)},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	snapshot, err = store.Load("key")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(snapshot.ResourceVersion).Should(Equal("42"))
+	g.Expect(snapshot.Objects).Should(HaveLen(1))
+	g.Expect(snapshot.Objects[0].ObjectAndFilterResult()).Should(Equal(obj))
+
+	// Key is changed with the binding configuration.
+	monitor := podsMonitor()
+	key := SnapshotKey(monitor, "default", "")
+	g.Expect(SnapshotKey(monitor, "default", "")).Should(Equal(key))
+	g.Expect(SnapshotKey(monitor, "prod", "")).ShouldNot(Equal(key))
+	monitor.JqFilter = ".metadata.labels"
+	g.Expect(SnapshotKey(monitor, "default", "")).ShouldNot(Equal(key))
+}
+
+func Test_ResourceInformer_RestoreSnapshot(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := ioutil.TempDir("", "snapshots")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+	store := NewSnapshotStore(dir)
+
+	fc := fake.NewFakeCluster()
+	fc.CreateSimpleNamespaced("default", "Pod", "pod-1")
+	fc.CreateSimpleNamespaced("default", "Pod", "pod-2")
+
+	newInformer := func() *resourceInformer {
+		informer := NewResourceInformer(podsMonitor()).(*resourceInformer)
+		informer.WithKubeClient(fc.KubeClient)
+		informer.WithNamespace("default")
+		informer.WithSnapshotStore(store)
+		return informer
+	}
+
+	first := newInformer()
+	first.WithContext(context.Background())
+	g.Expect(first.CreateSharedInformer()).Should(Succeed())
+	// Fake client returns lists without resourceVersion.
+	first.lastResourceVersion = "100"
+	first.SaveSnapshot()
+	listed := first.GetExistedObjects()
+	g.Expect(listed).Should(HaveLen(2))
+
+	// pod-2 is deleted while the operator is stopped.
+	fc.DeleteSimpleNamespaced("default", "Pod", "pod-2")
+
+	// The first Watch from the stored resourceVersion fails with 410 Gone.
+	dynamicClient := fc.KubeClient.Dynamic().(*fakedynamic.FakeDynamicClient)
+	var watchOnce sync.Once
+	watchVersion := make(chan string, 1)
+	dynamicClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		handled := false
+		var w watch.Interface
+		watchOnce.Do(func() {
+			handled = true
+			watchVersion <- action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion
+			fw := watch.NewFake()
+			go fw.Error(&apierrors.NewGone("too old resource version").ErrStatus)
+			w = fw
+		})
+		return handled, w, nil
+	})
+
+	second := newInformer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	second.WithContext(ctx)
+
+	var m sync.Mutex
+	events := []WatchEventType{}
+	second.WithKubeEventCb(func(ev KubeEvent) {
+		m.Lock()
+		defer m.Unlock()
+		events = append(events, ev.WatchEvents...)
+	})
+	g.Expect(second.CreateSharedInformer()).Should(Succeed())
+	g.Expect(second.GetExistedObjects()).Should(ConsistOf(listed))
+
+	go second.Start()
+	g.Eventually(watchVersion, 5*time.Second).Should(Receive(Equal("100")))
+
+	// Only the deleted object is reported after the relist, unchanged objects are skipped.
+	receivedEvents := func() []WatchEventType {
+		m.Lock()
+		defer m.Unlock()
+		return append([]WatchEventType{}, events...)
+	}
+	g.Eventually(receivedEvents, 5*time.Second, 50*time.Millisecond).Should(Equal([]WatchEventType{WatchEventDeleted}))
+	g.Consistently(receivedEvents, 500*time.Millisecond, 50*time.Millisecond).Should(HaveLen(1))
+	g.Expect(second.GetExistedObjects()).Should(HaveLen(1))
+}
+
+// PauseHandleEvents waits for the running handler, so a snapshot saved after pause
+// contains only changes that are passed to the callback.
+func Test_ResourceInformer_PauseHandleEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	informer := NewResourceInformer(podsMonitor()).(*resourceInformer)
+	emitted := make(chan KubeEvent)
+	informer.WithKubeEventCb(func(ev KubeEvent) {
+		emitted <- ev
+	})
+
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace("default")
+	pod.SetName("pod-1")
+	go informer.OnAdd(pod)
+	g.Eventually(func() int {
+		return len(informer.GetExistedObjects())
+	}, 5*time.Second, 10*time.Millisecond).Should(Equal(1))
+
+	paused := make(chan struct{})
+	go func() {
+		informer.PauseHandleEvents()
+		close(paused)
+	}()
+	g.Consistently(paused, 100*time.Millisecond).ShouldNot(BeClosed())
+
+	ev := <-emitted
+	g.Expect(ev.Objects[0].Metadata.ResourceId).Should(Equal("default/Pod/pod-1"))
+	g.Eventually(paused, 5*time.Second).Should(BeClosed())
+
+	// Events are ignored after pause.
+	pod2 := pod.DeepCopy()
+	pod2.SetName("pod-2")
+	informer.OnAdd(pod2)
+	g.Expect(informer.GetExistedObjects()).Should(HaveLen(1))
+}
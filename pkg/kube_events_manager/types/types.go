@@ -6,7 +6,9 @@ import (
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -40,6 +42,8 @@ type ObjectAndFilterResult struct {
 		Checksum     string
 		ResourceId   string // Used for sorting
 		RemoveObject bool
+		// ObjectRef is kept when the full object is removed from memory.
+		ObjectRef corev1.ObjectReference
 	}
 	Object       *unstructured.Unstructured // here is a pointer because of MarshalJSON receiver
 	FilterResult string
@@ -117,12 +121,14 @@ func (a ByNamespaceAndName) Less(i, j int) bool {
 }
 
 // KubeEvent contains MonitorId from monitor configuration, event type
-// and involved k8s objects.
+// and involved k8s objects. WatchEvents has an event for each object if events are debounced.
 type KubeEvent struct {
 	MonitorId   string
 	Type        KubeEventType // Event or Synchronization
 	WatchEvents []WatchEventType
 	Objects     []ObjectAndFilterResult
+	// SpanContext is a context of the span that emitted the event.
+	SpanContext trace.SpanContext
 }
 
 func (k KubeEvent) String() string {
@@ -136,7 +142,9 @@ func (k KubeEvent) String() string {
 			msgs = append(msgs, "Synchronization with 0 objects")
 		}
 	case TypeEvent:
-		if len(k.Objects) == 1 {
+		if len(k.Objects) > 1 {
+			msgs = append(msgs, fmt.Sprintf("Event with %d objects", len(k.Objects)))
+		} else if len(k.Objects) == 1 {
 			obj := k.Objects[0].Object
 			if len(k.WatchEvents) > 0 {
 				if obj != nil {
@@ -0,0 +1,155 @@
+package leader_election
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+// LeaderElector runs a leader election based on a Lease resource.
+// Callbacks are called when a replica starts or stops leading.
+type LeaderElector struct {
+	KubeClient kube.KubernetesClient
+	Namespace  string
+	LeaseName  string
+	Identity   string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	OnStartedLeading func(ctx context.Context)
+	OnStoppedLeading func()
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	elector  *leaderelection.LeaderElector
+	done     chan struct{}
+	isLeader int32
+}
+
+func NewLeaderElector() *LeaderElector {
+	return &LeaderElector{
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+}
+
+func (e *LeaderElector) WithContext(ctx context.Context) {
+	e.ctx, e.cancel = context.WithCancel(ctx)
+}
+
+func (e *LeaderElector) WithKubeClient(client kube.KubernetesClient) {
+	e.KubeClient = client
+}
+
+func (e *LeaderElector) WithLease(namespace string, name string) {
+	e.Namespace = namespace
+	e.LeaseName = name
+}
+
+func (e *LeaderElector) WithIdentity(identity string) {
+	e.Identity = identity
+}
+
+func (e *LeaderElector) WithTimings(leaseDuration, renewDeadline, retryPeriod time.Duration) {
+	e.LeaseDuration = leaseDuration
+	e.RenewDeadline = renewDeadline
+	e.RetryPeriod = retryPeriod
+}
+
+func (e *LeaderElector) WithCallbacks(onStartedLeading func(ctx context.Context), onStoppedLeading func()) {
+	e.OnStartedLeading = onStartedLeading
+	e.OnStoppedLeading = onStoppedLeading
+}
+
+// IsLeader returns true if this replica holds the Lease.
+func (e *LeaderElector) IsLeader() bool {
+	return atomic.LoadInt32(&e.isLeader) == 1
+}
+
+// Init creates a lock and an elector. Returns an error for a bad configuration.
+func (e *LeaderElector) Init() error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      e.LeaseName,
+			Namespace: e.Namespace,
+		},
+		Client: e.KubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: e.Identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   e.LeaseDuration,
+		RenewDeadline:   e.RenewDeadline,
+		RetryPeriod:     e.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Infof("Leader election: '%s' acquired lease %s/%s", e.Identity, e.Namespace, e.LeaseName)
+				atomic.StoreInt32(&e.isLeader, 1)
+				if e.OnStartedLeading != nil {
+					e.OnStartedLeading(ctx)
+				}
+			},
+			OnStoppedLeading: func() {
+				if atomic.SwapInt32(&e.isLeader, 0) == 0 {
+					return
+				}
+				// Lease is released by Stop.
+				if e.ctx.Err() != nil {
+					log.Infof("Leader election: '%s' released lease %s/%s", e.Identity, e.Namespace, e.LeaseName)
+					return
+				}
+				log.Errorf("Leader election: '%s' lost lease %s/%s", e.Identity, e.Namespace, e.LeaseName)
+				if e.OnStoppedLeading != nil {
+					e.OnStoppedLeading()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if identity != e.Identity {
+					log.Infof("Leader election: current leader is '%s'", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("leader election: %v", err)
+	}
+
+	e.elector = elector
+	return nil
+}
+
+// Start runs the election loop in background.
+func (e *LeaderElector) Start() {
+	log.Infof("Leader election: '%s' is waiting for lease %s/%s", e.Identity, e.Namespace, e.LeaseName)
+	e.done = make(chan struct{})
+	go func() {
+		defer close(e.done)
+		e.elector.Run(e.ctx)
+	}()
+}
+
+// Stop stops the election loop and releases the lease if this replica is a leader.
+func (e *LeaderElector) Stop() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	if e.done != nil {
+		<-e.done
+	}
+}
@@ -0,0 +1,83 @@
+package leader_election
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+func Test_LeaderElector_Callbacks(t *testing.T) {
+	g := NewWithT(t)
+
+	client := kube.NewFakeKubernetesClient()
+
+	var started, stopped int32
+	e := NewLeaderElector()
+	e.WithContext(context.Background())
+	e.WithKubeClient(client)
+	e.WithLease("default", "shell-operator")
+	e.WithIdentity("replica-1")
+	e.WithTimings(time.Second, 500*time.Millisecond, 100*time.Millisecond)
+	e.WithCallbacks(func(ctx context.Context) {
+		atomic.AddInt32(&started, 1)
+	}, func() {
+		atomic.AddInt32(&stopped, 1)
+	})
+	g.Expect(e.Init()).Should(Succeed())
+	e.Start()
+	defer e.Stop()
+
+	g.Eventually(e.IsLeader, 5*time.Second, 50*time.Millisecond).Should(BeTrue())
+	g.Expect(atomic.LoadInt32(&started)).Should(Equal(int32(1)))
+
+	// Another replica takes the Lease: renew fails and OnStoppedLeading is called.
+	lease, err := client.CoordinationV1().Leases("default").Get("shell-operator", metav1.GetOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	holder := "replica-2"
+	duration := int32(60)
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = client.CoordinationV1().Leases("default").Update(lease)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Eventually(func() int32 { return atomic.LoadInt32(&stopped) }, 5*time.Second, 50*time.Millisecond).Should(Equal(int32(1)))
+	g.Expect(e.IsLeader()).Should(BeFalse())
+}
+
+func Test_LeaderElector_StopReleasesLease(t *testing.T) {
+	g := NewWithT(t)
+
+	client := kube.NewFakeKubernetesClient()
+
+	var stopped int32
+	e := NewLeaderElector()
+	e.WithContext(context.Background())
+	e.WithKubeClient(client)
+	e.WithLease("default", "shell-operator")
+	e.WithIdentity("replica-1")
+	e.WithTimings(time.Second, 500*time.Millisecond, 100*time.Millisecond)
+	e.WithCallbacks(nil, func() {
+		atomic.AddInt32(&stopped, 1)
+	})
+	g.Expect(e.Init()).Should(Succeed())
+	e.Start()
+
+	g.Eventually(e.IsLeader, 5*time.Second, 50*time.Millisecond).Should(BeTrue())
+	e.Stop()
+
+	// Lease is released for a fast failover, OnStoppedLeading is not called on Stop.
+	lease, err := client.CoordinationV1().Leases("default").Get("shell-operator", metav1.GetOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(lease.Spec.HolderIdentity).ShouldNot(BeNil())
+	g.Expect(*lease.Spec.HolderIdentity).Should(BeEmpty())
+	g.Expect(atomic.LoadInt32(&stopped)).Should(Equal(int32(0)))
+	g.Expect(e.IsLeader()).Should(BeFalse())
+}
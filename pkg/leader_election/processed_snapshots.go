@@ -0,0 +1,223 @@
+package leader_election
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/kube"
+	"github.com/flant/shell-operator/pkg/utils/checksum"
+)
+
+const ProcessedSnapshotsKey = "checksums.json"
+
+// ProcessedSnapshots tracks objects of 'kubernetes' bindings that were passed to hooks by the leader.
+// Checksums of these snapshots are saved into a ConfigMap, so a new leader can skip
+// Synchronization for bindings if the snapshot in its informers has the same checksum.
+type ProcessedSnapshots struct {
+	KubeClient    kube.KubernetesClient
+	Namespace     string
+	ConfigMapName string
+
+	m sync.Mutex
+	// Object checksums by resource id for each binding.
+	objects map[string]map[string]string
+	changed bool
+	// Snapshot checksums loaded from the ConfigMap.
+	recorded map[string]string
+}
+
+func NewProcessedSnapshots() *ProcessedSnapshots {
+	return &ProcessedSnapshots{
+		objects:  make(map[string]map[string]string),
+		recorded: make(map[string]string),
+	}
+}
+
+func (p *ProcessedSnapshots) WithKubeClient(client kube.KubernetesClient) {
+	p.KubeClient = client
+}
+
+func (p *ProcessedSnapshots) WithConfigMap(namespace string, name string) {
+	p.Namespace = namespace
+	p.ConfigMapName = name
+}
+
+// Reset replaces objects for the binding, e.g. after Synchronization.
+func (p *ProcessedSnapshots) Reset(hookName string, bindingName string, objects []ObjectAndFilterResult) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	bindingObjects := make(map[string]string)
+	for _, obj := range objects {
+		bindingObjects[obj.Metadata.ResourceId] = obj.Metadata.Checksum
+	}
+	p.objects[snapshotKey(hookName, bindingName)] = bindingObjects
+	p.changed = true
+}
+
+// Update applies a watch event to objects of the binding. Events are ignored
+// until Reset is called for the binding.
+func (p *ProcessedSnapshots) Update(hookName string, bindingName string, watchEvent WatchEventType, objects []ObjectAndFilterResult) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	bindingObjects, ok := p.objects[snapshotKey(hookName, bindingName)]
+	if !ok {
+		return
+	}
+	for _, obj := range objects {
+		if watchEvent == WatchEventDeleted {
+			delete(bindingObjects, obj.Metadata.ResourceId)
+		} else {
+			bindingObjects[obj.Metadata.ResourceId] = obj.Metadata.Checksum
+		}
+	}
+	p.changed = true
+}
+
+// Forget removes objects and recorded checksums for all bindings of the hook, e.g. when hook is changed.
+func (p *ProcessedSnapshots) Forget(hookName string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	prefix := snapshotKey(hookName, "")
+	for key := range p.objects {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.objects, key)
+			p.changed = true
+		}
+	}
+	for key := range p.recorded {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.recorded, key)
+		}
+	}
+}
+
+// Checksums returns snapshot checksums for all tracked bindings.
+func (p *ProcessedSnapshots) Checksums() map[string]string {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	res := make(map[string]string)
+	for key, bindingObjects := range p.objects {
+		res[key] = objectsChecksum(bindingObjects)
+	}
+	return res
+}
+
+// IsRecorded returns true if the previous leader has saved the same snapshot checksum for the binding.
+func (p *ProcessedSnapshots) IsRecorded(hookName string, bindingName string, objects []ObjectAndFilterResult) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	recorded, ok := p.recorded[snapshotKey(hookName, bindingName)]
+	if !ok {
+		return false
+	}
+	return recorded == SnapshotChecksum(objects)
+}
+
+// Load reads checksums saved by the previous leader. Absent ConfigMap is not an error.
+func (p *ProcessedSnapshots) Load() error {
+	cm, err := p.KubeClient.CoreV1().ConfigMaps(p.Namespace).Get(p.ConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get ConfigMap %s/%s: %v", p.Namespace, p.ConfigMapName, err)
+	}
+
+	recorded := make(map[string]string)
+	if data, has := cm.Data[ProcessedSnapshotsKey]; has {
+		err = json.Unmarshal([]byte(data), &recorded)
+		if err != nil {
+			return fmt.Errorf("parse ConfigMap %s/%s: %v", p.Namespace, p.ConfigMapName, err)
+		}
+	}
+
+	p.m.Lock()
+	p.recorded = recorded
+	p.m.Unlock()
+	return nil
+}
+
+// Save writes snapshot checksums into the ConfigMap if objects were changed since the last save.
+func (p *ProcessedSnapshots) Save() error {
+	p.m.Lock()
+	changed := p.changed
+	p.changed = false
+	p.m.Unlock()
+	if !changed {
+		return nil
+	}
+
+	data, err := json.Marshal(p.Checksums())
+	if err != nil {
+		return err
+	}
+
+	err = p.save(string(data))
+	if err != nil {
+		// Try again on the next call.
+		p.m.Lock()
+		p.changed = true
+		p.m.Unlock()
+	}
+	return err
+}
+
+func (p *ProcessedSnapshots) save(data string) error {
+	configMaps := p.KubeClient.CoreV1().ConfigMaps(p.Namespace)
+
+	cm, err := configMaps.Get(p.ConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      p.ConfigMapName,
+				Namespace: p.Namespace,
+			},
+			Data: map[string]string{ProcessedSnapshotsKey: data},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[ProcessedSnapshotsKey] = data
+	_, err = configMaps.Update(cm)
+	return err
+}
+
+// SnapshotChecksum calculates a checksum of objects regardless of their order.
+func SnapshotChecksum(objects []ObjectAndFilterResult) string {
+	bindingObjects := make(map[string]string)
+	for _, obj := range objects {
+		bindingObjects[obj.Metadata.ResourceId] = obj.Metadata.Checksum
+	}
+	return objectsChecksum(bindingObjects)
+}
+
+func objectsChecksum(bindingObjects map[string]string) string {
+	values := make([]string, 0, len(bindingObjects))
+	for id, sum := range bindingObjects {
+		values = append(values, id+"="+sum)
+	}
+	return checksum.CalculateChecksum(values...)
+}
+
+func snapshotKey(hookName string, bindingName string) string {
+	return hookName + "/" + bindingName
+}
@@ -0,0 +1,64 @@
+package leader_election
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+func obj(id string, sum string) ObjectAndFilterResult {
+	res := ObjectAndFilterResult{}
+	res.Metadata.ResourceId = id
+	res.Metadata.Checksum = sum
+	return res
+}
+
+func Test_ProcessedSnapshots_SaveAndLoad(t *testing.T) {
+	g := NewWithT(t)
+
+	client := kube.NewFakeKubernetesClient()
+
+	leader := NewProcessedSnapshots()
+	leader.WithKubeClient(client)
+	leader.WithConfigMap("default", "shell-operator-snapshots")
+
+	// Events before Synchronization are ignored.
+	leader.Update("hook.sh", "pods", WatchEventAdded, []ObjectAndFilterResult{obj("pod-a", "1")})
+	g.Expect(leader.Checksums()).Should(BeEmpty())
+
+	leader.Reset("hook.sh", "pods", []ObjectAndFilterResult{obj("pod-a", "1"), obj("pod-b", "1")})
+	leader.Update("hook.sh", "pods", WatchEventModified, []ObjectAndFilterResult{obj("pod-b", "2")})
+	leader.Update("hook.sh", "pods", WatchEventDeleted, []ObjectAndFilterResult{obj("pod-a", "1")})
+	leader.Update("hook.sh", "pods", WatchEventAdded, []ObjectAndFilterResult{obj("pod-c", "1")})
+	g.Expect(leader.Save()).Should(Succeed())
+
+	standby := NewProcessedSnapshots()
+	standby.WithKubeClient(client)
+	standby.WithConfigMap("default", "shell-operator-snapshots")
+	g.Expect(standby.Load()).Should(Succeed())
+
+	g.Expect(standby.IsRecorded("hook.sh", "pods", []ObjectAndFilterResult{obj("pod-c", "1"), obj("pod-b", "2")})).Should(BeTrue())
+	g.Expect(standby.IsRecorded("hook.sh", "pods", []ObjectAndFilterResult{obj("pod-c", "1"), obj("pod-b", "1")})).Should(BeFalse())
+	g.Expect(standby.IsRecorded("hook.sh", "nodes", []ObjectAndFilterResult{})).Should(BeFalse())
+
+	// Update an existing ConfigMap.
+	leader.Reset("hook.sh", "nodes", []ObjectAndFilterResult{})
+	g.Expect(leader.Save()).Should(Succeed())
+	g.Expect(standby.Load()).Should(Succeed())
+	g.Expect(standby.IsRecorded("hook.sh", "nodes", []ObjectAndFilterResult{})).Should(BeTrue())
+}
+
+func Test_ProcessedSnapshots_LoadWithoutConfigMap(t *testing.T) {
+	g := NewWithT(t)
+
+	p := NewProcessedSnapshots()
+	p.WithKubeClient(kube.NewFakeKubernetesClient())
+	p.WithConfigMap("default", "shell-operator-snapshots")
+
+	g.Expect(p.Load()).Should(Succeed())
+	g.Expect(p.IsRecorded("hook.sh", "pods", []ObjectAndFilterResult{})).Should(BeFalse())
+}
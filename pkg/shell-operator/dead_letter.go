@@ -0,0 +1,284 @@
+package shell_operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/kube/event_recorder"
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/dead_letter"
+	"github.com/flant/shell-operator/pkg/task/queue"
+	"github.com/flant/shell-operator/pkg/utils/exponential_backoff"
+)
+
+// MaxDroppedTasks is a number of the last dropped tasks to show in the debug server.
+const MaxDroppedTasks = 100
+
+// IsRetryExhausted returns true if the failed task has no more attempts.
+func IsRetryExhausted(retry *RetryPolicy, t task.Task) bool {
+	return retry != nil && retry.MaxAttempts > 0 && t.GetFailureCount()+1 >= retry.MaxAttempts
+}
+
+// RetryDelay returns a delay before the next attempt or 0 to use the queue default.
+func RetryDelay(retry *RetryPolicy, t task.Task) time.Duration {
+	if retry == nil || (retry.InitialDelay == 0 && retry.MaxDelay == 0) {
+		return 0
+	}
+	initialDelay := retry.InitialDelay
+	if initialDelay == 0 {
+		initialDelay = queue.DelayOnFailedTask
+	}
+	maxDelay := retry.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = exponential_backoff.MaxExponentialBackoffDelay
+	}
+	// Default initial delay should not exceed maxDelay.
+	if initialDelay > maxDelay {
+		initialDelay = maxDelay
+	}
+	return exponential_backoff.CalculateDelayWithMax(initialDelay, maxDelay, t.GetFailureCount())
+}
+
+// HandleExhaustedTask saves the task into the dead letter store or into the list of dropped tasks.
+// The task is removed from the queue by the caller.
+func (op *ShellOperator) HandleExhaustedTask(t task.Task, retry *RetryPolicy, err error) {
+	hookMeta := HookMetadataAccessor(t)
+	t.UpdateFailureMessage(err.Error())
+	t.IncrementFailureCount()
+
+	if retry.OnExhausted == ExhaustedDeadLetter && op.DeadLetter != nil {
+		op.DeadLetter.Add(t, err.Error())
+	} else if op.DroppedTasks != nil {
+		op.DroppedTasks.Add(t, err.Error())
+	}
+	op.RecordHookEvent(hookMeta, event_recorder.ReasonHookRetriesExhausted,
+		fmt.Sprintf("failed %d times, give up: %s", t.GetFailureCount(), err))
+
+	op.MetricStorage.CounterAdd("{PREFIX}tasks_exhausted_total", 1.0, map[string]string{
+		"hook":    hookMeta.HookName,
+		"binding": hookMeta.Binding,
+		"queue":   t.GetQueueName(),
+		"action":  retry.OnExhausted,
+	})
+}
+
+// ReplayDeadLetter adds a copy of the dead letter task to the tail of its queue
+// and removes the entry from the dead letter store. The current queue policy
+// of the binding is applied as for a new event.
+func (op *ShellOperator) ReplayDeadLetter(entry *dead_letter.Entry) (task.Task, error) {
+	hookMeta := HookMetadataAccessor(entry.Task)
+	h := op.HookManager.GetHook(hookMeta.HookName)
+	if h == nil {
+		return nil, fmt.Errorf("hook '%s' is not found", hookMeta.HookName)
+	}
+	hookMeta.QueuePolicy = h.Config.BindingQueuePolicy(hookMeta.Binding)
+	q := op.TaskQueues.GetByName(entry.QueueName)
+	if q == nil {
+		return nil, fmt.Errorf("queue '%s' is not found", entry.QueueName)
+	}
+
+	newTask := task.NewTask(entry.Task.GetType()).
+		WithMetadata(hookMeta).
+		WithQueueName(entry.QueueName).
+		WithLogLabels(entry.Task.GetLogLabels()).
+		WithQueuedAt(time.Now())
+	AddLastWithQueuePolicy(q, newTask)
+	op.DeadLetter.Remove(entry.Id)
+	log.Infof("Replay dead letter task %s as %s", entry.Id, newTask.GetDescription())
+	return newTask, nil
+}
+
+func (op *ShellOperator) hasHook(name string) bool {
+	for _, hookName := range op.HookManager.GetHookNames() {
+		if hookName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (op *ShellOperator) SetupDeadLetterHandles() {
+	op.DebugServer.Router.Get("/queue/dlq/list.{format:(json|yaml|text)}", func(writer http.ResponseWriter, request *http.Request) {
+		format := chi.URLParam(request, "format")
+		data, err := DeadLetterToFormat(op.DeadLetter.List(), format)
+		if err != nil {
+			log.Errorf("Dump dead letter queue: %v", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	})
+
+	op.DebugServer.Router.Get("/queue/dropped/list.{format:(json|yaml|text)}", func(writer http.ResponseWriter, request *http.Request) {
+		format := chi.URLParam(request, "format")
+		data, err := DroppedTasksToFormat(op.DroppedTasks.List(), format)
+		if err != nil {
+			log.Errorf("Dump dropped tasks: %v", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	})
+
+	op.DebugServer.Router.Get("/queue/dlq/task/{id}.{format:(json|yaml|text)}", func(writer http.ResponseWriter, request *http.Request) {
+		id := chi.URLParam(request, "id")
+		format := chi.URLParam(request, "format")
+		entry := op.DeadLetter.Get(id)
+		if entry == nil {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(writer, "Task '%s' is not found in dead letter queue\n", id)
+			return
+		}
+		data, err := DeadLetterEntryToFormat(entry, format)
+		if err != nil {
+			log.Errorf("Dump dead letter task: %v", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	})
+
+	replayHandler := func(writer http.ResponseWriter, request *http.Request) {
+		entries := op.deadLetterEntries(writer, chi.URLParam(request, "id"))
+		for _, entry := range entries {
+			newTask, err := op.ReplayDeadLetter(entry)
+			if err != nil {
+				_, _ = fmt.Fprintf(writer, "Task '%s' is not replayed: %v\n", entry.Id, err)
+				continue
+			}
+			_, _ = fmt.Fprintf(writer, "Task '%s' is queued as '%s' in queue '%s'\n", entry.Id, newTask.GetId(), entry.QueueName)
+		}
+	}
+	op.DebugServer.Router.Post("/queue/dlq/replay", replayHandler)
+	op.DebugServer.Router.Post("/queue/dlq/replay/{id}", replayHandler)
+
+	op.DebugServer.Router.Post("/queue/dlq/purge", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprintf(writer, "%d tasks are removed from dead letter queue\n", op.DeadLetter.Purge())
+	})
+	op.DebugServer.Router.Post("/queue/dlq/purge/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		id := chi.URLParam(request, "id")
+		if op.DeadLetter.Remove(id) == nil {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(writer, "Task '%s' is not found in dead letter queue\n", id)
+			return
+		}
+		_, _ = fmt.Fprintf(writer, "Task '%s' is removed from dead letter queue\n", id)
+	})
+}
+
+// deadLetterEntries returns an entry by id or all entries if id is empty.
+func (op *ShellOperator) deadLetterEntries(writer http.ResponseWriter, id string) []*dead_letter.Entry {
+	if id == "" {
+		return op.DeadLetter.List()
+	}
+	entry := op.DeadLetter.Get(id)
+	if entry == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(writer, "Task '%s' is not found in dead letter queue\n", id)
+		return nil
+	}
+	return []*dead_letter.Entry{entry}
+}
+
+type deadLetterInfo struct {
+	Id             string             `json:"id"`
+	Queue          string             `json:"queue"`
+	Hook           string             `json:"hook"`
+	Binding        string             `json:"binding"`
+	Description    string             `json:"description"`
+	FailureMessage string             `json:"failureMessage"`
+	FailureCount   int                `json:"failureCount"`
+	QueuedAt       time.Time          `json:"queuedAt"`
+	ExhaustedAt    time.Time          `json:"exhaustedAt"`
+	BindingContext BindingContextList `json:"bindingContext,omitempty"`
+}
+
+func newDeadLetterInfo(entry *dead_letter.Entry) deadLetterInfo {
+	hookMeta := HookMetadataAccessor(entry.Task)
+	return deadLetterInfo{
+		Id:             entry.Id,
+		Queue:          entry.QueueName,
+		Hook:           hookMeta.HookName,
+		Binding:        hookMeta.Binding,
+		Description:    entry.Task.GetDescription(),
+		FailureMessage: entry.FailureMessage,
+		FailureCount:   entry.FailureCount,
+		QueuedAt:       entry.QueuedAt,
+		ExhaustedAt:    entry.ExhaustedAt,
+	}
+}
+
+// DeadLetterToFormat dumps dead letter entries as json, yaml or text.
+func DeadLetterToFormat(entries []*dead_letter.Entry, format string) ([]byte, error) {
+	return exhaustedTasksToFormat("Dead letter queue", entries, format)
+}
+
+// DroppedTasksToFormat dumps the last dropped tasks as json, yaml or text.
+func DroppedTasksToFormat(entries []*dead_letter.Entry, format string) ([]byte, error) {
+	return exhaustedTasksToFormat("Dropped tasks", entries, format)
+}
+
+func exhaustedTasksToFormat(title string, entries []*dead_letter.Entry, format string) ([]byte, error) {
+	infos := make([]deadLetterInfo, 0, len(entries))
+	for _, entry := range entries {
+		infos = append(infos, newDeadLetterInfo(entry))
+	}
+
+	switch format {
+	case "json":
+		return json.Marshal(infos)
+	case "yaml":
+		return yaml.Marshal(infos)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("%s: %d tasks\n", title, len(infos)))
+	for _, info := range infos {
+		buf.WriteString(fmt.Sprintf("\n%s %s\n  queue: %s, failures: %d, exhausted at %s\n  %s\n",
+			info.Id, info.Description, info.Queue, info.FailureCount, info.ExhaustedAt.Format(time.RFC3339), info.FailureMessage))
+	}
+	return []byte(buf.String()), nil
+}
+
+// DeadLetterEntryToFormat dumps a dead letter entry with a binding context as json, yaml or text.
+func DeadLetterEntryToFormat(entry *dead_letter.Entry, format string) ([]byte, error) {
+	info := newDeadLetterInfo(entry)
+	info.BindingContext = ConvertBindingContextList("v1", HookMetadataAccessor(entry.Task).BindingContext)
+
+	switch format {
+	case "json":
+		return json.Marshal(info)
+	case "yaml":
+		return yaml.Marshal(info)
+	}
+
+	bindingContext, err := info.BindingContext.Json()
+	if err != nil {
+		return nil, err
+	}
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("Id: %s\n", info.Id))
+	buf.WriteString(fmt.Sprintf("Queue: %s\n", info.Queue))
+	buf.WriteString(fmt.Sprintf("Hook: %s\n", info.Hook))
+	buf.WriteString(fmt.Sprintf("Binding: %s\n", info.Binding))
+	buf.WriteString(fmt.Sprintf("Failure count: %d\n", info.FailureCount))
+	buf.WriteString(fmt.Sprintf("Queued at: %s\n", info.QueuedAt.Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("Exhausted at: %s\n", info.ExhaustedAt.Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("Failure message: %s\n", info.FailureMessage))
+	buf.WriteString(fmt.Sprintf("Binding context:\n%s\n", bindingContext))
+	return []byte(buf.String()), nil
+}
@@ -0,0 +1,190 @@
+package shell_operator
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/dead_letter"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+// failedTask returns a task of the 'schedule' binding with the failure count.
+func failedTask(failures int) task.Task {
+	t := testHookRunTask("hook.sh", testBindingContext("every-minute", Schedule))
+	for i := 0; i < failures; i++ {
+		t.IncrementFailureCount()
+	}
+	return t
+}
+
+func Test_IsRetryExhausted(t *testing.T) {
+	tests := []struct {
+		name      string
+		retry     *RetryPolicy
+		failures  int
+		exhausted bool
+	}{
+		{"no policy", nil, 100, false},
+		{"no limit", &RetryPolicy{MaxAttempts: 0}, 100, false},
+		{"first run of 3", &RetryPolicy{MaxAttempts: 3}, 0, false},
+		{"second run of 3", &RetryPolicy{MaxAttempts: 3}, 1, false},
+		{"third run of 3", &RetryPolicy{MaxAttempts: 3}, 2, true},
+		{"single attempt", &RetryPolicy{MaxAttempts: 1}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(IsRetryExhausted(tt.retry, failedTask(tt.failures))).Should(Equal(tt.exhausted))
+		})
+	}
+}
+
+func Test_RetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		retry    *RetryPolicy
+		failures int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{"no policy", nil, 3, 0, 0},
+		{"queue defaults", &RetryPolicy{MaxAttempts: 3}, 3, 0, 0},
+		{"first retry", &RetryPolicy{InitialDelay: 10 * time.Second}, 0, 10 * time.Second, 10 * time.Second},
+		{"default initial delay", &RetryPolicy{MaxDelay: time.Minute}, 0, queue.DelayOnFailedTask, queue.DelayOnFailedTask},
+		{"initial delay is bounded by maxDelay", &RetryPolicy{MaxDelay: time.Second}, 0, time.Second, time.Second},
+		{"exponential delay", &RetryPolicy{InitialDelay: 10 * time.Second, MaxDelay: time.Minute}, 3, 14 * time.Second, 15 * time.Second},
+		{"max delay", &RetryPolicy{InitialDelay: 10 * time.Second, MaxDelay: 20 * time.Second}, 10, 20 * time.Second, 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			delay := RetryDelay(tt.retry, failedTask(tt.failures))
+			g.Expect(delay).Should(BeNumerically(">=", tt.min))
+			g.Expect(delay).Should(BeNumerically("<=", tt.max))
+		})
+	}
+}
+
+func Test_HandleExhaustedTask(t *testing.T) {
+	tests := []struct {
+		name          string
+		onExhausted   string
+		hasDeadLetter bool
+		deadLetters   int
+		dropped       int
+	}{
+		{"drop", ExhaustedDrop, true, 0, 1},
+		{"dead letter", ExhaustedDeadLetter, true, 1, 0},
+		{"dead letter is disabled", ExhaustedDeadLetter, false, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			op := NewShellOperator()
+			op.DroppedTasks = dead_letter.NewStore().WithMaxEntries(MaxDroppedTasks)
+			if tt.hasDeadLetter {
+				op.DeadLetter = dead_letter.NewStore()
+			}
+
+			tsk := failedTask(2)
+			op.HandleExhaustedTask(tsk, &RetryPolicy{MaxAttempts: 3, OnExhausted: tt.onExhausted}, fmt.Errorf("hook failed"))
+
+			g.Expect(tsk.GetFailureCount()).Should(Equal(3))
+			g.Expect(op.DroppedTasks.Length()).Should(Equal(tt.dropped))
+			if op.DeadLetter != nil {
+				g.Expect(op.DeadLetter.Length()).Should(Equal(tt.deadLetters))
+			}
+
+			var entries []*dead_letter.Entry
+			entries = append(entries, op.DroppedTasks.List()...)
+			if op.DeadLetter != nil {
+				entries = append(entries, op.DeadLetter.List()...)
+			}
+			g.Expect(entries).Should(HaveLen(1))
+			g.Expect(entries[0].Id).Should(Equal(tsk.GetId()))
+			g.Expect(entries[0].FailureCount).Should(Equal(3))
+			g.Expect(entries[0].FailureMessage).Should(Equal("hook failed"))
+		})
+	}
+}
+
+func Test_HookRunStopCombineFn_RetryPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	retry := &RetryPolicy{MaxAttempts: 3, OnExhausted: ExhaustedDrop}
+	taskHook := hook.NewHook("hook.sh", "hook.sh")
+	taskHook.Config = &hook.HookConfig{
+		Schedules: []ScheduleConfig{
+			{CommonBindingConfig: CommonBindingConfig{BindingName: "limited"}, Retry: retry},
+			{CommonBindingConfig: CommonBindingConfig{BindingName: "also-limited"}, Retry: &RetryPolicy{MaxAttempts: 3, OnExhausted: ExhaustedDrop}},
+			{CommonBindingConfig: CommonBindingConfig{BindingName: "infinite"}},
+		},
+	}
+
+	op := NewShellOperator()
+	op.TaskQueues = queue.NewTaskQueueSet()
+	op.TaskQueues.WithContext(context.Background())
+	op.TaskQueues.NewNamedQueue("main", nil)
+	q := op.TaskQueues.GetByName("main")
+
+	newTask := func(binding string) task.Task {
+		return testHookRunTask("hook.sh", testBindingContext(binding, Schedule))
+	}
+	head := newTask("limited")
+	for _, tsk := range []task.Task{head, newTask("also-limited"), newTask("infinite"), newTask("limited")} {
+		q.AddLast(tsk)
+	}
+
+	// Tasks with equal policies are combined, the task without a policy stops combining.
+	bcs := op.CombineBindingContextForHook(q, head, op.hookRunStopCombineFn(taskHook, HookMetadataAccessor(head)))
+	g.Expect(bcs).Should(HaveLen(2))
+	g.Expect(bcs[0].Binding).Should(Equal("limited"))
+	g.Expect(bcs[1].Binding).Should(Equal("also-limited"))
+	g.Expect(q.Length()).Should(Equal(3))
+}
+
+func Test_ReplayDeadLetter_QueuePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "replay_dead_letter")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	op := NewShellOperator()
+	op.HookManager = initHookManager(g, tmpDir, `{"configVersion":"v1","kubernetes":[{"name":"pods","apiVersion":"v1","kind":"Pod","queuePolicy":{"dedupe":"byObject"}}]}`)
+	op.DeadLetter = dead_letter.NewStore()
+	op.TaskQueues = queue.NewTaskQueueSet()
+	op.TaskQueues.WithContext(context.Background())
+	op.TaskQueues.NewNamedQueue("main", nil)
+	q := op.TaskQueues.GetByName("main")
+
+	// The head task is running.
+	q.AddLast(testEventTask("hook.sh", "pods", "default/Pod/pod-0", nil))
+	q.AddLast(testEventTask("hook.sh", "pods", "default/Pod/pod-1", nil))
+	q.AddLast(testEventTask("hook.sh", "pods", "default/Pod/pod-2", nil))
+
+	// Tasks in the dead letter queue have no queue policy after restart.
+	entry := op.DeadLetter.Add(testEventTask("hook.sh", "pods", "default/Pod/pod-1", nil), "hook failed")
+	entry.QueueName = "main"
+
+	_, err = op.ReplayDeadLetter(entry)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(op.DeadLetter.Length()).Should(Equal(0))
+	// The replayed event replaces the pending event for the same object.
+	g.Expect(queuedResourceIds(q)).Should(Equal([]string{"default/Pod/pod-0", "default/Pod/pod-2", "default/Pod/pod-1"}))
+}
@@ -0,0 +1,136 @@
+package shell_operator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/app"
+	"github.com/flant/shell-operator/pkg/kube/event_recorder"
+)
+
+// MaxEventObjects limits a number of objects that get an Event for one hook run.
+var MaxEventObjects = 10
+
+// InitEventRecorder creates an EventRecorder if publishing of Kubernetes Events is enabled.
+func (op *ShellOperator) InitEventRecorder() error {
+	if !app.RecordEvents {
+		return nil
+	}
+
+	namespace := app.Namespace
+	if namespace == "" {
+		namespace = op.KubeClient.DefaultNamespace()
+	}
+
+	op.EventRecorder = event_recorder.NewEventRecorder()
+	op.EventRecorder.WithKubeClient(op.KubeClient)
+	op.EventRecorder.WithPod(namespace, app.PodName)
+	return op.EventRecorder.Init()
+}
+
+// RecordHookEvent publishes a Warning Event for objects from 'Event' binding contexts
+// or for the operator Pod if the hook run has no such objects (schedule, onStartup, Synchronization).
+func (op *ShellOperator) RecordHookEvent(hookMeta HookMetadata, reason string, message string) {
+	if op.EventRecorder == nil {
+		return
+	}
+	refs := InvolvedObjectRefs(hookMeta.BindingContext)
+	if len(refs) == 0 {
+		refs = append(refs, nil)
+	}
+	for _, ref := range refs {
+		op.EventRecorder.Eventf(ref, corev1.EventTypeWarning, reason, "Hook '%s' binding '%s': %s", hookMeta.HookName, hookMeta.Binding, message)
+	}
+}
+
+// RecordAdmissionDenied publishes a Warning Event for the object rejected by a webhook hook.
+// Objects that do not exist yet (e.g. on CREATE) are reported on the operator Pod.
+func (op *ShellOperator) RecordAdmissionDenied(hookName string, review *admissionv1.AdmissionReview, message string) {
+	if op.EventRecorder == nil || review == nil || review.Request == nil {
+		return
+	}
+	req := review.Request
+	op.EventRecorder.Eventf(AdmissionObjectRef(req), corev1.EventTypeWarning, event_recorder.ReasonAdmissionDenied,
+		"Hook '%s' denied %s of %s '%s': %s", hookName, req.Operation, req.Kind.Kind, namespacedName(req.Namespace, req.Name), message)
+}
+
+// InvolvedObjectRefs returns unique references to objects from 'Event' binding contexts.
+// Synchronization contexts are skipped, they may contain all objects in the cluster.
+func InvolvedObjectRefs(bcs []BindingContext) []*corev1.ObjectReference {
+	refs := make([]*corev1.ObjectReference, 0)
+	seen := map[string]bool{}
+	for _, bc := range bcs {
+		if bc.Type != TypeEvent {
+			continue
+		}
+		for _, obj := range bc.Objects {
+			ref := obj.Metadata.ObjectRef
+			if ref.Name == "" || ref.UID == "" {
+				continue
+			}
+			key := string(ref.UID)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			refs = append(refs, &ref)
+			if len(refs) >= MaxEventObjects {
+				return refs
+			}
+		}
+	}
+	return refs
+}
+
+// AdmissionObjectRef returns a reference to the existing object from the AdmissionRequest
+// or nil if the object has no uid yet.
+func AdmissionObjectRef(req *admissionv1.AdmissionRequest) *corev1.ObjectReference {
+	uid := admissionObjectUID(req.OldObject.Raw)
+	if uid == "" {
+		uid = admissionObjectUID(req.Object.Raw)
+	}
+	if uid == "" || req.Name == "" {
+		return nil
+	}
+	apiVersion := req.Kind.Version
+	if req.Kind.Group != "" {
+		apiVersion = req.Kind.Group + "/" + req.Kind.Version
+	}
+	return &corev1.ObjectReference{
+		APIVersion: apiVersion,
+		Kind:       req.Kind.Kind,
+		Namespace:  req.Namespace,
+		Name:       req.Name,
+		UID:        uid,
+	}
+}
+
+func admissionObjectUID(raw []byte) types.UID {
+	if len(raw) == 0 {
+		return ""
+	}
+	var obj struct {
+		Metadata struct {
+			UID types.UID `json:"uid"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return ""
+	}
+	return obj.Metadata.UID
+}
+
+func namespacedName(namespace string, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
@@ -0,0 +1,56 @@
+package shell_operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+func Test_InvolvedObjectRefs(t *testing.T) {
+	g := NewWithT(t)
+
+	bcList := []BindingContext{
+		{Type: TypeSynchronization, Objects: []ObjectAndFilterResult{testObjectWithRef("Pod", "sync", "uid-0")}},
+		{Type: TypeEvent, Objects: []ObjectAndFilterResult{testObjectWithRef("Pod", "pod-a", "uid-a")}},
+		{Type: TypeEvent, Objects: []ObjectAndFilterResult{testObjectWithRef("Pod", "pod-a", "uid-a")}},
+		{Type: TypeEvent, Objects: []ObjectAndFilterResult{testObjectWithRef("Pod", "pod-b", "uid-b")}},
+		// Manual runs have no references.
+		{Type: TypeEvent, Objects: []ObjectAndFilterResult{{}}},
+	}
+
+	refs := InvolvedObjectRefs(bcList)
+	g.Expect(refs).Should(HaveLen(2))
+	g.Expect(refs[0].Name).Should(Equal("pod-a"))
+	g.Expect(refs[1].Name).Should(Equal("pod-b"))
+
+	g.Expect(InvolvedObjectRefs([]BindingContext{{Binding: "every-minute"}})).Should(BeEmpty())
+}
+
+func Test_AdmissionObjectRef(t *testing.T) {
+	g := NewWithT(t)
+
+	req := &admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Namespace: "app",
+		Name:      "web",
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"web","uid":"dep-uid"}}`)},
+	}
+	ref := AdmissionObjectRef(req)
+	g.Expect(ref).ShouldNot(BeNil())
+	g.Expect(ref.APIVersion).Should(Equal("apps/v1"))
+	g.Expect(ref.Kind).Should(Equal("Deployment"))
+	g.Expect(ref.Namespace).Should(Equal("app"))
+	g.Expect(ref.UID).Should(BeEquivalentTo("dep-uid"))
+
+	// A new object has no uid, the Event goes to the operator Pod.
+	req.Operation = admissionv1.Create
+	req.Object.Raw = []byte(`{"metadata":{"name":"web"}}`)
+	g.Expect(AdmissionObjectRef(req)).Should(BeNil())
+}
@@ -0,0 +1,62 @@
+package shell_operator
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+// testObject returns an object from a snapshot with a resource id and a checksum.
+func testObject(resourceId string, checksum string) ObjectAndFilterResult {
+	obj := ObjectAndFilterResult{}
+	obj.Metadata.ResourceId = resourceId
+	obj.Metadata.Checksum = checksum
+	return obj
+}
+
+// testObjectWithRef returns an object with a reference to the involved object.
+func testObjectWithRef(kind string, name string, uid string) ObjectAndFilterResult {
+	obj := ObjectAndFilterResult{}
+	obj.Metadata.ObjectRef.Kind = kind
+	obj.Metadata.ObjectRef.Name = name
+	obj.Metadata.ObjectRef.UID = types.UID(uid)
+	return obj
+}
+
+// testBindingContext returns a binding context with the binding type in metadata.
+// Binding contexts of 'kubernetes' bindings have the "Event" type.
+func testBindingContext(binding string, bindingType BindingType, objects ...ObjectAndFilterResult) BindingContext {
+	bc := BindingContext{Binding: binding, Objects: objects}
+	bc.Metadata.BindingType = bindingType
+	if bindingType == OnKubernetesEvent {
+		bc.Type = TypeEvent
+	}
+	return bc
+}
+
+// testHookRunTask returns a HookRun task in the "main" queue. Binding and binding type
+// of the task are taken from the first binding context.
+func testHookRunTask(hookName string, bcs ...BindingContext) task.Task {
+	return task.NewTask(HookRun).
+		WithQueueName("main").
+		WithMetadata(HookMetadata{
+			HookName:       hookName,
+			Binding:        bcs[0].Binding,
+			BindingType:    bcs[0].Metadata.BindingType,
+			BindingContext: bcs,
+		})
+}
+
+// testEventTask returns a HookRun task with an "Event" binding context for the object.
+func testEventTask(hookName string, binding string, resourceId string, policy *QueuePolicy) task.Task {
+	t := testHookRunTask(hookName, testBindingContext(binding, OnKubernetesEvent, testObject(resourceId, "")))
+	hookMeta := HookMetadataAccessor(t)
+	hookMeta.QueuePolicy = policy
+	t.UpdateMetadata(hookMeta)
+	return t
+}
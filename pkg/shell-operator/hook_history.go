@@ -0,0 +1,175 @@
+package shell_operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+	"github.com/flant/shell-operator/pkg/hook/history"
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+// NewHookHistoryEntry returns a history entry for the HookRun task. Error is not set.
+func NewHookHistoryEntry(t task.Task, startedAt time.Time, result *hook.HookResult, err error) *history.Entry {
+	hookMeta := HookMetadataAccessor(t)
+	eventTypes, objectCount := BindingContextSummary(hookMeta.BindingContext)
+	entry := &history.Entry{
+		TaskId:      t.GetId(),
+		HookName:    hookMeta.HookName,
+		Binding:     hookMeta.Binding,
+		BindingType: string(hookMeta.BindingType),
+		Queue:       t.GetQueueName(),
+		EventTypes:  eventTypes,
+		ObjectCount: objectCount,
+		StartedAt:   startedAt,
+		Duration:    time.Since(startedAt),
+	}
+	if result != nil {
+		entry.TimedOut = result.TimedOut
+		if result.Usage != nil {
+			entry.SysTime = result.Usage.Sys
+			entry.UserTime = result.Usage.User
+			entry.MaxRss = result.Usage.MaxRss
+			entry.ExitCode = result.Usage.ExitCode
+			entry.StdoutTail = result.Usage.StdoutTail
+			entry.StderrTail = result.Usage.StderrTail
+		}
+	}
+	return entry
+}
+
+// BindingContextSummary returns unique event types and a number of objects in the binding context.
+// Event type is a watch event for "Event" contexts and a context type or a binding type for others.
+func BindingContextSummary(bcList []BindingContext) ([]string, int) {
+	eventTypes := make([]string, 0)
+	seen := map[string]bool{}
+	objectCount := 0
+	addEventType := func(eventType string) {
+		if eventType != "" && !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	for _, bc := range bcList {
+		objectCount += len(bc.Objects)
+		if bc.IsBatch() {
+			for _, watchEvent := range bc.WatchEvents {
+				addEventType(string(watchEvent))
+			}
+			continue
+		}
+		eventType := string(bc.Type)
+		switch {
+		case bc.Type == TypeEvent:
+			eventType = string(bc.WatchEvent)
+		case bc.Type == "":
+			eventType = string(bc.Metadata.BindingType)
+		}
+		addEventType(eventType)
+	}
+	return eventTypes, objectCount
+}
+
+type hookHistoryEntryInfo struct {
+	TaskId      string   `json:"taskId"`
+	Binding     string   `json:"binding"`
+	BindingType string   `json:"bindingType"`
+	Queue       string   `json:"queue"`
+	EventTypes  []string `json:"eventTypes"`
+	ObjectCount int      `json:"objectCount"`
+	StartedAt   string   `json:"startedAt"`
+	Duration    string   `json:"duration"`
+	SysTime     string   `json:"sysTime,omitempty"`
+	UserTime    string   `json:"userTime,omitempty"`
+	MaxRssBytes int64    `json:"maxRssBytes,omitempty"`
+	ExitCode    int      `json:"exitCode"`
+	TimedOut    bool     `json:"timedOut,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	StdoutTail  []string `json:"stdoutTail,omitempty"`
+	StderrTail  []string `json:"stderrTail,omitempty"`
+}
+
+func newHookHistoryEntryInfo(entry *history.Entry) hookHistoryEntryInfo {
+	info := hookHistoryEntryInfo{
+		TaskId:      entry.TaskId,
+		Binding:     entry.Binding,
+		BindingType: entry.BindingType,
+		Queue:       entry.Queue,
+		EventTypes:  entry.EventTypes,
+		ObjectCount: entry.ObjectCount,
+		StartedAt:   entry.StartedAt.Format(time.RFC3339),
+		Duration:    entry.Duration.String(),
+		MaxRssBytes: entry.MaxRss * 1024,
+		ExitCode:    entry.ExitCode,
+		TimedOut:    entry.TimedOut,
+		Error:       entry.Error,
+		StdoutTail:  entry.StdoutTail,
+		StderrTail:  entry.StderrTail,
+	}
+	if entry.SysTime != 0 || entry.UserTime != 0 {
+		info.SysTime = entry.SysTime.String()
+		info.UserTime = entry.UserTime.String()
+	}
+	return info
+}
+
+// HookHistoryToFormat dumps executions of the hook as json, yaml or text.
+func HookHistoryToFormat(hookName string, entries []*history.Entry, format string) ([]byte, error) {
+	infos := make([]hookHistoryEntryInfo, 0, len(entries))
+	for _, entry := range entries {
+		infos = append(infos, newHookHistoryEntryInfo(entry))
+	}
+
+	switch format {
+	case "json":
+		return json.Marshal(infos)
+	case "yaml":
+		return yaml.Marshal(infos)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("Hook '%s': %d executions\n", hookName, len(infos)))
+	for _, info := range infos {
+		buf.WriteString(fmt.Sprintf("\n%s %s '%s' in queue '%s', task %s\n",
+			info.StartedAt, info.BindingType, info.Binding, info.Queue, info.TaskId))
+		buf.WriteString(fmt.Sprintf("  events: %s, objects: %d, duration: %s, exit code: %d\n",
+			strings.Join(info.EventTypes, ","), info.ObjectCount, info.Duration, info.ExitCode))
+		if info.SysTime != "" {
+			buf.WriteString(fmt.Sprintf("  sys: %s, user: %s, max rss: %d bytes\n", info.SysTime, info.UserTime, info.MaxRssBytes))
+		}
+		if info.Error != "" {
+			buf.WriteString(fmt.Sprintf("  error: %s\n", info.Error))
+		}
+		for _, line := range info.StdoutTail {
+			buf.WriteString(fmt.Sprintf("  stdout| %s\n", line))
+		}
+		for _, line := range info.StderrTail {
+			buf.WriteString(fmt.Sprintf("  stderr| %s\n", line))
+		}
+	}
+	return []byte(buf.String()), nil
+}
+
+func (op *ShellOperator) SetupHookHistoryHandles() {
+	op.DebugServer.Router.Get("/hook/{name}/history.{format:(json|yaml|text)}", op.withHook(func(writer http.ResponseWriter, request *http.Request, h *hook.Hook) {
+		format := chi.URLParam(request, "format")
+
+		data, err := HookHistoryToFormat(h.Name, op.HookHistory.List(h.Name), format)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	}))
+}
@@ -0,0 +1,40 @@
+package shell_operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+func Test_BindingContextSummary(t *testing.T) {
+	g := NewWithT(t)
+
+	schedule := BindingContext{Binding: "every-minute"}
+	schedule.Metadata.BindingType = Schedule
+
+	bcList := []BindingContext{
+		{Binding: "pods", Type: TypeSynchronization, Objects: make([]ObjectAndFilterResult, 3)},
+		{Binding: "pods", Type: TypeEvent, WatchEvent: WatchEventAdded, Objects: make([]ObjectAndFilterResult, 1)},
+		{Binding: "pods", Type: TypeEvent, WatchEvent: WatchEventAdded, Objects: make([]ObjectAndFilterResult, 1)},
+		schedule,
+	}
+
+	eventTypes, objectCount := BindingContextSummary(bcList)
+	g.Expect(eventTypes).Should(Equal([]string{"Synchronization", "Added", "schedule"}))
+	g.Expect(objectCount).Should(Equal(5))
+
+	// A debounced binding context.
+	bcList = append(bcList, BindingContext{
+		Binding:     "pods",
+		Type:        TypeEvent,
+		WatchEvents: []WatchEventType{WatchEventModified, WatchEventDeleted},
+		Objects:     make([]ObjectAndFilterResult, 2),
+	})
+	eventTypes, objectCount = BindingContextSummary(bcList)
+	g.Expect(eventTypes).Should(Equal([]string{"Synchronization", "Added", "schedule", "Modified", "Deleted"}))
+	g.Expect(objectCount).Should(Equal(7))
+}
@@ -0,0 +1,368 @@
+package shell_operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	v1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+)
+
+// HookInfo is an effective configuration of the hook with a runtime state of its bindings.
+type HookInfo struct {
+	Name          string        `json:"name"`
+	Path          string        `json:"path"`
+	ConfigVersion string        `json:"configVersion"`
+	Bindings      []BindingInfo `json:"bindings"`
+}
+
+// BindingInfo describes a binding. Config is a type specific part of the binding configuration.
+type BindingInfo struct {
+	Type                 BindingType      `json:"type"`
+	Name                 string           `json:"name"`
+	Queue                string           `json:"queue,omitempty"`
+	Group                string           `json:"group,omitempty"`
+	AllowFailure         bool             `json:"allowFailure"`
+	IncludeSnapshotsFrom []string         `json:"includeSnapshotsFrom,omitempty"`
+	Config               interface{}      `json:"config,omitempty"`
+	State                BindingStateInfo `json:"state"`
+}
+
+// BindingStateInfo is a runtime state of the binding. MonitorStarted is set for kubernetes
+// bindings, ScheduleEnabled is set for schedule bindings.
+type BindingStateInfo struct {
+	MonitorStarted  *bool      `json:"monitorStarted,omitempty"`
+	ScheduleEnabled *bool      `json:"scheduleEnabled,omitempty"`
+	LastRun         *time.Time `json:"lastRun,omitempty"`
+	LastError       string     `json:"lastError,omitempty"`
+	LastErrorTime   *time.Time `json:"lastErrorTime,omitempty"`
+}
+
+type onStartupConfigInfo struct {
+	Order float64 `json:"order"`
+}
+
+type scheduleConfigInfo struct {
+	Crontab     string       `json:"crontab"`
+	ScheduleId  string       `json:"scheduleId"`
+	QueuePolicy *QueuePolicy `json:"queuePolicy,omitempty"`
+	Timeout     string       `json:"timeout,omitempty"`
+	Retry       *RetryPolicy `json:"retry,omitempty"`
+}
+
+type kubernetesConfigInfo struct {
+	MonitorId                    string                `json:"monitorId"`
+	ApiVersion                   string                `json:"apiVersion,omitempty"`
+	Kind                         string                `json:"kind"`
+	EventTypes                   []WatchEventType      `json:"eventTypes"`
+	NameSelector                 *NameSelector         `json:"nameSelector,omitempty"`
+	NamespaceSelector            *NamespaceSelector    `json:"namespaceSelector,omitempty"`
+	LabelSelector                *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	FieldSelector                *FieldSelector        `json:"fieldSelector,omitempty"`
+	JqFilter                     string                `json:"jqFilter,omitempty"`
+	ExecuteHookOnSynchronization bool                  `json:"executeHookOnSynchronization"`
+	WaitForSynchronization       bool                  `json:"waitForSynchronization"`
+	KeepFullObjectsInMemory      bool                  `json:"keepFullObjectsInMemory"`
+	MetadataOnly                 bool                  `json:"metadataOnly"`
+	Debounce                     string                `json:"debounce,omitempty"`
+	MaxWait                      string                `json:"maxWait,omitempty"`
+	QueuePolicy                  *QueuePolicy          `json:"queuePolicy,omitempty"`
+	Timeout                      string                `json:"timeout,omitempty"`
+	Retry                        *RetryPolicy          `json:"retry,omitempty"`
+}
+
+type validatingConfigInfo struct {
+	WebhookId       string                `json:"webhookId"`
+	ConfigurationId string                `json:"configurationId"`
+	Webhook         *v1.ValidatingWebhook `json:"webhook,omitempty"`
+}
+
+type mutatingConfigInfo struct {
+	WebhookId       string              `json:"webhookId"`
+	ConfigurationId string              `json:"configurationId"`
+	Webhook         *v1.MutatingWebhook `json:"webhook,omitempty"`
+}
+
+type conversionConfigInfo struct {
+	CrdName   string `json:"crdName"`
+	WebhookId string `json:"webhookId"`
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// NewHookInfo returns bindings of the hook in the order of binding types.
+// Type specific configs are added if withConfig is true.
+func NewHookInfo(h *hook.Hook, withConfig bool) HookInfo {
+	info := HookInfo{
+		Name:     h.Name,
+		Path:     h.Path,
+		Bindings: make([]BindingInfo, 0),
+	}
+	if h.Config == nil {
+		return info
+	}
+	cfg := h.Config
+	info.ConfigVersion = cfg.Version
+
+	add := func(binding BindingInfo, config interface{}) {
+		if withConfig {
+			binding.Config = config
+		}
+		binding.State = newBindingStateInfo(h, binding)
+		info.Bindings = append(info.Bindings, binding)
+	}
+
+	if cfg.OnStartup != nil {
+		add(BindingInfo{
+			Type:         OnStartup,
+			Name:         cfg.OnStartup.BindingName,
+			Queue:        "main",
+			AllowFailure: cfg.OnStartup.AllowFailure,
+		}, onStartupConfigInfo{Order: cfg.OnStartup.Order})
+	}
+
+	for _, sc := range cfg.Schedules {
+		add(BindingInfo{
+			Type:                 Schedule,
+			Name:                 sc.BindingName,
+			Queue:                sc.Queue,
+			Group:                sc.Group,
+			AllowFailure:         sc.AllowFailure,
+			IncludeSnapshotsFrom: sc.IncludeSnapshotsFrom,
+		}, scheduleConfigInfo{
+			Crontab:     sc.ScheduleEntry.Crontab,
+			ScheduleId:  sc.ScheduleEntry.Id,
+			QueuePolicy: sc.QueuePolicy,
+			Timeout:     durationString(sc.Timeout),
+			Retry:       sc.Retry,
+		})
+	}
+
+	for _, kc := range cfg.OnKubernetesEvents {
+		config := kubernetesConfigInfo{
+			ExecuteHookOnSynchronization: kc.ExecuteHookOnSynchronization,
+			WaitForSynchronization:       kc.WaitForSynchronization,
+			KeepFullObjectsInMemory:      kc.KeepFullObjectsInMemory,
+			QueuePolicy:                  kc.QueuePolicy,
+			Timeout:                      durationString(kc.Timeout),
+			Retry:                        kc.Retry,
+		}
+		if kc.Monitor != nil {
+			config.MonitorId = kc.Monitor.Metadata.MonitorId
+			config.ApiVersion = kc.Monitor.ApiVersion
+			config.Kind = kc.Monitor.Kind
+			config.EventTypes = kc.Monitor.EventTypes
+			config.NameSelector = kc.Monitor.NameSelector
+			config.NamespaceSelector = kc.Monitor.NamespaceSelector
+			config.LabelSelector = kc.Monitor.LabelSelector
+			config.FieldSelector = kc.Monitor.FieldSelector
+			config.JqFilter = kc.Monitor.JqFilter
+			config.MetadataOnly = kc.Monitor.MetadataOnly
+			config.Debounce = durationString(kc.Monitor.Debounce)
+			config.MaxWait = durationString(kc.Monitor.MaxWait)
+		}
+		add(BindingInfo{
+			Type:                 OnKubernetesEvent,
+			Name:                 kc.BindingName,
+			Queue:                kc.Queue,
+			Group:                kc.Group,
+			AllowFailure:         kc.AllowFailure,
+			IncludeSnapshotsFrom: kc.IncludeSnapshotsFrom,
+		}, config)
+	}
+
+	for _, vc := range cfg.KubernetesValidating {
+		config := validatingConfigInfo{}
+		if vc.Webhook != nil {
+			config.WebhookId = vc.Webhook.Metadata.WebhookId
+			config.ConfigurationId = vc.Webhook.Metadata.ConfigurationId
+			config.Webhook = vc.Webhook.ValidatingWebhook
+		}
+		add(BindingInfo{
+			Type:                 KubernetesValidating,
+			Name:                 vc.BindingName,
+			Group:                vc.Group,
+			AllowFailure:         vc.AllowFailure,
+			IncludeSnapshotsFrom: vc.IncludeSnapshotsFrom,
+		}, config)
+	}
+
+	for _, mc := range cfg.KubernetesMutating {
+		config := mutatingConfigInfo{}
+		if mc.Webhook != nil {
+			config.WebhookId = mc.Webhook.Metadata.WebhookId
+			config.ConfigurationId = mc.Webhook.Metadata.ConfigurationId
+			config.Webhook = mc.Webhook.MutatingWebhook
+		}
+		add(BindingInfo{
+			Type:                 KubernetesMutating,
+			Name:                 mc.BindingName,
+			Group:                mc.Group,
+			AllowFailure:         mc.AllowFailure,
+			IncludeSnapshotsFrom: mc.IncludeSnapshotsFrom,
+		}, config)
+	}
+
+	for _, cc := range cfg.KubernetesConversion {
+		config := conversionConfigInfo{}
+		if cc.Webhook != nil {
+			config.CrdName = cc.Webhook.CrdName
+			config.WebhookId = cc.Webhook.Metadata.WebhookId
+		}
+		add(BindingInfo{
+			Type:                 KubernetesConversion,
+			Name:                 cc.BindingName,
+			Group:                cc.Group,
+			AllowFailure:         cc.AllowFailure,
+			IncludeSnapshotsFrom: cc.IncludeSnapshotsFrom,
+		}, config)
+	}
+
+	return info
+}
+
+func newBindingStateInfo(h *hook.Hook, binding BindingInfo) BindingStateInfo {
+	res := BindingStateInfo{}
+	if h.HookController != nil {
+		switch binding.Type {
+		case OnKubernetesEvent:
+			started := h.HookController.IsMonitorStarted(binding.Name)
+			res.MonitorStarted = &started
+		case Schedule:
+			enabled := h.HookController.IsScheduleEnabled(binding.Name)
+			res.ScheduleEnabled = &enabled
+		}
+	}
+
+	// onStartup runs are recorded by the binding type.
+	runBinding := binding.Name
+	if binding.Type == OnStartup {
+		runBinding = string(OnStartup)
+	}
+	state := h.RunState(runBinding)
+	if !state.LastRun.IsZero() {
+		res.LastRun = &state.LastRun
+	}
+	if state.LastError != "" {
+		res.LastError = state.LastError
+		res.LastErrorTime = &state.LastErrorTime
+	}
+	return res
+}
+
+// HookInventoryToText returns a short description of hooks and their bindings.
+func HookInventoryToText(infos []HookInfo) []byte {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("Hooks: %d\n", len(infos)))
+	for _, info := range infos {
+		buf.WriteString(fmt.Sprintf("\n%s (config v%s)\n", info.Name, info.ConfigVersion))
+		for _, b := range info.Bindings {
+			buf.WriteString(fmt.Sprintf("  %s '%s'", b.Type, b.Name))
+			if b.Queue != "" {
+				buf.WriteString(fmt.Sprintf(", queue: %s", b.Queue))
+			}
+			if b.Group != "" {
+				buf.WriteString(fmt.Sprintf(", group: %s", b.Group))
+			}
+			if b.State.MonitorStarted != nil {
+				buf.WriteString(fmt.Sprintf(", monitor started: %t", *b.State.MonitorStarted))
+			}
+			if b.State.ScheduleEnabled != nil {
+				buf.WriteString(fmt.Sprintf(", schedule enabled: %t", *b.State.ScheduleEnabled))
+			}
+			if b.State.LastRun != nil {
+				buf.WriteString(fmt.Sprintf(", last run: %s", b.State.LastRun.Format(time.RFC3339)))
+			}
+			buf.WriteString("\n")
+			if b.State.LastError != "" {
+				buf.WriteString(fmt.Sprintf("    last error at %s: %s\n", b.State.LastErrorTime.Format(time.RFC3339), b.State.LastError))
+			}
+		}
+	}
+	return []byte(buf.String())
+}
+
+func (op *ShellOperator) SetupHookInventoryHandles() {
+	op.DebugServer.Router.Get("/hook/list.{format:(json|yaml|text)}", func(writer http.ResponseWriter, request *http.Request) {
+		format := chi.URLParam(request, "format")
+
+		hookNames := op.HookManager.GetHookNames()
+		sort.Strings(hookNames)
+		infos := make([]HookInfo, 0, len(hookNames))
+		for _, hookName := range hookNames {
+			infos = append(infos, NewHookInfo(op.HookManager.GetHook(hookName), false))
+		}
+
+		var data []byte
+		var err error
+		switch format {
+		case "json":
+			data, err = json.Marshal(infos)
+		case "yaml":
+			data, err = yaml.Marshal(infos)
+		default:
+			data = HookInventoryToText(infos)
+		}
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	})
+
+	op.DebugServer.Router.Get("/hook/{name}/config.{format:(json|yaml)}", op.withHook(func(writer http.ResponseWriter, request *http.Request, h *hook.Hook) {
+		format := chi.URLParam(request, "format")
+		info := NewHookInfo(h, true)
+
+		var data []byte
+		var err error
+		if format == "json" {
+			data, err = json.Marshal(info)
+		} else {
+			data, err = yaml.Marshal(info)
+		}
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	}))
+}
+
+// withHook returns a handler that gets a hook by the "name" URL parameter.
+// Hook names with subdirectories are escaped.
+func (op *ShellOperator) withHook(fn func(http.ResponseWriter, *http.Request, *hook.Hook)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name, err := url.PathUnescape(chi.URLParam(request, "name"))
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(writer, "Bad hook name: %v\n", err)
+			return
+		}
+		if !op.hasHook(name) {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(writer, "hook '%s' is not found\n", name)
+			return
+		}
+		fn(writer, request, op.HookManager.GetHook(name))
+	}
+}
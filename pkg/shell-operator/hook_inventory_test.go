@@ -0,0 +1,59 @@
+package shell_operator
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+)
+
+func Test_NewHookInfo(t *testing.T) {
+	g := NewWithT(t)
+
+	h := hook.NewHook("hook.sh", "/hooks/hook.sh")
+	h.Config = &hook.HookConfig{}
+	err := h.Config.LoadAndValidate([]byte(`{
+  "configVersion": "v1",
+  "onStartup": 10,
+  "schedule": [{"name": "every-minute", "crontab": "* * * * *", "queue": "cron"}],
+  "kubernetes": [{
+    "name": "pods",
+    "kind": "Pod",
+    "group": "main",
+    "labelSelector": {"matchLabels": {"app": "web"}},
+    "jqFilter": ".metadata.labels"
+  }]
+}`))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	h.RecordRun("pods", fmt.Errorf("exit status 1"))
+	h.RecordRun(string(OnStartup), nil)
+
+	info := NewHookInfo(h, false)
+	g.Expect(info.ConfigVersion).Should(Equal("v1"))
+	g.Expect(info.Bindings).Should(HaveLen(3))
+	g.Expect(info.Bindings[0].Type).Should(Equal(OnStartup))
+	g.Expect(info.Bindings[0].State.LastRun).ShouldNot(BeNil())
+	g.Expect(info.Bindings[0].State.LastError).Should(BeEmpty())
+	g.Expect(info.Bindings[1].Queue).Should(Equal("cron"))
+	g.Expect(info.Bindings[1].State.LastRun).Should(BeNil())
+	g.Expect(info.Bindings[2].Group).Should(Equal("main"))
+	g.Expect(info.Bindings[2].Config).Should(BeNil())
+	g.Expect(info.Bindings[2].State.LastError).Should(Equal("exit status 1"))
+
+	info = NewHookInfo(h, true)
+	kubeConfig, ok := info.Bindings[2].Config.(kubernetesConfigInfo)
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(kubeConfig.Kind).Should(Equal("Pod"))
+	g.Expect(kubeConfig.JqFilter).Should(Equal(".metadata.labels"))
+	g.Expect(kubeConfig.LabelSelector.MatchLabels).Should(HaveKeyWithValue("app", "web"))
+	g.Expect(kubeConfig.MonitorId).ShouldNot(BeEmpty())
+
+	text := string(HookInventoryToText([]HookInfo{info}))
+	g.Expect(text).Should(ContainSubstring("kubernetes 'pods', queue: main, group: main"))
+	g.Expect(text).Should(ContainSubstring("last error at"))
+}
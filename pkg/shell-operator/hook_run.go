@@ -0,0 +1,310 @@
+package shell_operator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	uuid "gopkg.in/satori/go.uuid.v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+	"github.com/flant/shell-operator/pkg/hook/controller"
+	"github.com/flant/shell-operator/pkg/kube_events_manager"
+	"github.com/flant/shell-operator/pkg/task"
+	utils "github.com/flant/shell-operator/pkg/utils/labels"
+)
+
+// NewManualHookRunTask returns a HookRun task for a schedule or a kubernetes binding.
+// Binding context is built from current objects if bindingContext is empty.
+func (op *ShellOperator) NewManualHookRunTask(hookName string, bindingName string, bindingContext []BindingContext) (task.Task, error) {
+	if !op.hasHook(hookName) {
+		return nil, fmt.Errorf("hook '%s' is not found", hookName)
+	}
+	h := op.HookManager.GetHook(hookName)
+
+	logLabels := map[string]string{
+		"event.id": uuid.NewV4().String(),
+		"binding":  "manual",
+	}
+
+	var newTask task.Task
+	err := h.HookController.HandleManualRun(bindingName, bindingContext, func(info controller.BindingExecutionInfo) {
+		newTask = task.NewTask(HookRun).
+			WithMetadata(HookMetadata{
+				HookName:       h.Name,
+				BindingType:    info.BindingContext[0].Metadata.BindingType,
+				BindingContext: info.BindingContext,
+				AllowFailure:   info.AllowFailure,
+				QueuePolicy:    info.QueuePolicy,
+				Binding:        info.Binding,
+				Group:          info.Group,
+			}).
+			WithLogLabels(logLabels).
+			WithQueueName(info.QueueName).
+			WithQueuedAt(time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	if op.TaskQueues.GetByName(newTask.GetQueueName()) == nil {
+		return nil, fmt.Errorf("queue '%s' is not found", newTask.GetQueueName())
+	}
+	return newTask, nil
+}
+
+func (op *ShellOperator) SetupHookRunHandles() {
+	op.DebugServer.Router.Post("/hook/{name}/run", op.withHook(func(writer http.ResponseWriter, request *http.Request, h *hook.Hook) {
+		bindingName := request.URL.Query().Get("binding")
+		wait := request.URL.Query().Get("wait") == "true"
+
+		data, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(writer, "Read binding context: %v\n", err)
+			return
+		}
+		var bindingContext []BindingContext
+		if len(bytes.TrimSpace(data)) > 0 {
+			bindingContext, err = ParseBindingContextJson(data)
+			if err != nil {
+				writer.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintf(writer, "Parse binding context: %v\n", err)
+				return
+			}
+		}
+
+		newTask, err := op.NewManualHookRunTask(h.Name, bindingName, bindingContext)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+
+		// Watch before queueing to not miss log lines of a fast hook.
+		var watcher *hookRunWatcher
+		if wait {
+			watcher = op.HookRunWatchers.Watch(newTask.GetId())
+			defer op.HookRunWatchers.Unwatch(watcher)
+		}
+
+		op.TaskQueues.GetByName(newTask.GetQueueName()).AddLast(newTask)
+		log.WithFields(utils.LabelsToLogFields(newTask.GetLogLabels())).
+			Infof("Queue manual run task %s", newTask.GetDescription())
+
+		_, _ = fmt.Fprintln(writer, newTask.GetId())
+		if !wait {
+			return
+		}
+
+		flusher, _ := writer.(http.Flusher)
+		for {
+			if flusher != nil {
+				flusher.Flush()
+			}
+			select {
+			case line := <-watcher.lines:
+				_, _ = fmt.Fprint(writer, line)
+			case runErr := <-watcher.done:
+				// Write log lines that are fired before the result.
+				for len(watcher.lines) > 0 {
+					_, _ = fmt.Fprint(writer, <-watcher.lines)
+				}
+				if runErr != nil {
+					_, _ = fmt.Fprintf(writer, "Hook run failed: %v\n", runErr)
+				} else {
+					_, _ = fmt.Fprintln(writer, "Hook run succeeded")
+				}
+				return
+			case <-request.Context().Done():
+				return
+			case <-op.ctx.Done():
+				return
+			}
+		}
+	}))
+}
+
+// HookRunWatchers is a logrus hook that sends log lines of watched HookRun tasks to watchers.
+type HookRunWatchers struct {
+	m        sync.RWMutex
+	watchers map[*hookRunWatcher]bool
+}
+
+type hookRunWatcher struct {
+	taskId string
+	lines  chan string
+	done   chan error
+}
+
+var _ log.Hook = &HookRunWatchers{}
+
+func NewHookRunWatchers() *HookRunWatchers {
+	return &HookRunWatchers{
+		watchers: make(map[*hookRunWatcher]bool),
+	}
+}
+
+// Watch returns a new watcher for the task.
+func (w *HookRunWatchers) Watch(taskId string) *hookRunWatcher {
+	watcher := &hookRunWatcher{
+		taskId: taskId,
+		lines:  make(chan string, 1000),
+		done:   make(chan error, 1),
+	}
+	w.m.Lock()
+	w.watchers[watcher] = true
+	w.m.Unlock()
+	return watcher
+}
+
+func (w *HookRunWatchers) Unwatch(watcher *hookRunWatcher) {
+	w.m.Lock()
+	delete(w.watchers, watcher)
+	w.m.Unlock()
+}
+
+func (w *hookRunWatcher) isWatching(id string) bool {
+	return w.taskId == id
+}
+
+// IsWatched returns true if the task is watched. Such tasks are not combined with other tasks.
+func (w *HookRunWatchers) IsWatched(t task.Task) bool {
+	w.m.RLock()
+	defer w.m.RUnlock()
+	for watcher := range w.watchers {
+		if watcher.isWatching(t.GetId()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Done sends a result of the hook run to watchers of the task.
+func (w *HookRunWatchers) Done(t task.Task, err error) {
+	w.m.RLock()
+	defer w.m.RUnlock()
+	for watcher := range w.watchers {
+		if watcher.isWatching(t.GetId()) {
+			select {
+			case watcher.done <- err:
+			default:
+			}
+		}
+	}
+}
+
+// Removed tells watchers of the task that the task is removed from the queue and will not run.
+func (w *HookRunWatchers) Removed(t task.Task) {
+	w.Done(t, fmt.Errorf("task is removed from the queue"))
+}
+
+func (w *HookRunWatchers) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire sends log entries with the "task.id" field to watchers of the task. Lines are
+// dropped if the watcher is too slow.
+func (w *HookRunWatchers) Fire(entry *log.Entry) error {
+	w.m.RLock()
+	defer w.m.RUnlock()
+	if len(w.watchers) == 0 {
+		return nil
+	}
+	taskId, ok := entry.Data["task.id"].(string)
+	if !ok {
+		return nil
+	}
+	for watcher := range w.watchers {
+		if !watcher.isWatching(taskId) {
+			continue
+		}
+		line, err := entry.String()
+		if err != nil {
+			return err
+		}
+		select {
+		case watcher.lines <- line:
+		default:
+		}
+	}
+	return nil
+}
+
+// ParseBindingContextJson parses a binding context in the format for hooks. Data can be an array
+// or a single binding context. Snapshots are ignored, they are updated before the hook run.
+func ParseBindingContextJson(data []byte) ([]BindingContext, error) {
+	type objectJson struct {
+		Object       *unstructured.Unstructured `json:"object"`
+		FilterResult json.RawMessage            `json:"filterResult"`
+		// watchEvent of an item in a debounced "Event" context.
+		WatchEvent WatchEventType `json:"watchEvent"`
+	}
+	type bindingContextJson struct {
+		objectJson
+		Binding    string         `json:"binding"`
+		Type       KubeEventType  `json:"type"`
+		WatchEvent WatchEventType `json:"watchEvent"`
+		Objects    []objectJson   `json:"objects"`
+	}
+
+	var items []bindingContextJson
+	if strings.HasPrefix(string(bytes.TrimSpace(data)), "{") {
+		var item bindingContextJson
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	} else if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	convertObject := func(obj objectJson) ObjectAndFilterResult {
+		res := ObjectAndFilterResult{
+			Object:       obj.Object,
+			FilterResult: string(obj.FilterResult),
+		}
+		if obj.Object != nil {
+			res.Metadata.ResourceId = kube_events_manager.ResourceId(obj.Object)
+		}
+		return res
+	}
+
+	res := make([]BindingContext, 0, len(items))
+	for _, item := range items {
+		bc := BindingContext{
+			Binding:    item.Binding,
+			Type:       item.Type,
+			WatchEvent: item.WatchEvent,
+		}
+		switch item.Type {
+		case TypeSynchronization:
+			bc.Objects = make([]ObjectAndFilterResult, 0, len(item.Objects))
+			for _, obj := range item.Objects {
+				bc.Objects = append(bc.Objects, convertObject(obj))
+			}
+		case TypeEvent:
+			if len(item.Objects) == 0 {
+				bc.Objects = []ObjectAndFilterResult{convertObject(item.objectJson)}
+				break
+			}
+			// A debounced binding context.
+			for _, obj := range item.Objects {
+				bc.Objects = append(bc.Objects, convertObject(obj))
+				bc.WatchEvents = append(bc.WatchEvents, obj.WatchEvent)
+			}
+		}
+		res = append(res, bc)
+	}
+	return res, nil
+}
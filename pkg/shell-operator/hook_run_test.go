@@ -0,0 +1,105 @@
+package shell_operator
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+func Test_ParseBindingContextJson(t *testing.T) {
+	g := NewWithT(t)
+
+	bcs, err := ParseBindingContextJson([]byte(`[
+  {
+    "binding": "pods",
+    "type": "Synchronization",
+    "objects": [
+      {"object": {"kind": "Pod", "metadata": {"name": "pod-a", "namespace": "default"}}, "filterResult": {"app": "a"}},
+      {"object": {"kind": "Pod", "metadata": {"name": "pod-b", "namespace": "default"}}}
+    ]
+  },
+  {
+    "binding": "pods",
+    "type": "Event",
+    "watchEvent": "Modified",
+    "object": {"kind": "Pod", "metadata": {"name": "pod-a", "namespace": "default"}},
+    "filterResult": "a"
+  },
+  {
+    "binding": "pods",
+    "type": "Event",
+    "objects": [
+      {"watchEvent": "Added", "object": {"kind": "Pod", "metadata": {"name": "pod-c", "namespace": "default"}}},
+      {"watchEvent": "Deleted", "object": {"kind": "Pod", "metadata": {"name": "pod-d", "namespace": "default"}}}
+    ]
+  }
+]`))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(bcs).Should(HaveLen(3))
+
+	g.Expect(bcs[0].Type).Should(Equal(TypeSynchronization))
+	g.Expect(bcs[0].Objects).Should(HaveLen(2))
+	g.Expect(bcs[0].Objects[0].Metadata.ResourceId).Should(Equal("default/Pod/pod-a"))
+	g.Expect(bcs[0].Objects[0].FilterResult).Should(MatchJSON(`{"app": "a"}`))
+	g.Expect(bcs[0].Objects[1].FilterResult).Should(Equal(""))
+
+	g.Expect(bcs[1].Type).Should(Equal(TypeEvent))
+	g.Expect(bcs[1].WatchEvent).Should(Equal(WatchEventModified))
+	g.Expect(bcs[1].Objects).Should(HaveLen(1))
+	g.Expect(bcs[1].Objects[0].Object.GetName()).Should(Equal("pod-a"))
+	g.Expect(bcs[1].Objects[0].FilterResult).Should(Equal(`"a"`))
+
+	// A debounced binding context has objects with watch events.
+	g.Expect(bcs[2].IsBatch()).Should(BeTrue())
+	g.Expect(bcs[2].WatchEvents).Should(Equal([]WatchEventType{WatchEventAdded, WatchEventDeleted}))
+	g.Expect(bcs[2].Objects).Should(HaveLen(2))
+	g.Expect(bcs[2].Objects[1].Metadata.ResourceId).Should(Equal("default/Pod/pod-d"))
+
+	// A single binding context without array.
+	bcs, err = ParseBindingContextJson([]byte(`{"binding": "every-minute", "type": "Schedule"}`))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(bcs).Should(HaveLen(1))
+	g.Expect(bcs[0].Binding).Should(Equal("every-minute"))
+	g.Expect(bcs[0].Objects).Should(BeEmpty())
+
+	_, err = ParseBindingContextJson([]byte(`[{"binding": 1}]`))
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func Test_HookRunWatchers(t *testing.T) {
+	g := NewWithT(t)
+
+	watchers := NewHookRunWatchers()
+	logger := log.New()
+	logger.AddHook(watchers)
+
+	watchedTask := task.NewTask(HookRun)
+	otherTask := task.NewTask(HookRun)
+	watcher := watchers.Watch(watchedTask.GetId())
+
+	g.Expect(watchers.IsWatched(watchedTask)).Should(BeTrue())
+	g.Expect(watchers.IsWatched(otherTask)).Should(BeFalse())
+
+	logger.WithField("task.id", watchedTask.GetId()).Info("hook output")
+	logger.WithField("task.id", otherTask.GetId()).Info("other hook output")
+	logger.Info("no task")
+	g.Expect(watcher.lines).Should(HaveLen(1))
+	g.Expect(<-watcher.lines).Should(ContainSubstring("hook output"))
+
+	watchers.Done(otherTask, nil)
+	g.Expect(watcher.done).Should(BeEmpty())
+	watchers.Done(watchedTask, fmt.Errorf("hook failed"))
+	g.Expect(<-watcher.done).Should(MatchError("hook failed"))
+	watchers.Removed(watchedTask)
+	g.Expect(<-watcher.done).Should(MatchError("task is removed from the queue"))
+
+	watchers.Unwatch(watcher)
+	g.Expect(watchers.IsWatched(watchedTask)).Should(BeFalse())
+}
@@ -0,0 +1,102 @@
+package shell_operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+)
+
+// SnapshotObjectInfo describes a cached object without a full dump.
+type SnapshotObjectInfo struct {
+	Id                string      `json:"id"`
+	Checksum          string      `json:"checksum"`
+	FilterResult      interface{} `json:"filterResult,omitempty"`
+	FilterResultBytes int         `json:"filterResultBytes"`
+	ObjectBytes       int64       `json:"objectBytes"`
+}
+
+// BindingSnapshotInfo is a snapshot of a kubernetes binding. Bytes is a total size of objects and filter results.
+type BindingSnapshotInfo struct {
+	Binding string               `json:"binding"`
+	Count   int                  `json:"count"`
+	Bytes   int64                `json:"bytes"`
+	Objects []SnapshotObjectInfo `json:"objects"`
+}
+
+// SnapshotsInfo returns object ids, checksums, filter results and sizes for each binding
+// sorted by binding name. All bindings are returned if bindingName is empty.
+func SnapshotsInfo(snapshots map[string][]ObjectAndFilterResult, bindingName string) ([]BindingSnapshotInfo, error) {
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		if bindingName == "" || bindingName == name {
+			names = append(names, name)
+		}
+	}
+	if bindingName != "" && len(names) == 0 {
+		return nil, fmt.Errorf("no kubernetes binding with name '%s'", bindingName)
+	}
+	sort.Strings(names)
+
+	res := make([]BindingSnapshotInfo, 0, len(names))
+	for _, name := range names {
+		info := BindingSnapshotInfo{
+			Binding: name,
+			Count:   len(snapshots[name]),
+			Objects: make([]SnapshotObjectInfo, 0, len(snapshots[name])),
+		}
+		for _, obj := range snapshots[name] {
+			objInfo := SnapshotObjectInfo{
+				Id:                obj.Metadata.ResourceId,
+				Checksum:          obj.Metadata.Checksum,
+				FilterResultBytes: len(obj.FilterResult),
+				ObjectBytes:       obj.ObjectBytes,
+			}
+			if obj.FilterResult != "" {
+				var filterResult interface{}
+				if json.Unmarshal([]byte(obj.FilterResult), &filterResult) == nil {
+					objInfo.FilterResult = filterResult
+				} else {
+					objInfo.FilterResult = obj.FilterResult
+				}
+			}
+			info.Bytes += objInfo.ObjectBytes + int64(objInfo.FilterResultBytes)
+			info.Objects = append(info.Objects, objInfo)
+		}
+		res = append(res, info)
+	}
+	return res, nil
+}
+
+func (op *ShellOperator) SetupHookSnapshotsHandles() {
+	op.DebugServer.Router.Get("/hook/{name}/snapshots.{format:(json|yaml)}", op.withHook(func(writer http.ResponseWriter, request *http.Request, h *hook.Hook) {
+		format := chi.URLParam(request, "format")
+
+		infos, err := SnapshotsInfo(h.HookController.KubernetesSnapshots(), request.URL.Query().Get("binding"))
+		if err != nil {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+
+		var data []byte
+		if format == "json" {
+			data, err = json.Marshal(infos)
+		} else {
+			data, err = yaml.Marshal(infos)
+		}
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = writer.Write(data)
+	}))
+}
@@ -0,0 +1,49 @@
+package shell_operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+)
+
+func Test_SnapshotsInfo(t *testing.T) {
+	g := NewWithT(t)
+
+	podA := testObject("default/Pod/pod-a", "sum-a")
+	podA.FilterResult = `{"app":"a"}`
+	podA.ObjectBytes = 100
+	podB := testObject("default/Pod/pod-b", "sum-b")
+	podB.ObjectBytes = 200
+	snapshots := map[string][]ObjectAndFilterResult{
+		"pods":   {podA, podB},
+		"config": {},
+	}
+
+	infos, err := SnapshotsInfo(snapshots, "")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(infos).Should(HaveLen(2))
+	g.Expect(infos[0].Binding).Should(Equal("config"))
+	g.Expect(infos[0].Objects).Should(BeEmpty())
+
+	pods := infos[1]
+	g.Expect(pods.Binding).Should(Equal("pods"))
+	g.Expect(pods.Count).Should(Equal(2))
+	g.Expect(pods.Bytes).Should(Equal(int64(311)))
+	g.Expect(pods.Objects[0]).Should(Equal(SnapshotObjectInfo{
+		Id:                "default/Pod/pod-a",
+		Checksum:          "sum-a",
+		FilterResult:      map[string]interface{}{"app": "a"},
+		FilterResultBytes: 11,
+		ObjectBytes:       100,
+	}))
+	g.Expect(pods.Objects[1].FilterResult).Should(BeNil())
+
+	infos, err = SnapshotsInfo(snapshots, "pods")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(infos).Should(HaveLen(1))
+
+	_, err = SnapshotsInfo(snapshots, "unknown")
+	g.Expect(err).Should(HaveOccurred())
+}
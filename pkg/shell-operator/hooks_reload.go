@@ -0,0 +1,261 @@
+package shell_operator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+// QueueReloadHooksTask adds a ReloadHooks task to the main queue if there is no such task yet.
+// Hooks are reloaded in the main queue to not interfere with enabling of kubernetes bindings.
+func (op *ShellOperator) QueueReloadHooksTask() bool {
+	mainQueue := op.TaskQueues.GetMain()
+	if mainQueue == nil {
+		return false
+	}
+
+	queued := false
+	mainQueue.Iterate(func(t task.Task) {
+		if t.GetType() == ReloadHooks {
+			queued = true
+		}
+	})
+	if queued {
+		return false
+	}
+
+	newTask := task.NewTask(ReloadHooks).
+		WithMetadata(HookMetadata{
+			Binding: string(ReloadHooks),
+		}).
+		WithQueuedAt(time.Now())
+	mainQueue.AddLast(newTask)
+	log.Infof("queue task %s", newTask.GetDescription())
+	return true
+}
+
+// StartHooksWatcher periodically checks hook files and queues a ReloadHooks task on changes.
+func (op *ShellOperator) StartHooksWatcher(interval time.Duration) {
+	logEntry := log.WithField("operator.component", "hooksWatcher")
+	logEntry.Infof("Check hooks dir for changes every %s", interval.String())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-op.ctx.Done():
+				return
+			case <-ticker.C:
+				changed, err := op.HookManager.IsChanged()
+				if err != nil {
+					logEntry.Errorf("Check hooks dir: %v", err)
+					continue
+				}
+				if changed {
+					logEntry.Infof("Hooks dir is changed")
+					op.QueueReloadHooksTask()
+				}
+			}
+		}
+	}()
+}
+
+func (op *ShellOperator) SetupHooksReloadHandles() {
+	op.DebugServer.Router.Post("/hook/reload", func(writer http.ResponseWriter, request *http.Request) {
+		if op.QueueReloadHooksTask() {
+			_, _ = fmt.Fprintln(writer, "ReloadHooks task is queued")
+		} else {
+			_, _ = fmt.Fprintln(writer, "ReloadHooks task is already queued")
+		}
+	})
+}
+
+// TaskHandleReloadHooks reloads hooks and restarts bindings of changed hooks.
+// Bindings of unchanged hooks are not affected. Monitors of unchanged 'kubernetes'
+// bindings of changed hooks are reused. onStartup is executed only for new hooks.
+func (op *ShellOperator) TaskHandleReloadHooks(t task.Task) queue.TaskResult {
+	logEntry := log.WithField("task", string(ReloadHooks)).
+		WithField("queue", "main")
+
+	var res queue.TaskResult
+	// Reload errors are not retried: hooks are not changed and the watcher
+	// queues a new task on the next check.
+	res.Status = "Success"
+
+	diff, err := op.HookManager.Reload()
+	if err != nil {
+		logEntry.Errorf("Reload hooks failed, hooks are not changed: %v", err)
+		return res
+	}
+	if diff.IsEmpty() {
+		logEntry.Infof("Hooks are not changed")
+		return res
+	}
+
+	oldHooks := make([]*hook.Hook, 0)
+	newHooks := make([]*hook.Hook, 0)
+	// Unchanged kubernetes bindings of old hooks, their monitors are passed to new hooks.
+	keptBindings := make(map[*hook.Hook][]string)
+	for _, h := range diff.Removed {
+		logEntry.Infof("Hook '%s' is removed", h.Name)
+		op.HookHistory.Remove(h.Name)
+		oldHooks = append(oldHooks, h)
+	}
+	for _, u := range diff.Updated {
+		logEntry.Infof("Hook '%s' is changed", u.New.Name)
+		if len(u.UnchangedKubernetesBindings) > 0 {
+			logEntry.Infof("Hook '%s': keep informers of unchanged kubernetes bindings %v", u.New.Name, u.UnchangedKubernetesBindings)
+			keptBindings[u.Old] = u.UnchangedKubernetesBindings
+		}
+		oldHooks = append(oldHooks, u.Old)
+		newHooks = append(newHooks, u.New)
+	}
+	for _, h := range diff.Added {
+		logEntry.Infof("Hook '%s' is added", h.Name)
+		newHooks = append(newHooks, h)
+	}
+
+	webhooksChanged := false
+	for _, h := range oldHooks {
+		// Changed hooks should receive Synchronization.
+		if op.ProcessedSnapshots != nil {
+			op.ProcessedSnapshots.Forget(h.Name)
+		}
+		stopHookMonitors(h, keptBindings[h])
+		h.HookController.DisableScheduleBindings()
+		h.HookController.DisableValidatingBindings()
+		h.HookController.DisableMutatingBindings()
+		h.HookController.DisableConversionBindings()
+		webhooksChanged = webhooksChanged || hasWebhookBindings(h)
+	}
+	for _, h := range newHooks {
+		h.HookController.EnableValidatingBindings()
+		h.HookController.EnableMutatingBindings()
+		h.HookController.EnableConversionBindings()
+		webhooksChanged = webhooksChanged || hasWebhookBindings(h)
+	}
+
+	if webhooksChanged {
+		if op.WebhookManager.IsStarted() {
+			err = op.WebhookManager.UpdateConfigurations()
+			if err != nil {
+				logEntry.Errorf("Update webhook configurations: %v", err)
+			}
+		} else {
+			logEntry.Errorf("Webhook bindings are changed, but webhook server is not started. Restart Shell-operator to apply changes.")
+		}
+	}
+
+	op.InitAndStartHookQueues()
+	op.RemoveUnusedHookQueues()
+
+	// Run onStartup for new hooks, enable kubernetes and schedule bindings for new and changed hooks.
+	sort.SliceStable(diff.Added, func(i, j int) bool {
+		return onStartupOrder(diff.Added[i]) < onStartupOrder(diff.Added[j])
+	})
+	now := time.Now()
+	for _, h := range diff.Added {
+		if !h.Config.HasBinding(OnStartup) {
+			continue
+		}
+		bc := BindingContext{
+			Binding: string(OnStartup),
+		}
+		bc.Metadata.BindingType = OnStartup
+		res.AfterTasks = append(res.AfterTasks, task.NewTask(HookRun).
+			WithMetadata(HookMetadata{
+				HookName:       h.Name,
+				BindingType:    OnStartup,
+				BindingContext: []BindingContext{bc},
+			}).
+			WithQueuedAt(now))
+	}
+	for _, h := range newHooks {
+		if h.Config.HasBinding(OnKubernetesEvent) {
+			res.AfterTasks = append(res.AfterTasks, task.NewTask(EnableKubernetesBindings).
+				WithMetadata(HookMetadata{
+					HookName: h.Name,
+					Binding:  string(EnableKubernetesBindings),
+				}).
+				WithQueuedAt(now))
+		}
+		if h.Config.HasBinding(Schedule) {
+			res.AfterTasks = append(res.AfterTasks, task.NewTask(EnableScheduleBindings).
+				WithMetadata(HookMetadata{
+					HookName: h.Name,
+					Binding:  string(EnableScheduleBindings),
+				}).
+				WithQueuedAt(now))
+		}
+	}
+
+	logEntry.Infof("Hooks reloaded: %d added, %d changed, %d removed", len(diff.Added), len(diff.Updated), len(diff.Removed))
+	return res
+}
+
+// RemoveUnusedHookQueues stops and removes empty named queues that are not used by hooks.
+func (op *ShellOperator) RemoveUnusedHookQueues() {
+	usedQueues := map[string]bool{}
+	for _, hookName := range op.HookManager.GetHookNames() {
+		h := op.HookManager.GetHook(hookName)
+		for _, cfg := range h.Config.Schedules {
+			usedQueues[cfg.Queue] = true
+		}
+		for _, cfg := range h.Config.OnKubernetesEvents {
+			usedQueues[cfg.Queue] = true
+		}
+	}
+
+	unused := make([]string, 0)
+	op.TaskQueues.Iterate(func(q *queue.TaskQueue) {
+		if q.Name != op.TaskQueues.MainName && !usedQueues[q.Name] && q.IsEmpty() {
+			unused = append(unused, q.Name)
+		}
+	})
+	for _, name := range unused {
+		log.Infof("Queue '%s' is not used by hooks, remove it", name)
+		op.TaskQueues.Remove(name)
+	}
+}
+
+// stopHookMonitors stops monitors of 'kubernetes' bindings of the hook except kept bindings.
+func stopHookMonitors(h *hook.Hook, keptBindings []string) {
+	if len(keptBindings) == 0 {
+		h.HookController.StopMonitors()
+		return
+	}
+	kept := make(map[string]bool)
+	for _, bindingName := range keptBindings {
+		kept[bindingName] = true
+	}
+	for _, cfg := range h.Config.OnKubernetesEvents {
+		if !kept[cfg.BindingName] {
+			h.HookController.StopMonitor(cfg.BindingName)
+		}
+	}
+}
+
+func hasWebhookBindings(h *hook.Hook) bool {
+	return h.Config.HasBinding(KubernetesValidating) ||
+		h.Config.HasBinding(KubernetesMutating) ||
+		h.Config.HasBinding(KubernetesConversion)
+}
+
+func onStartupOrder(h *hook.Hook) float64 {
+	if h.Config.OnStartup == nil {
+		return 0
+	}
+	return h.Config.OnStartup.Order
+}
@@ -0,0 +1,161 @@
+package shell_operator
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/app"
+	"github.com/flant/shell-operator/pkg/hook/controller"
+	"github.com/flant/shell-operator/pkg/leader_election"
+)
+
+// InitLeaderElection creates a LeaderElector and a storage for processed snapshots
+// if leader election is enabled.
+func (op *ShellOperator) InitLeaderElection() error {
+	if !app.LeaderElectionSettings.Enabled {
+		return nil
+	}
+
+	namespace := app.LeaderElectionSettings.Namespace
+	if namespace == "" {
+		namespace = app.Namespace
+	}
+	if namespace == "" {
+		namespace = op.KubeClient.DefaultNamespace()
+	}
+
+	identity := app.LeaderElectionSettings.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		identity = hostname
+	}
+
+	leaseName := app.LeaderElectionSettings.LeaseName
+
+	op.ProcessedSnapshots = leader_election.NewProcessedSnapshots()
+	op.ProcessedSnapshots.WithKubeClient(op.KubeClient)
+	op.ProcessedSnapshots.WithConfigMap(namespace, leaseName+"-snapshots")
+
+	op.LeaderElector = leader_election.NewLeaderElector()
+	op.LeaderElector.WithContext(op.ctx)
+	op.LeaderElector.WithKubeClient(op.KubeClient)
+	op.LeaderElector.WithLease(namespace, leaseName)
+	op.LeaderElector.WithIdentity(identity)
+	op.LeaderElector.WithTimings(
+		app.LeaderElectionSettings.LeaseDuration,
+		app.LeaderElectionSettings.RenewDeadline,
+		app.LeaderElectionSettings.RetryPeriod,
+	)
+	op.LeaderElector.WithCallbacks(op.StartLeading, op.StopLeading)
+
+	return op.LeaderElector.Init()
+}
+
+// StartStandby creates and starts informers for all 'kubernetes' bindings without running hooks.
+// Snapshots are kept up to date, so Synchronization can be skipped for unchanged
+// snapshots when this replica becomes a leader.
+func (op *ShellOperator) StartStandby() {
+	logEntry := log.WithField("operator.component", "standby")
+
+	kubeHooks, _ := op.HookManager.GetHooksInOrder(OnKubernetesEvent)
+	for _, hookName := range kubeHooks {
+		h := op.HookManager.GetHook(hookName)
+		err := h.HookController.HandleEnableKubernetesBindings(nil)
+		if err != nil {
+			logEntry.Errorf("Hook '%s': create informers: %v. Will retry on leading.", hookName, err)
+			continue
+		}
+		h.HookController.StartMonitors()
+	}
+	logEntry.Infof("Informers are started, wait for leadership")
+}
+
+// StartLeading starts queues and periodically saves checksums of processed snapshots.
+func (op *ShellOperator) StartLeading(ctx context.Context) {
+	err := op.ProcessedSnapshots.Load()
+	if err != nil {
+		log.Errorf("Load processed snapshots, Synchronization will be executed for all hooks: %v", err)
+	}
+
+	op.StartQueues()
+
+	go func() {
+		ticker := time.NewTicker(op.LeaderElector.RetryPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := op.ProcessedSnapshots.Save()
+				if err != nil {
+					log.Errorf("Save processed snapshots: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// StopLeading requests the shutdown to not run hooks along with a new leader.
+// It is called from the elector goroutine, Shutdown waits for this goroutine, so
+// the shutdown is done by the main goroutine.
+func (op *ShellOperator) StopLeading() {
+	log.Errorf("Leadership is lost, shutdown")
+	op.RequestShutdown()
+}
+
+// IsQueuesStarted returns false for a standby replica.
+func (op *ShellOperator) IsQueuesStarted() bool {
+	return atomic.LoadInt32(&op.queuesStarted) == 1
+}
+
+// SkipSynchronization returns true if the previous leader has passed the same
+// snapshot to the hook. The snapshot is marked as processed by this replica.
+func (op *ShellOperator) SkipSynchronization(hookName string, info controller.BindingExecutionInfo) bool {
+	if op.ProcessedSnapshots == nil {
+		return false
+	}
+
+	for _, bc := range info.BindingContext {
+		if bc.Type != TypeSynchronization || !op.ProcessedSnapshots.IsRecorded(hookName, bc.Binding, bc.Objects) {
+			return false
+		}
+	}
+	for _, bc := range info.BindingContext {
+		op.ProcessedSnapshots.Reset(hookName, bc.Binding, bc.Objects)
+	}
+	return true
+}
+
+// RecordProcessedSnapshots updates processed snapshots after successful execution of a 'kubernetes' hook.
+func (op *ShellOperator) RecordProcessedSnapshots(hookName string, bindingContexts []BindingContext) {
+	if op.ProcessedSnapshots == nil {
+		return
+	}
+
+	for _, bc := range bindingContexts {
+		switch bc.Type {
+		case TypeSynchronization:
+			op.ProcessedSnapshots.Reset(hookName, bc.Binding, bc.Objects)
+		case TypeEvent:
+			if bc.IsBatch() {
+				for i := range bc.Objects {
+					op.ProcessedSnapshots.Update(hookName, bc.Binding, bc.WatchEvents[i], bc.Objects[i:i+1])
+				}
+				continue
+			}
+			op.ProcessedSnapshots.Update(hookName, bc.Binding, bc.WatchEvent, bc.Objects)
+		}
+	}
+}
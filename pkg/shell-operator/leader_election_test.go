@@ -0,0 +1,63 @@
+package shell_operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/leader_election"
+)
+
+func Test_StopLeading_RequestsShutdown(t *testing.T) {
+	g := NewWithT(t)
+
+	op := NewShellOperator()
+	g.Expect(op.ShutdownRequested()).ShouldNot(BeClosed())
+
+	op.StopLeading()
+	g.Expect(op.ShutdownRequested()).Should(BeClosed())
+	// Repeated calls are safe.
+	op.StopLeading()
+}
+
+func Test_RecordProcessedSnapshots(t *testing.T) {
+	g := NewWithT(t)
+
+	op := NewShellOperator()
+	// No leader election.
+	op.RecordProcessedSnapshots("hook.sh", []BindingContext{{Binding: "pods", Type: TypeSynchronization}})
+
+	op.ProcessedSnapshots = leader_election.NewProcessedSnapshots()
+
+	op.RecordProcessedSnapshots("hook.sh", []BindingContext{
+		{
+			Binding: "pods",
+			Type:    TypeSynchronization,
+			Objects: []ObjectAndFilterResult{testObject("pod-a", "1"), testObject("pod-b", "1")},
+		},
+		{
+			Binding:    "pods",
+			Type:       TypeEvent,
+			WatchEvent: WatchEventModified,
+			Objects:    []ObjectAndFilterResult{testObject("pod-a", "2")},
+		},
+		// A debounced batch.
+		{
+			Binding:     "pods",
+			Type:        TypeEvent,
+			WatchEvents: []WatchEventType{WatchEventDeleted, WatchEventAdded},
+			Objects:     []ObjectAndFilterResult{testObject("pod-b", "1"), testObject("pod-c", "1")},
+		},
+		// Schedule binding context in a combined task.
+		{
+			Binding: "every-minute",
+		},
+	})
+
+	expected := leader_election.NewProcessedSnapshots()
+	expected.Reset("hook.sh", "pods", []ObjectAndFilterResult{testObject("pod-a", "2"), testObject("pod-c", "1")})
+	g.Expect(op.ProcessedSnapshots.Checksums()).Should(Equal(expected.Checksums()))
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/flant/shell-operator/pkg/schedule_manager"
 	"github.com/flant/shell-operator/pkg/task"
 	"github.com/flant/shell-operator/pkg/task/queue"
+	"github.com/flant/shell-operator/pkg/tracing"
 	"github.com/flant/shell-operator/pkg/utils/labels"
 )
 
@@ -26,6 +29,9 @@ type ManagerEventsHandler struct {
 	scheduleCb  func(crontab string) []task.Task
 
 	taskQueues *queue.TaskQueueSet
+
+	// done is closed when the events loop is stopped.
+	done chan struct{}
 }
 
 func NewManagerEventsHandler() *ManagerEventsHandler {
@@ -56,24 +62,56 @@ func (m *ManagerEventsHandler) WithContext(ctx context.Context) {
 	m.ctx, m.cancel = context.WithCancel(ctx)
 }
 
+// Stop stops the events loop and waits until the current event is queued.
 func (m *ManagerEventsHandler) Stop() {
 	if m.cancel != nil {
 		m.cancel()
 	}
+	if m.done != nil {
+		<-m.done
+	}
+}
+
+// HandlePendingKubeEvents queues tasks for events that are emitted but not handled by
+// the stopped events loop. It is used on shutdown to persist events that are already
+// in the cache of informers.
+func (m *ManagerEventsHandler) HandlePendingKubeEvents() {
+	for {
+		select {
+		case kubeEvent := <-m.kubeEventsManager.Ch():
+			_, span := tracing.Start(kubeEvent.SpanContext, "HandleKubeEvent",
+				trace.WithAttributes(attribute.String("monitor.id", kubeEvent.MonitorId)))
+			var tailTasks []task.Task
+			if m.kubeEventCb != nil {
+				tailTasks = m.kubeEventCb(kubeEvent)
+			}
+			m.queueTasks(tailTasks, span)
+			span.End()
+		default:
+			return
+		}
+	}
 }
 
 func (m *ManagerEventsHandler) Start() {
+	m.done = make(chan struct{})
 	go func() {
+		defer close(m.done)
 		for {
 			var tailTasks []task.Task
+			var span trace.Span
 			var logEntry = log.WithField("operator.component", "handleEvents")
 			select {
 			case crontab := <-m.scheduleManager.Ch():
+				_, span = tracing.Start(trace.SpanContext{}, "HandleScheduleEvent",
+					trace.WithAttributes(attribute.String("crontab", crontab)))
 				if m.scheduleCb != nil {
 					tailTasks = m.scheduleCb(crontab)
 				}
 
 			case kubeEvent := <-m.kubeEventsManager.Ch():
+				_, span = tracing.Start(kubeEvent.SpanContext, "HandleKubeEvent",
+					trace.WithAttributes(attribute.String("monitor.id", kubeEvent.MonitorId)))
 				if m.kubeEventCb != nil {
 					tailTasks = m.kubeEventCb(kubeEvent)
 				}
@@ -83,20 +121,32 @@ func (m *ManagerEventsHandler) Start() {
 				return
 			}
 
-			m.taskQueues.DoWithLock(func(tqs *queue.TaskQueueSet) {
-				for _, resTask := range tailTasks {
-					q := tqs.GetByName(resTask.GetQueueName())
-					if q == nil {
-						log.Errorf("Possible bug!!! Got task for queue '%s' but queue is not created yet. task: %s", resTask.GetQueueName(), resTask.GetDescription())
-					} else {
-						resTask.WithQueuedAt(time.Now())
-						q.AddLast(resTask)
-						logEntry.WithFields(utils.LabelsToLogFields(resTask.GetLogLabels())).
-							WithField("queue", q.Name).
-							Infof("queue task %s", resTask.GetDescription())
-					}
-				}
-			})
+			m.queueTasks(tailTasks, span)
+			span.End()
 		}
 	}()
 }
+
+func (m *ManagerEventsHandler) queueTasks(tailTasks []task.Task, span trace.Span) {
+	var logEntry = log.WithField("operator.component", "handleEvents")
+	m.taskQueues.DoWithLock(func(tqs *queue.TaskQueueSet) {
+		for _, resTask := range tailTasks {
+			q := tqs.GetByName(resTask.GetQueueName())
+			if q == nil {
+				log.Errorf("Possible bug!!! Got task for queue '%s' but queue is not created yet. task: %s", resTask.GetQueueName(), resTask.GetDescription())
+			} else {
+				resTask.WithQueuedAt(time.Now())
+				_, queueSpan := tracing.Start(span.SpanContext(), "QueueTask", trace.WithAttributes(
+					attribute.String("queue", q.Name),
+					attribute.String("task.id", resTask.GetId()),
+				))
+				tracing.WithTaskSpanContext(resTask, queueSpan.SpanContext())
+				AddLastWithQueuePolicy(q, resTask)
+				queueSpan.End()
+				logEntry.WithFields(utils.LabelsToLogFields(resTask.GetLogLabels())).
+					WithField("queue", q.Name).
+					Infof("queue task %s", resTask.GetDescription())
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package shell_operator
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/kube_events_manager"
+	"github.com/flant/shell-operator/pkg/schedule_manager"
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+func Test_ManagerEventsHandler_HandlePendingKubeEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	tqs := queue.NewTaskQueueSet()
+	tqs.WithContext(context.Background())
+	tqs.NewNamedQueue("main", nil)
+
+	kubeEventsManager := kube_events_manager.NewKubeEventsManager()
+	handler := NewManagerEventsHandler()
+	handler.WithContext(context.Background())
+	handler.WithTaskQueueSet(tqs)
+	handler.WithKubeEventsManager(kubeEventsManager)
+	handler.WithScheduleManager(schedule_manager.NewScheduleManager())
+	handler.WithKubeEventHandler(func(kubeEvent KubeEvent) []task.Task {
+		return []task.Task{
+			task.NewTask(HookRun).
+				WithQueueName("main").
+				WithMetadata(HookMetadata{HookName: kubeEvent.MonitorId}),
+		}
+	})
+
+	handler.Start()
+	kubeEventsManager.Ch() <- KubeEvent{MonitorId: "handled"}
+	g.Eventually(tqs.GetByName("main").Length).Should(Equal(1))
+	handler.Stop()
+
+	// An event emitted after stop is queued on shutdown.
+	kubeEventsManager.Ch() <- KubeEvent{MonitorId: "pending"}
+	handler.HandlePendingKubeEvents()
+
+	hookNames := []string{}
+	tqs.GetByName("main").Iterate(func(t task.Task) {
+		hookNames = append(hookNames, HookMetadataAccessor(t).HookName)
+	})
+	g.Expect(hookNames).Should(Equal([]string{"handled", "pending"}))
+}
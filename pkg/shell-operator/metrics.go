@@ -34,6 +34,7 @@ func RegisterTaskQueueMetrics(metricStorage *metric_storage.MetricStorage) {
 	)
 
 	metricStorage.RegisterGauge("{PREFIX}tasks_queue_length", map[string]string{"queue": ""})
+	metricStorage.RegisterGauge("{PREFIX}tasks_queue_in_flight", map[string]string{"queue": ""})
 }
 
 // metrics for kube_event_manager
@@ -126,9 +127,30 @@ func RegisterHookMetrics(metricStorage *metric_storage.MetricStorage) {
 	// Max RSS in bytes.
 	metricStorage.RegisterGauge("{PREFIX}hook_run_max_rss_bytes", labels)
 
-	metricStorage.RegisterCounter("{PREFIX}hook_run_errors_total", labels)
+	// Errors have an additional "reason" label: "error" or "timeout".
+	errorLabels := map[string]string{"reason": ""}
+	for k, v := range labels {
+		errorLabels[k] = v
+	}
+	metricStorage.RegisterCounter("{PREFIX}hook_run_errors_total", errorLabels)
 	metricStorage.RegisterCounter("{PREFIX}hook_run_allowed_errors_total", labels)
+	// Tasks given up on after retries, "action" label is "drop" or "deadLetter".
+	exhaustedLabels := map[string]string{"action": ""}
+	for k, v := range labels {
+		exhaustedLabels[k] = v
+	}
+	metricStorage.RegisterCounter("{PREFIX}tasks_exhausted_total", exhaustedLabels)
 	metricStorage.RegisterCounter("{PREFIX}hook_run_success_total", labels)
 	// hook_run task waiting time
 	metricStorage.RegisterCounter("{PREFIX}task_wait_in_queue_seconds_total", labels)
+
+	// Operations from $KUBERNETES_PATCH_PATH.
+	patchLabels := map[string]string{
+		"hook":      "",
+		"binding":   "",
+		"queue":     "",
+		"operation": "",
+	}
+	metricStorage.RegisterCounter("{PREFIX}hook_kubernetes_patch_operations_total", patchLabels)
+	metricStorage.RegisterCounter("{PREFIX}hook_kubernetes_patch_errors_total", patchLabels)
 }
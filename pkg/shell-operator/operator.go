@@ -7,10 +7,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/hashicorp/go-multierror"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	uuid "gopkg.in/satori/go.uuid.v1"
 
 	. "github.com/flant/shell-operator/pkg/hook/binding_context"
@@ -21,15 +28,22 @@ import (
 
 	"github.com/flant/shell-operator/pkg/app"
 	"github.com/flant/shell-operator/pkg/debug"
+	"github.com/flant/shell-operator/pkg/executor"
 	"github.com/flant/shell-operator/pkg/hook"
 	"github.com/flant/shell-operator/pkg/hook/controller"
+	"github.com/flant/shell-operator/pkg/hook/history"
 	"github.com/flant/shell-operator/pkg/kube"
+	"github.com/flant/shell-operator/pkg/kube/event_recorder"
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
 	"github.com/flant/shell-operator/pkg/kube_events_manager"
+	"github.com/flant/shell-operator/pkg/leader_election"
 	"github.com/flant/shell-operator/pkg/metric_storage"
 	"github.com/flant/shell-operator/pkg/schedule_manager"
 	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/dead_letter"
 	"github.com/flant/shell-operator/pkg/task/dump"
 	"github.com/flant/shell-operator/pkg/task/queue"
+	"github.com/flant/shell-operator/pkg/tracing"
 	utils_file "github.com/flant/shell-operator/pkg/utils/file"
 	utils "github.com/flant/shell-operator/pkg/utils/labels"
 	"github.com/flant/shell-operator/pkg/utils/measure"
@@ -50,23 +64,54 @@ type ShellOperator struct {
 	// separate metric storage for hook metrics if separate listen port is configured
 	HookMetricStorage *metric_storage.MetricStorage
 	KubeClient        kube.KubernetesClient
+	ObjectPatcher     *object_patch.ObjectPatcher
 
 	ScheduleManager   schedule_manager.ScheduleManager
 	KubeEventsManager kube_events_manager.KubeEventsManager
 
 	TaskQueues *queue.TaskQueueSet
+	// DeadLetter keeps tasks that are given up on after retries.
+	DeadLetter *dead_letter.Store
+	// DroppedTasks keeps the last tasks that are dropped after retries.
+	DroppedTasks *dead_letter.Store
+	// HookHistory keeps last executions of each hook.
+	HookHistory *history.Store
+	// HookRunWatchers streams log lines of manually started hook runs.
+	HookRunWatchers *HookRunWatchers
 
 	ManagerEventsHandler *ManagerEventsHandler
 
 	HookManager hook.HookManager
+	// GoHooks are registered in HookManager along with hooks from HooksDir.
+	GoHooks []*hook.Hook
 
 	WebhookManager *validating_webhook.WebhookManager
 
+	// EventRecorder is not nil if publishing of Kubernetes Events is enabled.
+	EventRecorder *event_recorder.EventRecorder
+
+	// LeaderElector is not nil if leader election is enabled.
+	LeaderElector      *leader_election.LeaderElector
+	ProcessedSnapshots *leader_election.ProcessedSnapshots
+	// queuesStarted is 0 for a standby replica.
+	queuesStarted int32
+
 	DebugServer *debug.Server
+
+	// stopTracing flushes spans to the OTLP receiver.
+	stopTracing func(context.Context) error
+
+	// shutdownRequested is closed when the operator should exit, e.g. when leadership is lost.
+	shutdownRequested     chan struct{}
+	shutdownRequestedOnce sync.Once
+	shutdownOnce          sync.Once
 }
 
 func NewShellOperator() *ShellOperator {
-	return &ShellOperator{}
+	return &ShellOperator{
+		HookRunWatchers:   NewHookRunWatchers(),
+		shutdownRequested: make(chan struct{}),
+	}
 }
 
 func (op *ShellOperator) WithHooksDir(dir string) {
@@ -88,6 +133,11 @@ func (op *ShellOperator) Stop() {
 	}
 }
 
+// WithGoHooks adds hooks created with hook.NewGoHook. Should be called before InitHookManager.
+func (op *ShellOperator) WithGoHooks(hooks ...*hook.Hook) {
+	op.GoHooks = append(op.GoHooks, hooks...)
+}
+
 func (op *ShellOperator) WithKubernetesClient(klient kube.KubernetesClient) {
 	op.KubeClient = klient
 }
@@ -162,6 +212,20 @@ func (op *ShellOperator) Init() (err error) {
 	}
 	log.Infof("Use temporary dir: %s", op.TempDir)
 
+	op.stopTracing, err = tracing.Init(op.ctx, tracing.Config{
+		Endpoint:    app.TracingOtlpEndpoint,
+		Insecure:    app.TracingOtlpInsecure,
+		ServiceName: app.TracingServiceName,
+		SampleRatio: app.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Errorf("MAIN Fatal: initialize tracing: %s", err)
+		return err
+	}
+	if app.TracingOtlpEndpoint != "" {
+		log.Infof("Export traces to %s", app.TracingOtlpEndpoint)
+	}
+
 	op.DebugServer = debug.NewServer()
 	op.DebugServer.WithPrefix("/debug")
 	op.DebugServer.WithSocketPath(app.DebugUnixSocket)
@@ -185,10 +249,27 @@ func (op *ShellOperator) Init() (err error) {
 		}
 	}
 
+	op.ObjectPatcher = object_patch.NewObjectPatcher(op.KubeClient)
+
+	err = op.InitEventRecorder()
+	if err != nil {
+		log.Errorf("MAIN Fatal: initialize event recorder: %s", err)
+		return err
+	}
+
 	// Initialize the task queues set with the "main" queue.
 	op.TaskQueues = queue.NewTaskQueueSet()
 	op.TaskQueues.WithContext(op.ctx)
 	op.TaskQueues.WithMetricStorage(op.MetricStorage)
+	op.DeadLetter = dead_letter.NewStore()
+	err = op.InitTaskStore()
+	if err != nil {
+		log.Errorf("MAIN Fatal: initialize task store: %s", err)
+		return err
+	}
+	op.DroppedTasks = dead_letter.NewStore().WithMaxEntries(MaxDroppedTasks)
+	op.HookHistory = history.NewStore().WithMaxEntries(app.HookHistorySize)
+	log.AddHook(op.HookRunWatchers)
 
 	// Initialize schedule manager.
 	op.ScheduleManager = schedule_manager.NewScheduleManager()
@@ -199,6 +280,11 @@ func (op *ShellOperator) Init() (err error) {
 	op.KubeEventsManager.WithKubeClient(op.KubeClient)
 	op.KubeEventsManager.WithContext(op.ctx)
 	op.KubeEventsManager.WithMetricStorage(op.MetricStorage)
+	if app.KubeSnapshotDir != "" {
+		op.KubeEventsManager.WithSnapshotStore(
+			kube_events_manager.NewSnapshotStore(app.KubeSnapshotDir).WithSaveInterval(app.KubeSnapshotSaveInterval))
+		log.Infof("Save snapshots of 'kubernetes' bindings to %s", app.KubeSnapshotDir)
+	}
 
 	// Initialize events handler that emit tasks to run hooks
 	op.ManagerEventsHandler = NewManagerEventsHandler()
@@ -218,6 +304,15 @@ func (op *ShellOperator) InitHookManager() (err error) {
 	op.HookManager.WithKubeEventManager(op.KubeEventsManager)
 	op.HookManager.WithScheduleManager(op.ScheduleManager)
 	op.HookManager.WithWebhookManager(op.WebhookManager)
+	op.HookManager.WithGoHooks(op.GoHooks...)
+
+	executor.TerminationGracePeriod = app.HookTimeoutGracePeriod
+	hookRuntime, err := hook.NewHookRuntime(app.HookRuntime)
+	if err != nil {
+		return err
+	}
+	op.HookManager.WithRuntime(hookRuntime)
+
 	// Search hooks and load their configurations
 	err = op.HookManager.Init()
 	if err != nil {
@@ -227,6 +322,10 @@ func (op *ShellOperator) InitHookManager() (err error) {
 
 	// Define event handlers for schedule event and kubernetes event.
 	op.ManagerEventsHandler.WithKubeEventHandler(func(kubeEvent KubeEvent) []task.Task {
+		// A standby replica only keeps snapshots up to date.
+		if !op.IsQueuesStarted() {
+			return nil
+		}
 		logLabels := map[string]string{
 			"event.id": uuid.NewV4().String(),
 			"binding":  string(OnKubernetesEvent),
@@ -242,6 +341,7 @@ func (op *ShellOperator) InitHookManager() (err error) {
 					BindingType:    OnKubernetesEvent,
 					BindingContext: info.BindingContext,
 					AllowFailure:   info.AllowFailure,
+					QueuePolicy:    info.QueuePolicy,
 					Binding:        info.Binding,
 					Group:          info.Group,
 				}).
@@ -268,6 +368,7 @@ func (op *ShellOperator) InitHookManager() (err error) {
 					BindingType:    Schedule,
 					BindingContext: info.BindingContext,
 					AllowFailure:   info.AllowFailure,
+					QueuePolicy:    info.QueuePolicy,
 					Binding:        info.Binding,
 					Group:          info.Group,
 				}).
@@ -282,13 +383,14 @@ func (op *ShellOperator) InitHookManager() (err error) {
 	return nil
 }
 
-// InitWebhookManager adds kubernetesValidating hooks
-// to a WebhookManager and set a validating event handler.
+// InitWebhookManager adds kubernetesValidating, kubernetesMutating and
+// kubernetesCustomResourceConversion hooks to a WebhookManager and set event handlers.
 func (op *ShellOperator) InitWebhookManager() (err error) {
 	// Initialize validating webhooks manager
 	op.WebhookManager.WithKubeClient(op.KubeClient)
 	op.WebhookManager.Namespace = app.Namespace
 	op.WebhookManager.ConfigurationName = app.ValidatingWebhookSettings.ConfigurationName
+	op.WebhookManager.MutatingConfigurationName = app.ValidatingWebhookSettings.MutatingConfigurationName
 	op.WebhookManager.ServiceName = app.ValidatingWebhookSettings.ServiceName
 
 	err = op.WebhookManager.Init()
@@ -298,7 +400,9 @@ func (op *ShellOperator) InitWebhookManager() (err error) {
 
 	// error is only for OnStartup hooks.
 	hookNames, _ := op.HookManager.GetHooksInOrder(KubernetesValidating)
-	if len(hookNames) == 0 {
+	mutatingHookNames, _ := op.HookManager.GetHooksInOrder(KubernetesMutating)
+	conversionHookNames, _ := op.HookManager.GetHooksInOrder(KubernetesConversion)
+	if len(hookNames) == 0 && len(mutatingHookNames) == 0 && len(conversionHookNames) == 0 {
 		return
 	}
 
@@ -306,6 +410,14 @@ func (op *ShellOperator) InitWebhookManager() (err error) {
 		h := op.HookManager.GetHook(hookName)
 		h.HookController.EnableValidatingBindings()
 	}
+	for _, hookName := range mutatingHookNames {
+		h := op.HookManager.GetHook(hookName)
+		h.HookController.EnableMutatingBindings()
+	}
+	for _, hookName := range conversionHookNames {
+		h := op.HookManager.GetHook(hookName)
+		h.HookController.EnableConversionBindings()
+	}
 
 	// Define handler for ValidatingEvent
 	op.WebhookManager.WithValidatingEventHandler(func(event ValidatingEvent) (*ValidatingResponse, error) {
@@ -342,8 +454,10 @@ func (op *ShellOperator) InitWebhookManager() (err error) {
 		}
 
 		res := op.TaskHandler(tasks[0])
+		hookName := HookMetadataAccessor(tasks[0]).HookName
 
 		if res.Status == "Fail" {
+			op.RecordAdmissionDenied(hookName, event.Review, "Hook failed")
 			return &ValidatingResponse{
 				Allowed: false,
 				Message: "Hook failed",
@@ -356,9 +470,120 @@ func (op *ShellOperator) InitWebhookManager() (err error) {
 			logEntry.Errorf("'validatingResponse' task prop is not of type *ValidatingResponse: %T", validatingProp)
 			return nil, fmt.Errorf("hook task prop error")
 		}
+		if !validatingResponse.Allowed {
+			op.RecordAdmissionDenied(hookName, event.Review, validatingResponse.Message)
+		}
 		return validatingResponse, nil
 	})
 
+	// Define handler for MutatingEvent
+	op.WebhookManager.WithMutatingEventHandler(func(event MutatingEvent) (*MutatingResponse, error) {
+		logLabels := map[string]string{
+			"event.id": uuid.NewV4().String(),
+			"binding":  string(KubernetesMutating),
+		}
+		logEntry := log.WithFields(utils.LabelsToLogFields(logLabels))
+		logEntry.Debugf("Handle '%s' event '%s' '%s'", string(KubernetesMutating), event.ConfigurationId, event.WebhookId)
+
+		var tasks []task.Task
+		op.HookManager.HandleMutatingEvent(event, func(hook *hook.Hook, info controller.BindingExecutionInfo) {
+			newTask := task.NewTask(HookRun).
+				WithMetadata(HookMetadata{
+					HookName:       hook.Name,
+					BindingType:    KubernetesMutating,
+					BindingContext: info.BindingContext,
+					AllowFailure:   info.AllowFailure,
+					Binding:        info.Binding,
+					Group:          info.Group,
+				}).
+				WithLogLabels(logLabels)
+			tasks = append(tasks, newTask)
+		})
+
+		// Assert exactly one task is created.
+		if len(tasks) == 0 {
+			logEntry.Errorf("Possible bug!!! No hook found for '%s' event '%s' '%s'", string(KubernetesMutating), event.ConfigurationId, event.WebhookId)
+			return nil, fmt.Errorf("no hook found for '%s' '%s'", event.ConfigurationId, event.WebhookId)
+		}
+
+		if len(tasks) > 1 {
+			logEntry.Errorf("Possible bug!!! %d hooks found for '%s' event '%s' '%s'", len(tasks), string(KubernetesMutating), event.ConfigurationId, event.WebhookId)
+		}
+
+		res := op.TaskHandler(tasks[0])
+		hookName := HookMetadataAccessor(tasks[0]).HookName
+
+		if res.Status == "Fail" {
+			op.RecordAdmissionDenied(hookName, event.Review, "Hook failed")
+			return &MutatingResponse{
+				Allowed: false,
+				Message: "Hook failed",
+			}, nil
+		}
+
+		mutatingProp := tasks[0].GetProp("mutatingResponse")
+		mutatingResponse, ok := mutatingProp.(*MutatingResponse)
+		if !ok {
+			logEntry.Errorf("'mutatingResponse' task prop is not of type *MutatingResponse: %T", mutatingProp)
+			return nil, fmt.Errorf("hook task prop error")
+		}
+		if !mutatingResponse.Allowed {
+			op.RecordAdmissionDenied(hookName, event.Review, mutatingResponse.Message)
+		}
+		return mutatingResponse, nil
+	})
+
+	// Define handler for ConversionEvent
+	op.WebhookManager.WithConversionEventHandler(func(event ConversionEvent) (*ConversionHookResponse, error) {
+		logLabels := map[string]string{
+			"event.id": uuid.NewV4().String(),
+			"binding":  string(KubernetesConversion),
+		}
+		logEntry := log.WithFields(utils.LabelsToLogFields(logLabels))
+		logEntry.Debugf("Handle '%s' event for crd '%s'", string(KubernetesConversion), event.CrdName)
+
+		var tasks []task.Task
+		op.HookManager.HandleConversionEvent(event, func(hook *hook.Hook, info controller.BindingExecutionInfo) {
+			newTask := task.NewTask(HookRun).
+				WithMetadata(HookMetadata{
+					HookName:       hook.Name,
+					BindingType:    KubernetesConversion,
+					BindingContext: info.BindingContext,
+					AllowFailure:   info.AllowFailure,
+					Binding:        info.Binding,
+					Group:          info.Group,
+				}).
+				WithLogLabels(logLabels)
+			tasks = append(tasks, newTask)
+		})
+
+		// Assert exactly one task is created.
+		if len(tasks) == 0 {
+			logEntry.Errorf("Possible bug!!! No hook found for '%s' event for crd '%s'", string(KubernetesConversion), event.CrdName)
+			return nil, fmt.Errorf("no hook found for crd '%s'", event.CrdName)
+		}
+
+		if len(tasks) > 1 {
+			logEntry.Errorf("Possible bug!!! %d hooks found for '%s' event for crd '%s'", len(tasks), string(KubernetesConversion), event.CrdName)
+		}
+
+		res := op.TaskHandler(tasks[0])
+
+		if res.Status == "Fail" {
+			return &ConversionHookResponse{
+				FailedMessage: "Hook failed",
+			}, nil
+		}
+
+		conversionProp := tasks[0].GetProp("conversionResponse")
+		conversionResponse, ok := conversionProp.(*ConversionHookResponse)
+		if !ok {
+			logEntry.Errorf("'conversionResponse' task prop is not of type *ConversionHookResponse: %T", conversionProp)
+			return nil, fmt.Errorf("hook task prop error")
+		}
+		return conversionResponse, nil
+	})
+
 	err = op.WebhookManager.Start()
 	if err != nil {
 		log.Errorf("Webhook start: %v", err)
@@ -372,18 +597,43 @@ func (op *ShellOperator) Start() {
 	// Start emit "live" metrics
 	op.RunMetrics()
 
+	// Managers are generating events. This go-routine handles all events and converts them into queued tasks.
+	// Start it before start all informers to catch all kubernetes events (#42)
+	op.ManagerEventsHandler.Start()
+
+	if op.LeaderElector != nil {
+		// Queues are started when this replica becomes a leader.
+		op.StartStandby()
+		op.LeaderElector.Start()
+		return
+	}
+
+	op.StartQueues()
+}
+
+// StartQueues fills the main queue with initial tasks and starts queues and schedules.
+func (op *ShellOperator) StartQueues() {
 	// Prepopulate main queue with onStartup tasks and enable kubernetes bindings tasks.
 	op.PrepopulateMainQueue(op.TaskQueues)
-	// Start main task queue handler
-	op.TaskQueues.StartMain()
 	op.InitAndStartHookQueues()
+	// Add tasks saved before restart.
+	op.RestoreQueues()
+	op.RestoreDeadLetter()
+	op.TaskQueues.StartPersistence()
+	op.DeadLetter.StartPersistence(op.ctx)
 
-	// Managers are generating events. This go-routine handles all events and converts them into queued tasks.
-	// Start it before start all informers to catch all kubernetes events (#42)
-	op.ManagerEventsHandler.Start()
+	// Queue events only after queues are created.
+	atomic.StoreInt32(&op.queuesStarted, 1)
+
+	// Start main task queue handler
+	op.TaskQueues.StartMain()
 
 	// Unlike KubeEventsManager, ScheduleManager has one go-routine.
 	op.ScheduleManager.Start()
+
+	if app.HooksReloadInterval > 0 {
+		op.StartHooksWatcher(app.HooksReloadInterval)
+	}
 }
 
 // TaskHandler
@@ -392,10 +642,20 @@ func (op *ShellOperator) TaskHandler(t task.Task) queue.TaskResult {
 	var hookMeta = HookMetadataAccessor(t)
 	var res queue.TaskResult
 
+	// Hook can be removed by ReloadHooks task.
+	if t.GetType() != ReloadHooks && op.HookManager.GetHook(hookMeta.HookName) == nil {
+		logEntry.Warnf("Hook '%s' is removed, skip task %s", hookMeta.HookName, t.GetDescription())
+		res.Status = "Success"
+		return res
+	}
+
 	switch t.GetType() {
 	case HookRun:
 		res = op.TaskHandleHookRun(t)
 
+	case ReloadHooks:
+		res = op.TaskHandleReloadHooks(t)
+
 	case EnableKubernetesBindings:
 		res = op.TaskHandleEnableKubernetesBindings(t)
 
@@ -444,6 +704,10 @@ func (op *ShellOperator) TaskHandleEnableKubernetesBindings(t task.Task) queue.T
 
 	// Run hook for each binding with Synchronization binding context. Ignore queue name here, execute in main queue.
 	err := taskHook.HookController.HandleEnableKubernetesBindings(func(info controller.BindingExecutionInfo) {
+		if op.SkipSynchronization(taskHook.Name, info) {
+			taskLogEntry.Infof("Snapshot for binding '%s' is not changed since the previous leader, skip Synchronization", info.Binding)
+			return
+		}
 		newTask := task.NewTask(HookRun).
 			WithMetadata(HookMetadata{
 				HookName:       taskHook.Name,
@@ -506,21 +770,44 @@ func (op *ShellOperator) TaskHandleHookRun(t task.Task) queue.TaskResult {
 	hookLogLabels["binding"] = hookMeta.Binding
 	hookLogLabels["event"] = string(hookMeta.BindingType)
 	hookLogLabels["task"] = "HookRun"
+	hookLogLabels["task.id"] = t.GetId()
 	hookLogLabels["queue"] = t.GetQueueName()
 
 	taskLogEntry := log.WithFields(utils.LabelsToLogFields(hookLogLabels))
 	taskLogEntry.Info("Execute hook")
 
+	parentSpanContext := tracing.TaskSpanContext(t)
+	_, waitSpan := tracing.StartAt(parentSpanContext, "WaitInQueue", t.GetQueuedAt())
+	waitSpan.End()
+	ctx, span := tracing.Start(parentSpanContext, "HookRun", trace.WithAttributes(
+		attribute.String("hook", hookMeta.HookName),
+		attribute.String("binding", hookMeta.Binding),
+		attribute.String("queue", t.GetQueueName()),
+		attribute.String("task.id", t.GetId()),
+		attribute.Int("failure.count", t.GetFailureCount()),
+	))
+	defer span.End()
+
 	taskHook := op.HookManager.GetHook(hookMeta.HookName)
-	if taskHook.Config.Version == "v1" {
-		bcs := op.CombineBindingContextForHook(op.TaskQueues.GetByName(t.GetQueueName()), t, nil)
+	// Tasks for onStartup and webhooks have no queue.
+	// Parallel queues have no head task to combine with, tasks for different objects are handled at once.
+	q := op.TaskQueues.GetByName(t.GetQueueName())
+	if taskHook.Config.Version == "v1" && q != nil && !q.IsParallel() {
+		// Manual runs with watchers are not combined to report results for the task.
+		_, combineSpan := tracing.Start(span.SpanContext(), "CombineBindingContext")
+		bcs := op.CombineBindingContextForHook(q, t, op.hookRunStopCombineFn(taskHook, hookMeta))
+		combineSpan.SetAttributes(attribute.Int("binding.contexts", len(bcs)))
+		combineSpan.End()
 		if bcs != nil {
 			hookMeta.BindingContext = bcs
 			t.UpdateMetadata(hookMeta)
 		}
 	}
 
-	result, err := taskHook.Run(hookMeta.BindingType, hookMeta.BindingContext, hookLogLabels)
+	startedAt := time.Now()
+	result, err := taskHook.Run(ctx, hookMeta.BindingType, hookMeta.BindingContext, hookLogLabels)
+	historyEntry := NewHookHistoryEntry(t, startedAt, result, err)
+	span.SetAttributes(attribute.Int("objects", historyEntry.ObjectCount))
 
 	if result != nil && result.Usage != nil {
 		taskLogEntry.Infof("Usage: %+v", result.Usage)
@@ -535,26 +822,67 @@ func (op *ShellOperator) TaskHandleHookRun(t task.Task) queue.TaskResult {
 			t.SetProp("validatingResponse", result.ValidatingResponse)
 			taskLogEntry.Infof("ValidatingResponse from hook: %s", result.ValidatingResponse.Dump())
 		}
-		err = op.HookMetricStorage.SendBatch(result.Metrics, map[string]string{
-			"hook": hookMeta.HookName,
-		})
+		// Save mutatingResponse in task props for future use.
+		if result.MutatingResponse != nil {
+			t.SetProp("mutatingResponse", result.MutatingResponse)
+			taskLogEntry.Infof("MutatingResponse from hook: %s", result.MutatingResponse.Dump())
+		}
+		// Save conversionResponse in task props for future use.
+		if result.ConversionResponse != nil {
+			t.SetProp("conversionResponse", result.ConversionResponse)
+			taskLogEntry.Infof("ConversionResponse from hook: %s", result.ConversionResponse.Dump())
+		}
+		err = op.ApplyKubernetesPatchOperations(result.KubernetesPatchOperations, t.GetFailureCount() > 0, metricLabels)
+		if err == nil {
+			err = op.HookMetricStorage.SendBatch(result.Metrics, map[string]string{
+				"hook": hookMeta.HookName,
+			})
+		}
 	}
 
+	runBinding := hookMeta.Binding
+	if runBinding == "" {
+		runBinding = string(hookMeta.BindingType)
+	}
+	taskHook.RecordRun(runBinding, err)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if err != nil {
+		historyEntry.Error = err.Error()
+	}
+	op.HookHistory.Add(historyEntry)
+
 	success := 0.0
 	errors := 0.0
 	allowed := 0.0
+	// A reason label for hook_run_errors_total.
+	errorReason := "error"
+	if result != nil && result.TimedOut {
+		errorReason = "timeout"
+	}
 	var res queue.TaskResult
+	// An exhausted task is removed from the queue, but its events are not processed.
+	exhausted := false
 	if err != nil {
 		if hookMeta.AllowFailure {
 			allowed = 1.0
 			taskLogEntry.Infof("Hook failed, but allowed to fail: %v", err)
 			res.Status = "Success"
+		} else if retry := taskHook.Config.BindingRetryPolicy(hookMeta.Binding); IsRetryExhausted(retry, t) {
+			errors = 1.0
+			taskLogEntry.Errorf("Hook failed %d times, give up. Error: %s", t.GetFailureCount()+1, err)
+			op.HandleExhaustedTask(t, retry, err)
+			exhausted = true
+			res.Status = "Success"
 		} else {
 			errors = 1.0
 			t.UpdateFailureMessage(err.Error())
 			t.WithQueuedAt(time.Now()) // Reset queueAt for correct results in 'task_wait_in_queue' metric.
 			taskLogEntry.Errorf("Hook failed. Will retry after delay. Failed count is %d. Error: %s", t.GetFailureCount()+1, err)
+			op.RecordHookEvent(hookMeta, event_recorder.ReasonHookFailed, err.Error())
 			res.Status = "Fail"
+			res.DelayBeforeNextTask = RetryDelay(retry, t)
 		}
 	} else {
 		success = 1.0
@@ -562,12 +890,69 @@ func (op *ShellOperator) TaskHandleHookRun(t task.Task) queue.TaskResult {
 		res.Status = "Success"
 	}
 
+	if res.Status == "Success" && !exhausted && hookMeta.BindingType == OnKubernetesEvent {
+		op.RecordProcessedSnapshots(hookMeta.HookName, hookMeta.BindingContext)
+	}
+
 	op.MetricStorage.CounterAdd("{PREFIX}hook_run_allowed_errors_total", allowed, metricLabels)
-	op.MetricStorage.CounterAdd("{PREFIX}hook_run_errors_total", errors, metricLabels)
+	errorLabels := map[string]string{"reason": errorReason}
+	for k, v := range metricLabels {
+		errorLabels[k] = v
+	}
+	op.MetricStorage.CounterAdd("{PREFIX}hook_run_errors_total", errors, errorLabels)
 	op.MetricStorage.CounterAdd("{PREFIX}hook_run_success_total", success, metricLabels)
+	// Watchers wait for a final result, the failed task is retried.
+	if res.Status == "Success" {
+		op.HookRunWatchers.Done(t, err)
+	}
 	return res
 }
 
+// hookRunStopCombineFn returns a function to stop combining binding contexts on a watched task
+// or on a task with another retry policy: the combined task is retried with the policy of the binding of the first task.
+func (op *ShellOperator) hookRunStopCombineFn(taskHook *hook.Hook, hookMeta HookMetadata) func(tsk task.Task) bool {
+	retry := taskHook.Config.BindingRetryPolicy(hookMeta.Binding)
+	return func(tsk task.Task) bool {
+		if op.HookRunWatchers.IsWatched(tsk) {
+			return true
+		}
+		nextRetry := taskHook.Config.BindingRetryPolicy(HookMetadataAccessor(tsk).Binding)
+		return !reflect.DeepEqual(retry, nextRetry)
+	}
+}
+
+// ApplyKubernetesPatchOperations executes operations from $KUBERNETES_PATCH_PATH file.
+// All operations are executed, errors are combined. Task will be repeated on error.
+// Operations of a retried task may be already applied by the previous run, so
+// Create operations for existing objects are successful for them.
+func (op *ShellOperator) ApplyKubernetesPatchOperations(specs []object_patch.OperationSpec, retried bool, metricLabels map[string]string) error {
+	var allErrs *multierror.Error
+	for _, spec := range specs {
+		labels := map[string]string{
+			"operation": string(spec.Operation),
+		}
+		for k, v := range metricLabels {
+			labels[k] = v
+		}
+		var err error
+		if retried {
+			err = op.ObjectPatcher.ExecuteRetriedOperation(spec)
+		} else {
+			err = op.ObjectPatcher.ExecuteOperation(spec)
+		}
+		if err != nil {
+			op.MetricStorage.CounterAdd("{PREFIX}hook_kubernetes_patch_errors_total", 1.0, labels)
+			allErrs = multierror.Append(allErrs, fmt.Errorf("%s: %v", spec, err))
+			continue
+		}
+		op.MetricStorage.CounterAdd("{PREFIX}hook_kubernetes_patch_operations_total", 1.0, labels)
+	}
+	if allErrs.ErrorOrNil() != nil {
+		return fmt.Errorf("apply kubernetes patch operations: %v", allErrs)
+	}
+	return nil
+}
+
 // CombineBindingContextForHook combines binding contexts from a sequence of task with similar
 // hook name and task type into array of binding context and delete excess tasks from queue.
 // Also, compacts sequences of binding contexts with similar group.
@@ -726,13 +1111,18 @@ func (op *ShellOperator) PrepopulateMainQueue(tqs *queue.TaskQueueSet) {
 
 // CreateQueues create all queues defined in hooks
 func (op *ShellOperator) InitAndStartHookQueues() {
+	concurrency := op.HookQueuesConcurrency()
+
 	schHooks, _ := op.HookManager.GetHooksInOrder(Schedule)
 	for _, hookName := range schHooks {
 		h := op.HookManager.GetHook(hookName)
 		for _, hookBinding := range h.Config.Schedules {
 			if op.TaskQueues.GetByName(hookBinding.Queue) == nil {
 				op.TaskQueues.NewNamedQueue(hookBinding.Queue, op.TaskHandler)
-				op.TaskQueues.GetByName(hookBinding.Queue).Start()
+				op.TaskQueues.GetByName(hookBinding.Queue).
+					WithConcurrency(concurrency[hookBinding.Queue]).
+					WithTaskKeysFn(HookRunTaskKeys).
+					Start()
 			}
 		}
 	}
@@ -743,10 +1133,26 @@ func (op *ShellOperator) InitAndStartHookQueues() {
 		for _, hookBinding := range h.Config.OnKubernetesEvents {
 			if op.TaskQueues.GetByName(hookBinding.Queue) == nil {
 				op.TaskQueues.NewNamedQueue(hookBinding.Queue, op.TaskHandler)
-				op.TaskQueues.GetByName(hookBinding.Queue).Start()
+				op.TaskQueues.GetByName(hookBinding.Queue).
+					WithConcurrency(concurrency[hookBinding.Queue]).
+					WithTaskKeysFn(HookRunTaskKeys).
+					Start()
 			}
 		}
 	}
+
+	// Hooks can be reloaded, update concurrency for running queues.
+	for name, n := range concurrency {
+		q := op.TaskQueues.GetByName(name)
+		if q == nil || q.GetConcurrency() == n {
+			continue
+		}
+		if q.IsParallel() && n > 1 {
+			q.WithConcurrency(n)
+		} else {
+			log.Warnf("Concurrency for queue '%s' is changed to %d, restart Shell-operator to apply changes.", name, n)
+		}
+	}
 }
 
 func (op *ShellOperator) RunMetrics() {
@@ -764,6 +1170,8 @@ func (op *ShellOperator) RunMetrics() {
 			op.TaskQueues.Iterate(func(queue *queue.TaskQueue) {
 				queueLen := float64(queue.Length())
 				op.MetricStorage.GaugeSet("{PREFIX}tasks_queue_length", queueLen, map[string]string{"queue": queue.Name})
+				inFlight := float64(queue.InFlight())
+				op.MetricStorage.GaugeSet("{PREFIX}tasks_queue_in_flight", inFlight, map[string]string{"queue": queue.Name})
 			})
 			time.Sleep(5 * time.Second)
 		}
@@ -780,6 +1188,14 @@ func (op *ShellOperator) SetupDebugServerHandles() {
 		structured_logger.GetLogEntry(request).Debugf("queue list using format %s", format)
 		_, _ = writer.Write([]byte(dump.TaskQueueSetToText(op.TaskQueues)))
 	})
+
+	op.SetupHooksReloadHandles()
+	op.SetupDeadLetterHandles()
+	op.SetupHookRunHandles()
+	op.SetupHookSnapshotsHandles()
+	op.SetupQueueControlHandles()
+	op.SetupHookInventoryHandles()
+	op.SetupHookHistoryHandles()
 }
 
 func (op *ShellOperator) SetupHttpServerHandles() {
@@ -882,16 +1298,83 @@ func InitAndStart(operator *ShellOperator) error {
 		log.Errorf("INIT WebhookManager failed: %s", err)
 	}
 
+	err = operator.InitLeaderElection()
+	if err != nil {
+		log.Errorf("INIT leader election failed: %s", err)
+		return err
+	}
+
 	operator.Start()
 
 	return nil
 }
 
+// RequestShutdown asks the main goroutine to stop the operator with Shutdown.
+func (op *ShellOperator) RequestShutdown() {
+	op.shutdownRequestedOnce.Do(func() {
+		close(op.shutdownRequested)
+	})
+}
+
+// ShutdownRequested returns a channel that is closed by RequestShutdown.
+func (op *ShellOperator) ShutdownRequested() <-chan struct{} {
+	return op.shutdownRequested
+}
+
 // Shutdown pause kubernetes events handling and stop queues. Wait for queues to stop.
+// Concurrent calls wait for the first one to finish.
 func (op *ShellOperator) Shutdown() {
+	op.shutdownOnce.Do(op.shutdown)
+}
+
+func (op *ShellOperator) shutdown() {
+	op.stopQueuesAndHooks()
+
+	if op.LeaderElector != nil {
+		if op.LeaderElector.IsLeader() {
+			err := op.ProcessedSnapshots.Save()
+			if err != nil {
+				log.Errorf("Save processed snapshots: %v", err)
+			}
+		}
+		// Release the lease for a fast failover.
+		op.LeaderElector.Stop()
+	}
+
+	op.EventRecorder.Stop()
+
+	if op.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := op.stopTracing(ctx)
+		if err != nil {
+			log.Errorf("Stop tracing: %v", err)
+		}
+	}
+}
+
+func (op *ShellOperator) stopQueuesAndHooks() {
 	op.ScheduleManager.Stop()
+	// The events handler is running: informers are paused after their events are consumed.
 	op.KubeEventsManager.PauseHandleEvents()
+	// Queue events that are already in caches of informers, so snapshots
+	// do not contain changes that are neither handled nor persisted as tasks.
+	op.ManagerEventsHandler.Stop()
+	op.ManagerEventsHandler.HandlePendingKubeEvents()
+	// Caches are not changed after pause.
+	op.KubeEventsManager.SaveSnapshots()
 	op.TaskQueues.Stop()
 	// Wait for queues to stop, but no more than 10 seconds
 	op.TaskQueues.WaitStopWithTimeout(WaitQueuesTimeout)
+	// Save tasks that are not handled yet.
+	err := op.TaskQueues.Persist()
+	if err != nil {
+		log.Errorf("Persist queues: %v", err)
+	}
+	err = op.DeadLetter.Persist()
+	if err != nil {
+		log.Errorf("Persist dead letter queue: %v", err)
+	}
+	// Stop long-lived hook processes.
+	op.HookManager.Stop()
 }
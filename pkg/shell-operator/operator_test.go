@@ -2,12 +2,16 @@ package shell_operator
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/flant/shell-operator/pkg/hook/types"
 	. "github.com/onsi/gomega"
 
 	"github.com/flant/shell-operator/pkg/hook/binding_context"
+	"github.com/flant/shell-operator/pkg/hook/history"
 	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
 	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
 	"github.com/flant/shell-operator/pkg/task"
@@ -428,3 +432,78 @@ func Test_CombineBindingContext_Group_Type(t *testing.T) {
 	g.Expect(bcList[4].Type).Should(Equal(TypeEvent))
 	g.Expect(bcList[4].Metadata.Group).Should(Equal("pods"), "bc: %+v", bcList[4])
 }
+
+// onStartup tasks are queued without a queue name.
+func Test_TaskHandleHookRun_OnStartup(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "hook_run_on_startup")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	op := NewShellOperator()
+	op.HookManager = initHookManager(g, tmpDir, `{"configVersion":"v1","onStartup":1}`)
+	op.HookHistory = history.NewStore()
+	op.TaskQueues = queue.NewTaskQueueSet()
+	op.TaskQueues.WithContext(context.Background())
+	op.TaskQueues.NewNamedQueue("main", func(tsk task.Task) queue.TaskResult {
+		return queue.TaskResult{Status: "Success"}
+	})
+
+	tsk := task.NewTask(HookRun).
+		WithMetadata(HookMetadata{
+			HookName:    "hook.sh",
+			BindingType: types.OnStartup,
+			BindingContext: []hook.BindingContext{
+				{Binding: string(types.OnStartup)},
+			},
+		})
+
+	var res queue.TaskResult
+	g.Expect(func() {
+		res = op.TaskHandleHookRun(tsk)
+	}).ShouldNot(Panic())
+	g.Expect(res.Status).Should(Equal("Success"))
+}
+
+// Watchers of a manual run are not notified about a failed run that will be retried.
+func Test_TaskHandleHookRun_Watchers(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "hook_run_watchers")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	op := NewShellOperator()
+	op.HookManager = initHookManager(g, tmpDir, `{"configVersion":"v1","schedule":[{"name":"every-minute","crontab":"* * * * *"}]}`)
+	g.Expect(ioutil.WriteFile(filepath.Join(tmpDir, "hooks", "hook.sh"), []byte("#!/usr/bin/env bash\nexit 1\n"), 0755)).Should(Succeed())
+	op.HookHistory = history.NewStore()
+	op.TaskQueues = queue.NewTaskQueueSet()
+	op.TaskQueues.WithContext(context.Background())
+	op.TaskQueues.NewNamedQueue("main", func(tsk task.Task) queue.TaskResult {
+		return queue.TaskResult{Status: "Success"}
+	})
+
+	hookMeta := HookMetadata{
+		HookName:    "hook.sh",
+		BindingType: types.Schedule,
+		Binding:     "every-minute",
+		BindingContext: []hook.BindingContext{
+			{Binding: "every-minute"},
+		},
+	}
+	tsk := task.NewTask(HookRun).WithQueueName("main").WithMetadata(hookMeta)
+	watcher := op.HookRunWatchers.Watch(tsk.GetId())
+	defer op.HookRunWatchers.Unwatch(watcher)
+
+	res := op.TaskHandleHookRun(tsk)
+	g.Expect(res.Status).Should(Equal("Fail"))
+	g.Expect(watcher.done).Should(BeEmpty())
+
+	hookMeta.AllowFailure = true
+	tsk.UpdateMetadata(hookMeta)
+	res = op.TaskHandleHookRun(tsk)
+	g.Expect(res.Status).Should(Equal("Success"))
+	g.Expect(watcher.done).Should(HaveLen(1))
+	g.Expect(<-watcher.done).Should(HaveOccurred())
+}
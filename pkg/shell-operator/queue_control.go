@@ -0,0 +1,88 @@
+package shell_operator
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+// SetupQueueControlHandles adds endpoints to pause, resume and drain queues and to remove or move tasks.
+func (op *ShellOperator) SetupQueueControlHandles() {
+	op.DebugServer.Router.Post("/queue/{name}/pause", op.withQueue(func(writer http.ResponseWriter, request *http.Request, q *queue.TaskQueue) {
+		q.Pause()
+		log.Warnf("Queue '%s' is paused via debug endpoint", q.Name)
+		_, _ = fmt.Fprintf(writer, "Queue '%s' is paused, %d tasks are queued\n", q.Name, q.Length())
+	}))
+
+	op.DebugServer.Router.Post("/queue/{name}/resume", op.withQueue(func(writer http.ResponseWriter, request *http.Request, q *queue.TaskQueue) {
+		q.Resume()
+		log.Warnf("Queue '%s' is resumed via debug endpoint", q.Name)
+		_, _ = fmt.Fprintf(writer, "Queue '%s' is resumed\n", q.Name)
+	}))
+
+	op.DebugServer.Router.Post("/queue/{name}/drain", op.withQueue(func(writer http.ResponseWriter, request *http.Request, q *queue.TaskQueue) {
+		removed := q.Drain()
+		log.Warnf("Queue '%s' is drained via debug endpoint, %d tasks are removed", q.Name, len(removed))
+		_, _ = fmt.Fprintf(writer, "Queue '%s' is paused, %d tasks are removed:\n", q.Name, len(removed))
+		for _, t := range removed {
+			op.HookRunWatchers.Removed(t)
+			_, _ = fmt.Fprintf(writer, "  %s, id=%s\n", t.GetDescription(), t.GetId())
+		}
+	}))
+
+	op.DebugServer.Router.Post("/queue/{name}/task/{id}/remove", op.withQueue(func(writer http.ResponseWriter, request *http.Request, q *queue.TaskQueue) {
+		id := chi.URLParam(request, "id")
+		t := q.Get(id)
+		err := q.RemovePending(id)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		op.HookRunWatchers.Removed(t)
+		log.Warnf("Task '%s' is removed from queue '%s' via debug endpoint", id, q.Name)
+		_, _ = fmt.Fprintf(writer, "Task '%s' is removed from queue '%s'\n", id, q.Name)
+	}))
+
+	op.DebugServer.Router.Post("/queue/{name}/task/{id}/move", op.withQueue(func(writer http.ResponseWriter, request *http.Request, q *queue.TaskQueue) {
+		id := chi.URLParam(request, "id")
+		position, err := strconv.Atoi(request.URL.Query().Get("position"))
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(writer, "Bad position: %v\n", err)
+			return
+		}
+		err = q.Move(id, position)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintln(writer, err.Error())
+			return
+		}
+		_, _ = fmt.Fprintf(writer, "Task '%s' is moved in queue '%s'\n", id, q.Name)
+	}))
+}
+
+// withQueue returns a handler that gets a queue by the "name" URL parameter.
+func (op *ShellOperator) withQueue(fn func(http.ResponseWriter, *http.Request, *queue.TaskQueue)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name, err := url.PathUnescape(chi.URLParam(request, "name"))
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(writer, "Bad queue name: %v\n", err)
+			return
+		}
+		q := op.TaskQueues.GetByName(name)
+		if q == nil {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(writer, "Queue '%s' is not found\n", name)
+			return
+		}
+		fn(writer, request, q)
+	}
+}
@@ -0,0 +1,184 @@
+package shell_operator
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+// AddLastWithQueuePolicy adds a task to the tail of the queue. If the task has a queue policy,
+// pending tasks of the same hook and binding are deduplicated and limited before adding.
+func AddLastWithQueuePolicy(q *queue.TaskQueue, t task.Task) {
+	if t.GetType() != HookRun {
+		q.AddLast(t)
+		return
+	}
+	policy := HookMetadataAccessor(t).QueuePolicy
+	if policy == nil {
+		q.AddLast(t)
+		return
+	}
+	q.AddLastWithPendingFn(t, func(pending []task.Task) []task.Task {
+		return ApplyQueuePolicy(policy, pending, t)
+	})
+}
+
+// ApplyQueuePolicy returns pending tasks to keep in the queue before adding newTask.
+//
+// 'dedupe: byObject' removes events from pending tasks for objects that are in the newTask.
+// Tasks without binding contexts are removed.
+//
+// If there are more than 'maxPending' tasks, 'dropOldest' removes the oldest tasks and
+// 'compact' moves binding contexts from pending tasks into the newTask.
+func ApplyQueuePolicy(policy *QueuePolicy, pending []task.Task, newTask task.Task) []task.Task {
+	newMeta := HookMetadataAccessor(newTask)
+	isSameBinding := func(t task.Task) bool {
+		if t.GetType() != HookRun {
+			return false
+		}
+		meta := HookMetadataAccessor(t)
+		return meta.HookName == newMeta.HookName && meta.Binding == newMeta.Binding
+	}
+
+	res := pending
+	if policy.Dedupe == DedupeByObject {
+		newIds := map[string]bool{}
+		for _, bc := range newMeta.BindingContext {
+			if id := eventObjectId(bc); id != "" {
+				newIds[id] = true
+			}
+		}
+
+		res = make([]task.Task, 0, len(pending))
+		for _, t := range pending {
+			if !isSameBinding(t) {
+				res = append(res, t)
+				continue
+			}
+			meta := HookMetadataAccessor(t)
+			bcs := make([]BindingContext, 0, len(meta.BindingContext))
+			for _, bc := range meta.BindingContext {
+				if !newIds[eventObjectId(bc)] {
+					bcs = append(bcs, bc)
+				}
+			}
+			if len(bcs) == 0 {
+				log.Debugf("Queue policy: drop task %s, events are outdated", t.GetDescription())
+				continue
+			}
+			if len(bcs) != len(meta.BindingContext) {
+				meta.BindingContext = bcs
+				t.UpdateMetadata(meta)
+			}
+			res = append(res, t)
+		}
+	}
+
+	if policy.MaxPending == 0 {
+		return res
+	}
+
+	count := 0
+	for _, t := range res {
+		if isSameBinding(t) {
+			count++
+		}
+	}
+	overflow := count + 1 - policy.MaxPending
+	if overflow <= 0 {
+		return res
+	}
+
+	kept := make([]task.Task, 0, len(res))
+	compacted := make([]BindingContext, 0)
+	for _, t := range res {
+		if !isSameBinding(t) {
+			kept = append(kept, t)
+			continue
+		}
+		switch policy.OnOverflow {
+		case OverflowCompact:
+			compacted = append(compacted, HookMetadataAccessor(t).BindingContext...)
+			continue
+		default:
+			if overflow > 0 {
+				log.Debugf("Queue policy: drop task %s, more than %d tasks are pending", t.GetDescription(), policy.MaxPending)
+				overflow--
+				continue
+			}
+		}
+		kept = append(kept, t)
+	}
+
+	if len(compacted) > 0 {
+		newMeta.BindingContext = append(compacted, newMeta.BindingContext...)
+		newTask.UpdateMetadata(newMeta)
+	}
+
+	return kept
+}
+
+// eventObjectId returns an id of the object for 'Event' binding context or an empty string.
+// Debounced binding contexts have many objects, they are not deduplicated and not ordered by object.
+func eventObjectId(bc BindingContext) string {
+	if bc.Type != TypeEvent || len(bc.Objects) == 0 || bc.IsBatch() {
+		return ""
+	}
+	return bc.Objects[0].Metadata.ResourceId
+}
+
+// HookQueuesConcurrency returns a maximum concurrency from queue policies of bindings for each named queue.
+func (op *ShellOperator) HookQueuesConcurrency() map[string]int {
+	res := map[string]int{}
+	setConcurrency := func(queueName string, policy *QueuePolicy) {
+		if policy != nil && policy.Concurrency > res[queueName] {
+			res[queueName] = policy.Concurrency
+		}
+	}
+	for _, hookName := range op.HookManager.GetHookNames() {
+		h := op.HookManager.GetHook(hookName)
+		for _, cfg := range h.Config.Schedules {
+			setConcurrency(cfg.Queue, cfg.QueuePolicy)
+		}
+		for _, cfg := range h.Config.OnKubernetesEvents {
+			setConcurrency(cfg.Queue, cfg.QueuePolicy)
+		}
+	}
+	return res
+}
+
+// HookRunTaskKeys returns ordering keys for a task in a parallel queue: ids or namespaces
+// of objects from 'Event' binding contexts. Other tasks have no keys and are handled exclusively.
+func HookRunTaskKeys(t task.Task) []string {
+	if t.GetType() != HookRun {
+		return nil
+	}
+	hookMeta := HookMetadataAccessor(t)
+	orderBy := OrderByObject
+	if hookMeta.QueuePolicy != nil {
+		orderBy = hookMeta.QueuePolicy.OrderBy
+	}
+
+	keys := make([]string, 0, len(hookMeta.BindingContext))
+	for _, bc := range hookMeta.BindingContext {
+		id := eventObjectId(bc)
+		if id == "" {
+			return nil
+		}
+		namespace := strings.SplitN(id, "/", 2)[0]
+		if orderBy == OrderByNamespace && namespace != "" {
+			keys = append(keys, "namespace/"+namespace)
+		} else {
+			keys = append(keys, "object/"+id)
+		}
+	}
+	return keys
+}
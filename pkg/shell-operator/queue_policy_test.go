@@ -0,0 +1,59 @@
+package shell_operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+func queuedResourceIds(q *queue.TaskQueue) []string {
+	ids := make([]string, 0)
+	q.Iterate(func(t task.Task) {
+		for _, bc := range HookMetadataAccessor(t).BindingContext {
+			ids = append(ids, bc.Objects[0].Metadata.ResourceId)
+		}
+	})
+	return ids
+}
+
+func Test_QueuePolicy_DedupeByObject(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := &QueuePolicy{Dedupe: DedupeByObject}
+	q := queue.NewTasksQueue()
+
+	AddLastWithQueuePolicy(q, testEventTask("hook.sh", "pods", "pod-a", policy))
+	AddLastWithQueuePolicy(q, testEventTask("hook.sh", "pods", "pod-a", policy))
+	AddLastWithQueuePolicy(q, testEventTask("hook.sh", "pods", "pod-b", policy))
+	AddLastWithQueuePolicy(q, testEventTask("other.sh", "pods", "pod-a", nil))
+	AddLastWithQueuePolicy(q, testEventTask("hook.sh", "pods", "pod-a", policy))
+
+	// The head task is kept, pending events for pod-a are replaced with the latest one.
+	g.Expect(queuedResourceIds(q)).Should(Equal([]string{"pod-a", "pod-b", "pod-a", "pod-a"}))
+	g.Expect(q.Length()).Should(Equal(4))
+}
+
+func Test_QueuePolicy_MaxPending(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := &QueuePolicy{MaxPending: 2, OnOverflow: OverflowDropOldest}
+	q := queue.NewTasksQueue()
+	for _, id := range []string{"pod-a", "pod-b", "pod-c", "pod-d"} {
+		AddLastWithQueuePolicy(q, testEventTask("hook.sh", "pods", id, policy))
+	}
+	g.Expect(queuedResourceIds(q)).Should(Equal([]string{"pod-a", "pod-c", "pod-d"}))
+
+	policy = &QueuePolicy{MaxPending: 1, OnOverflow: OverflowCompact}
+	q = queue.NewTasksQueue()
+	for _, id := range []string{"pod-a", "pod-b", "pod-c", "pod-d"} {
+		AddLastWithQueuePolicy(q, testEventTask("hook.sh", "pods", id, policy))
+	}
+	g.Expect(q.Length()).Should(Equal(2))
+	g.Expect(queuedResourceIds(q)).Should(Equal([]string{"pod-a", "pod-b", "pod-c", "pod-d"}))
+}
@@ -0,0 +1,132 @@
+package shell_operator
+
+import (
+	"fmt"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+	. "github.com/flant/shell-operator/pkg/kube_events_manager/types"
+
+	"github.com/flant/shell-operator/pkg/app"
+	"github.com/flant/shell-operator/pkg/hook"
+	"github.com/flant/shell-operator/pkg/task/queue"
+	"github.com/flant/shell-operator/pkg/task/store"
+)
+
+// InitTaskStore enables persistence of queued tasks and the dead letter queue.
+// It should be called before queues are created.
+func (op *ShellOperator) InitTaskStore() error {
+	var taskStore queue.TaskStore
+	var deadLetterStore queue.TaskStore
+
+	switch app.TaskStore {
+	case "none", "":
+		return nil
+	case "file":
+		path := app.TaskStorePath
+		if path == "" {
+			path = filepath.Join(op.TempDir, "tasks.json")
+		}
+		log.Infof("Persist queued tasks into file '%s'", path)
+		taskStore = store.NewFileTaskStore(path)
+		deadLetterStore = store.NewFileTaskStore(filepath.Join(filepath.Dir(path), "dead_letter.json"))
+	case "configmap":
+		namespace := app.Namespace
+		if namespace == "" {
+			namespace = op.KubeClient.DefaultNamespace()
+		}
+		log.Infof("Persist queued tasks into ConfigMap %s/%s", namespace, app.TaskStoreConfigMapName)
+		taskStore = store.NewConfigMapTaskStore(op.KubeClient, namespace, app.TaskStoreConfigMapName)
+		deadLetterStore = store.NewConfigMapTaskStore(op.KubeClient, namespace, app.TaskStoreConfigMapName).
+			WithKey(store.ConfigMapDeadLetterKey)
+	default:
+		return fmt.Errorf("unknown task store '%s'", app.TaskStore)
+	}
+
+	op.TaskQueues.WithPersistence(taskStore, NewHookTaskCodec())
+	op.DeadLetter.WithPersistence(deadLetterStore, NewHookTaskCodec())
+	return nil
+}
+
+// RestoreDeadLetter adds persisted tasks to the dead letter queue. Tasks for removed
+// hooks are kept: they can be inspected, but not replayed.
+func (op *ShellOperator) RestoreDeadLetter() {
+	restored, err := op.DeadLetter.Restore()
+	if err != nil {
+		log.WithField("operator.component", "restoreQueues").
+			Errorf("Restore dead letter queue: %v", err)
+		return
+	}
+	if restored > 0 {
+		log.WithField("operator.component", "restoreQueues").
+			Infof("Restore %d tasks into the dead letter queue", restored)
+	}
+}
+
+// RestoreQueues adds persisted tasks to the tail of their queues. Fresh Synchronization
+// tasks are queued on start and they run before restored tasks, so binding contexts of
+// 'kubernetes' bindings that receive Synchronization are removed from restored tasks:
+// old events should not undo the state from the fresh Synchronization. Events of bindings
+// with executeHookOnSynchronization: false are restored. Tasks for removed hooks or
+// queues are dropped.
+func (op *ShellOperator) RestoreQueues() {
+	logEntry := log.WithField("operator.component", "restoreQueues")
+
+	restored, err := op.TaskQueues.Restore()
+	if err != nil {
+		logEntry.Errorf("Restore queued tasks: %v", err)
+		return
+	}
+
+	for queueName, tasks := range restored {
+		q := op.TaskQueues.GetByName(queueName)
+		for _, t := range tasks {
+			hookMeta := HookMetadataAccessor(t)
+			if q == nil {
+				logEntry.Warnf("Queue '%s' is not used by hooks, drop task %s", queueName, t.GetDescription())
+				continue
+			}
+			h := op.HookManager.GetHook(hookMeta.HookName)
+			if h == nil {
+				logEntry.Warnf("Hook '%s' is removed, drop task %s", hookMeta.HookName, t.GetDescription())
+				continue
+			}
+
+			bindingContexts := withoutSynchronizedBindings(h, hookMeta.BindingContext)
+			if len(bindingContexts) == 0 {
+				logEntry.Infof("Drop task %s: Synchronization is queued on start", t.GetDescription())
+				continue
+			}
+			hookMeta.BindingContext = bindingContexts
+			t.UpdateMetadata(hookMeta)
+
+			q.AddLast(t)
+			logEntry.WithField("queue", queueName).
+				Infof("Restore task %s", t.GetDescription())
+		}
+	}
+}
+
+// withoutSynchronizedBindings removes Synchronization binding contexts and binding
+// contexts of 'kubernetes' bindings that receive Synchronization on start.
+func withoutSynchronizedBindings(h *hook.Hook, bindingContexts []BindingContext) []BindingContext {
+	synchronized := make(map[string]bool)
+	for _, cfg := range h.Config.OnKubernetesEvents {
+		if cfg.ExecuteHookOnSynchronization {
+			synchronized[cfg.BindingName] = true
+		}
+	}
+
+	res := make([]BindingContext, 0, len(bindingContexts))
+	for _, bc := range bindingContexts {
+		if bc.Metadata.BindingType == OnKubernetesEvent && (bc.Type == TypeSynchronization || synchronized[bc.Binding]) {
+			continue
+		}
+		res = append(res, bc)
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package shell_operator
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/hook"
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/queue"
+	"github.com/flant/shell-operator/pkg/task/store"
+	"github.com/flant/shell-operator/pkg/validating_webhook"
+)
+
+// initHookManager loads 'hook.sh' with the config from the 'hooks' directory in tmpDir.
+func initHookManager(g *WithT, tmpDir string, config string) hook.HookManager {
+	hooksDir := filepath.Join(tmpDir, "hooks")
+	g.Expect(os.Mkdir(hooksDir, 0755)).Should(Succeed())
+	content := "#!/usr/bin/env bash\nif [[ $1 == \"--config\" ]] ; then\n  echo '" + config + "'\nfi\n"
+	g.Expect(ioutil.WriteFile(filepath.Join(hooksDir, "hook.sh"), []byte(content), 0755)).Should(Succeed())
+
+	hm := hook.NewHookManager()
+	hm.WithDirectories(hooksDir, tmpDir)
+	hm.WithWebhookManager(validating_webhook.NewWebhookManager())
+	g.Expect(hm.Init()).Should(Succeed())
+	return hm
+}
+
+func Test_RestoreQueues_Order(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "restore_queues")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	op := NewShellOperator()
+	op.HookManager = initHookManager(g, tmpDir, `{"configVersion":"v1","schedule":[{"name":"every-minute","crontab":"* * * * *"}],"kubernetes":[{"name":"synced","apiVersion":"v1","kind":"Pod"},{"name":"no-sync","apiVersion":"v1","kind":"ConfigMap","executeHookOnSynchronization":false}]}`)
+
+	kubeBc := func(binding string) BindingContext {
+		return testBindingContext(binding, OnKubernetesEvent)
+	}
+	scheduleBc := testBindingContext("every-minute", Schedule)
+
+	// Persist tasks of the previous run.
+	taskStore := store.NewFileTaskStore(filepath.Join(tmpDir, "tasks.json"))
+	prev := queue.NewTaskQueueSet()
+	prev.WithContext(context.Background())
+	prev.WithPersistence(taskStore, NewHookTaskCodec())
+	prev.WithMainName("main")
+	prev.NewNamedQueue("main", nil)
+	prev.GetMain().AddLast(testHookRunTask("hook.sh", kubeBc("synced")))
+	prev.GetMain().AddLast(testHookRunTask("hook.sh", kubeBc("no-sync")))
+	prev.GetMain().AddLast(testHookRunTask("hook.sh", scheduleBc))
+	prev.GetMain().AddLast(testHookRunTask("hook.sh", kubeBc("synced"), kubeBc("no-sync")))
+	g.Expect(prev.Persist()).Should(Succeed())
+
+	// Start: tasks to enable bindings go first, restored tasks are added after them.
+	op.TaskQueues = queue.NewTaskQueueSet()
+	op.TaskQueues.WithContext(context.Background())
+	op.TaskQueues.WithPersistence(taskStore, NewHookTaskCodec())
+	op.PrepopulateMainQueue(op.TaskQueues)
+	op.RestoreQueues()
+
+	type taskInfo struct {
+		Type     task.TaskType
+		Bindings []string
+	}
+	var tasks []taskInfo
+	op.TaskQueues.GetMain().Iterate(func(t task.Task) {
+		info := taskInfo{Type: t.GetType()}
+		for _, bc := range HookMetadataAccessor(t).BindingContext {
+			info.Bindings = append(info.Bindings, bc.Binding)
+		}
+		tasks = append(tasks, info)
+	})
+
+	g.Expect(tasks).Should(Equal([]taskInfo{
+		{Type: EnableKubernetesBindings},
+		{Type: EnableScheduleBindings},
+		// An Event of the binding with Synchronization is dropped.
+		{Type: HookRun, Bindings: []string{"no-sync"}},
+		{Type: HookRun, Bindings: []string{"every-minute"}},
+		{Type: HookRun, Bindings: []string{"no-sync"}},
+	}))
+}
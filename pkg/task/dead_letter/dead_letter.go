@@ -0,0 +1,125 @@
+package dead_letter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+// DefaultMaxEntries is a default limit for entries in the Store.
+const DefaultMaxEntries = 1000
+
+// Entry is a task that is given up on after retries. Id is an id of the task.
+type Entry struct {
+	Id             string
+	Task           task.Task
+	QueueName      string
+	FailureMessage string
+	FailureCount   int
+	QueuedAt       time.Time
+	ExhaustedAt    time.Time
+}
+
+// Store keeps exhausted tasks in memory. The oldest entries are removed when
+// there are more than maxEntries entries. Entries are saved between restarts
+// if persistence is enabled.
+type Store struct {
+	m          sync.RWMutex
+	entries    []*Entry
+	maxEntries int
+
+	store   queue.TaskStore
+	codec   queue.TaskCodec
+	changed int32
+}
+
+func NewStore() *Store {
+	return &Store{
+		entries:    make([]*Entry, 0),
+		maxEntries: DefaultMaxEntries,
+	}
+}
+
+func (s *Store) WithMaxEntries(n int) *Store {
+	s.maxEntries = n
+	return s
+}
+
+// Add saves a task with the last failure message.
+func (s *Store) Add(t task.Task, failureMessage string) *Entry {
+	entry := &Entry{
+		Id:             t.GetId(),
+		Task:           t,
+		QueueName:      t.GetQueueName(),
+		FailureMessage: failureMessage,
+		FailureCount:   t.GetFailureCount(),
+		QueuedAt:       t.GetQueuedAt(),
+		ExhaustedAt:    time.Now(),
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.add(entry)
+	return entry
+}
+
+func (s *Store) add(entry *Entry) {
+	s.entries = append(s.entries, entry)
+	if s.maxEntries > 0 && len(s.entries) > s.maxEntries {
+		s.entries = s.entries[len(s.entries)-s.maxEntries:]
+	}
+	s.markChanged()
+}
+
+// List returns entries from the oldest to the newest.
+func (s *Store) List() []*Entry {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	res := make([]*Entry, len(s.entries))
+	copy(res, s.entries)
+	return res
+}
+
+// Get returns an entry by id or nil.
+func (s *Store) Get(id string) *Entry {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	for _, entry := range s.entries {
+		if entry.Id == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Remove deletes an entry by id and returns it or nil if there is no such entry.
+func (s *Store) Remove(id string) *Entry {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for i, entry := range s.entries {
+		if entry.Id == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			s.markChanged()
+			return entry
+		}
+	}
+	return nil
+}
+
+// Purge deletes all entries and returns a number of deleted entries.
+func (s *Store) Purge() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	n := len(s.entries)
+	s.entries = make([]*Entry, 0)
+	s.markChanged()
+	return n
+}
+
+func (s *Store) Length() int {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	return len(s.entries)
+}
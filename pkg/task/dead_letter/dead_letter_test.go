@@ -0,0 +1,98 @@
+package dead_letter
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	. "github.com/flant/shell-operator/pkg/hook/binding_context"
+	. "github.com/flant/shell-operator/pkg/hook/task_metadata"
+	. "github.com/flant/shell-operator/pkg/hook/types"
+
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/task/store"
+)
+
+func Test_Store_Add_TrimsOldestEntries(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewStore().WithMaxEntries(2)
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		tsk := task.NewTask("HookRun").WithQueueName("main")
+		tsk.IncrementFailureCount()
+		ids = append(ids, tsk.GetId())
+		entry := s.Add(tsk, "hook failed")
+		g.Expect(entry.QueueName).Should(Equal("main"))
+		g.Expect(entry.FailureCount).Should(Equal(1))
+	}
+
+	g.Expect(s.Length()).Should(Equal(2))
+	entries := s.List()
+	g.Expect(entries[0].Task.GetId()).Should(Equal(ids[1]))
+	g.Expect(entries[1].Task.GetId()).Should(Equal(ids[2]))
+}
+
+func Test_Store_RemoveAndPurge(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewStore()
+	t1 := task.NewTask("HookRun")
+	t2 := task.NewTask("HookRun")
+	s.Add(t1, "error 1")
+	s.Add(t2, "error 2")
+
+	g.Expect(s.Get(t2.GetId()).FailureMessage).Should(Equal("error 2"))
+	g.Expect(s.Remove(t1.GetId())).ShouldNot(BeNil())
+	g.Expect(s.Remove(t1.GetId())).Should(BeNil())
+	g.Expect(s.Get(t1.GetId())).Should(BeNil())
+	g.Expect(s.Length()).Should(Equal(1))
+
+	g.Expect(s.Purge()).Should(Equal(1))
+	g.Expect(s.Length()).Should(Equal(0))
+}
+
+func Test_Store_PersistAndRestore(t *testing.T) {
+	g := NewWithT(t)
+
+	bc := BindingContext{Binding: "every-minute"}
+	bc.Metadata.BindingType = Schedule
+	tsk := task.NewTask(HookRun).
+		WithQueueName("main").
+		WithMetadata(HookMetadata{
+			HookName:       "hook.sh",
+			Binding:        "every-minute",
+			BindingType:    Schedule,
+			BindingContext: []BindingContext{bc},
+		})
+	tsk.IncrementFailureCount()
+
+	taskStore := store.NewMemoryTaskStore()
+	s := NewStore().WithPersistence(taskStore, NewHookTaskCodec())
+	g.Expect(s.Persist()).Should(Succeed())
+	g.Expect(taskStore.Load()).Should(BeNil(), "should not save without changes")
+
+	entry := s.Add(tsk, "hook failed")
+	g.Expect(s.Persist()).Should(Succeed())
+	g.Expect(taskStore.Load()).ShouldNot(BeEmpty())
+
+	restored := NewStore().WithPersistence(taskStore, NewHookTaskCodec())
+	n, err := restored.Restore()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(n).Should(Equal(1))
+
+	restoredEntry := restored.Get(tsk.GetId())
+	g.Expect(restoredEntry).ShouldNot(BeNil())
+	g.Expect(restoredEntry.QueueName).Should(Equal("main"))
+	g.Expect(restoredEntry.FailureMessage).Should(Equal("hook failed"))
+	g.Expect(restoredEntry.FailureCount).Should(Equal(1))
+	g.Expect(restoredEntry.ExhaustedAt.Equal(entry.ExhaustedAt)).Should(BeTrue())
+	g.Expect(HookMetadataAccessor(restoredEntry.Task).BindingContext[0].Binding).Should(Equal("every-minute"))
+
+	// Purge is saved.
+	restored.Purge()
+	g.Expect(restored.Persist()).Should(Succeed())
+	n, err = NewStore().WithPersistence(taskStore, NewHookTaskCodec()).Restore()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(n).Should(Equal(0))
+}
@@ -0,0 +1,145 @@
+package dead_letter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+type persistedEntries struct {
+	Entries []persistedEntry `json:"entries"`
+}
+
+type persistedEntry struct {
+	QueueName      string          `json:"queue"`
+	FailureMessage string          `json:"failureMessage"`
+	FailureCount   int             `json:"failureCount"`
+	QueuedAt       time.Time       `json:"queuedAt"`
+	ExhaustedAt    time.Time       `json:"exhaustedAt"`
+	Task           json.RawMessage `json:"task"`
+}
+
+// WithPersistence enables saving entries into the store. Tasks are encoded with the codec for queued tasks.
+func (s *Store) WithPersistence(store queue.TaskStore, codec queue.TaskCodec) *Store {
+	s.store = store
+	s.codec = codec
+	return s
+}
+
+func (s *Store) markChanged() {
+	atomic.StoreInt32(&s.changed, 1)
+}
+
+// Persist saves entries if they were changed since the last save.
+func (s *Store) Persist() error {
+	if s.store == nil || atomic.SwapInt32(&s.changed, 0) == 0 {
+		return nil
+	}
+
+	state := persistedEntries{Entries: make([]persistedEntry, 0)}
+	var err error
+	for _, entry := range s.List() {
+		var data []byte
+		data, err = s.codec.Encode(entry.Task)
+		if err != nil {
+			err = fmt.Errorf("encode task %s: %v", entry.Task.GetDescription(), err)
+			break
+		}
+		if data == nil {
+			continue
+		}
+		state.Entries = append(state.Entries, persistedEntry{
+			QueueName:      entry.QueueName,
+			FailureMessage: entry.FailureMessage,
+			FailureCount:   entry.FailureCount,
+			QueuedAt:       entry.QueuedAt,
+			ExhaustedAt:    entry.ExhaustedAt,
+			Task:           data,
+		})
+	}
+
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(state)
+		if err == nil {
+			err = s.store.Save(data)
+		}
+	}
+	if err != nil {
+		// Try again on the next call.
+		s.markChanged()
+	}
+	return err
+}
+
+// StartPersistence saves changed entries every queue.PersistInterval until the context is done.
+func (s *Store) StartPersistence(ctx context.Context) {
+	if s.store == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(queue.PersistInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := s.Persist()
+				if err != nil {
+					log.Errorf("Persist dead letter queue: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Restore adds entries saved by Persist and returns a number of restored entries.
+// Entries that cannot be decoded are skipped with an error message.
+func (s *Store) Restore() (int, error) {
+	if s.store == nil {
+		return 0, nil
+	}
+
+	data, err := s.store.Load()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	var state persistedEntries
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return 0, fmt.Errorf("parse saved dead letter queue: %v", err)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	restored := 0
+	for _, item := range state.Entries {
+		t, err := s.codec.Decode(item.Task)
+		if err != nil {
+			log.Errorf("Restore dead letter task: %v", err)
+			continue
+		}
+		s.add(&Entry{
+			Id:             t.GetId(),
+			Task:           t,
+			QueueName:      item.QueueName,
+			FailureMessage: item.FailureMessage,
+			FailureCount:   item.FailureCount,
+			QueuedAt:       item.QueuedAt,
+			ExhaustedAt:    item.ExhaustedAt,
+		})
+		restored++
+	}
+	return restored, nil
+}
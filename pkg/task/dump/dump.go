@@ -56,13 +56,18 @@ func TaskQueueSetToText(tqs *queue.TaskQueueSet) string {
 // Dump tasks in queue
 func TaskQueueToText(q *queue.TaskQueue) string {
 	var buf strings.Builder
-	buf.WriteString(fmt.Sprintf("Queue '%s': length %d, status: '%s'\n", q.Name, q.Length(), q.Status))
+	paused := ""
+	if q.IsPaused() {
+		paused = ", paused"
+	}
+	buf.WriteString(fmt.Sprintf("Queue '%s': length %d, status: '%s'%s\n", q.Name, q.Length(), q.Status, paused))
 	buf.WriteString("\n")
 
 	var index = 1
 	q.Iterate(func(task task.Task) {
 		buf.WriteString(fmt.Sprintf("%2d. ", index))
 		buf.WriteString(task.GetDescription())
+		buf.WriteString(fmt.Sprintf(", id=%s", task.GetId()))
 		buf.WriteString("\n")
 		index++
 	})
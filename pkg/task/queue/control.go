@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+/*
+A queue can be paused at runtime. A paused queue does not start new tasks, but new
+tasks are still added to it. The running task is not interrupted.
+*/
+
+// Pause stops handling of new tasks until Resume.
+func (q *TaskQueue) Pause() {
+	q.m.Lock()
+	q.paused = true
+	q.m.Unlock()
+}
+
+func (q *TaskQueue) Resume() {
+	q.m.Lock()
+	q.paused = false
+	q.m.Unlock()
+}
+
+func (q *TaskQueue) IsPaused() bool {
+	q.m.RLock()
+	defer q.m.RUnlock()
+	return q.paused
+}
+
+// Drain pauses the queue and removes all tasks that are not running at the moment.
+// It returns removed tasks.
+func (q *TaskQueue) Drain() []task.Task {
+	q.Pause()
+
+	removed := make([]task.Task, 0)
+	for _, t := range q.pendingTasks() {
+		if q.RemovePending(t.GetId()) == nil {
+			removed = append(removed, t)
+		}
+	}
+	return removed
+}
+
+// RemovePending deletes a task by id. A running task cannot be removed.
+func (q *TaskQueue) RemovePending(id string) error {
+	q.m.Lock()
+	idx := q.indexOf(id)
+	if idx == -1 {
+		q.m.Unlock()
+		return fmt.Errorf("task '%s' is not found in queue '%s'", id, q.Name)
+	}
+	if q.isRunning(id) {
+		q.m.Unlock()
+		return fmt.Errorf("task '%s' is running", id)
+	}
+	t := q.items[idx]
+	q.items = append(q.items[:idx], q.items[idx+1:]...)
+	q.m.Unlock()
+	q.removeHandler(t)
+	return nil
+}
+
+// Move changes a position of a pending task. Position 0 is the head of the queue, negative position
+// means the tail. A task cannot be moved before the running head task of a sequential queue.
+func (q *TaskQueue) Move(id string, position int) error {
+	q.m.Lock()
+	idx := q.indexOf(id)
+	if idx == -1 {
+		q.m.Unlock()
+		return fmt.Errorf("task '%s' is not found in queue '%s'", id, q.Name)
+	}
+	if q.isRunning(id) {
+		q.m.Unlock()
+		return fmt.Errorf("task '%s' is running", id)
+	}
+
+	t := q.items[idx]
+	items := append(append([]task.Task{}, q.items[:idx]...), q.items[idx+1:]...)
+
+	if position < 0 || position > len(items) {
+		position = len(items)
+	}
+	if position == 0 && q.inFlight == nil && len(items) > 0 && q.isRunning(items[0].GetId()) {
+		position = 1
+	}
+
+	q.items = append(items[:position], append([]task.Task{t}, items[position:]...)...)
+	q.m.Unlock()
+	// The task is added at the new position.
+	q.addHandler(t)
+	return nil
+}
+
+func (q *TaskQueue) pendingTasks() []task.Task {
+	q.m.RLock()
+	defer q.m.RUnlock()
+	res := make([]task.Task, 0, len(q.items))
+	for _, t := range q.items {
+		if !q.isRunning(t.GetId()) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// indexOf should be called under the lock.
+func (q *TaskQueue) indexOf(id string) int {
+	for i, t := range q.items {
+		if t.GetId() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// isRunning should be called under the lock.
+func (q *TaskQueue) isRunning(id string) bool {
+	if q.inFlight != nil {
+		return q.inFlight[id]
+	}
+	return q.runningId == id
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+func Test_TaskQueue_PauseAndDrain(t *testing.T) {
+	g := NewWithT(t)
+
+	defer func(d time.Duration) { DelayOnQueueIsEmpty = d }(DelayOnQueueIsEmpty)
+	DelayOnQueueIsEmpty = 10 * time.Millisecond
+
+	var m sync.Mutex
+	handled := make([]string, 0)
+	releaseFirst := make(chan struct{})
+
+	q := NewTasksQueue()
+	q.WithName("test")
+	q.WithContext(context.Background())
+	q.WithHandler(func(tsk task.Task) TaskResult {
+		m.Lock()
+		handled = append(handled, tsk.GetId())
+		m.Unlock()
+		if tsk.GetId() == "first" {
+			<-releaseFirst
+		}
+		return TaskResult{Status: "Success"}
+	})
+	defer q.Stop()
+
+	getHandled := func() []string {
+		m.Lock()
+		defer m.Unlock()
+		return append([]string{}, handled...)
+	}
+
+	// Paused queue accumulates tasks.
+	q.Pause()
+	q.Start()
+	q.AddLast(&task.BaseTask{Id: "first"})
+	g.Consistently(getHandled, "100ms").Should(BeEmpty())
+
+	q.Resume()
+	g.Eventually(getHandled, "2s").Should(Equal([]string{"first"}))
+
+	q.AddLast(&task.BaseTask{Id: "second"})
+	q.AddLast(&task.BaseTask{Id: "third"})
+
+	// The running task cannot be removed or moved.
+	g.Expect(q.RemovePending("first")).Should(HaveOccurred())
+	g.Expect(q.Move("first", -1)).Should(HaveOccurred())
+
+	// A pending task cannot be moved before the running task.
+	g.Expect(q.Move("third", 0)).ShouldNot(HaveOccurred())
+	g.Expect(DumpTaskIds(q)).Should(Equal("0: first\n1: third\n2: second\n"))
+
+	removed := q.Drain()
+	g.Expect(removed).Should(HaveLen(2))
+	g.Expect(q.IsPaused()).Should(BeTrue())
+
+	close(releaseFirst)
+	g.Eventually(q.IsEmpty, "2s").Should(BeTrue())
+
+	q.AddLast(&task.BaseTask{Id: "fourth"})
+	g.Consistently(getHandled, "100ms").Should(Equal([]string{"first"}))
+	q.Resume()
+	g.Eventually(getHandled, "2s").Should(Equal([]string{"first", "fourth"}))
+}
+
+func Test_TaskQueue_Move(t *testing.T) {
+	g := NewWithT(t)
+
+	q := NewTasksQueue()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		q.AddLast(&task.BaseTask{Id: id})
+	}
+	// Queue set saves the queue on changes.
+	changed := 0
+	q.WithAddHandler(func(_ task.Task) { changed++ })
+
+	g.Expect(q.Move("c", 0)).ShouldNot(HaveOccurred())
+	g.Expect(DumpTaskIds(q)).Should(Equal("0: c\n1: a\n2: b\n3: d\n"))
+	g.Expect(changed).Should(Equal(1))
+
+	g.Expect(q.Move("c", -1)).ShouldNot(HaveOccurred())
+	g.Expect(DumpTaskIds(q)).Should(Equal("0: a\n1: b\n2: d\n3: c\n"))
+
+	g.Expect(q.Move("a", 2)).ShouldNot(HaveOccurred())
+	g.Expect(DumpTaskIds(q)).Should(Equal("0: b\n1: d\n2: a\n3: c\n"))
+
+	g.Expect(q.Move("unknown", 0)).Should(HaveOccurred())
+	g.Expect(changed).Should(Equal(3))
+	g.Expect(q.RemovePending("d")).ShouldNot(HaveOccurred())
+	g.Expect(q.Length()).Should(Equal(3))
+}
@@ -0,0 +1,243 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/flant/shell-operator/pkg/task"
+	"github.com/flant/shell-operator/pkg/utils/exponential_backoff"
+)
+
+/*
+A queue with concurrency > 1 handles several tasks at once.
+
+Each task has ordering keys returned by the keys function. A task is started only
+if there are no earlier tasks in the queue with the same key, so tasks for the same
+object are handled in order. A task without keys is a barrier: it is started when all
+earlier tasks are done and no later tasks are started until it is done.
+
+A failed task is retried after a delay, later tasks with the same keys wait for it.
+*/
+
+type parallelTaskResult struct {
+	task   task.Task
+	result TaskResult
+}
+
+// WithConcurrency sets a number of tasks that can be handled at once. Concurrency
+// is changed for the running queue only if the queue was started with concurrency > 1.
+func (q *TaskQueue) WithConcurrency(n int) *TaskQueue {
+	q.m.Lock()
+	q.concurrency = n
+	q.m.Unlock()
+	return q
+}
+
+func (q *TaskQueue) GetConcurrency() int {
+	q.m.RLock()
+	defer q.m.RUnlock()
+	return q.concurrency
+}
+
+// IsParallel returns true if the queue handles several tasks at once.
+func (q *TaskQueue) IsParallel() bool {
+	return q.GetConcurrency() > 1
+}
+
+// WithTaskKeysFn sets a function that returns ordering keys for a task.
+// Nil keys means that the task is a barrier. All tasks are barriers if fn is not set.
+func (q *TaskQueue) WithTaskKeysFn(fn func(task.Task) []string) *TaskQueue {
+	q.taskKeysFn = fn
+	return q
+}
+
+// InFlight returns a number of tasks that are handled at the moment.
+func (q *TaskQueue) InFlight() int {
+	q.m.RLock()
+	defer q.m.RUnlock()
+	if q.inFlight == nil {
+		if q.started && q.Status == "run first task" {
+			return 1
+		}
+		return 0
+	}
+	return len(q.inFlight)
+}
+
+// isInFlight should be called under the lock.
+func (q *TaskQueue) isInFlight(id string) bool {
+	return q.inFlight != nil && q.inFlight[id]
+}
+
+// nextParallelTask returns a task that can be started now and marks it as in flight.
+func (q *TaskQueue) nextParallelTask(retryAt map[string]time.Time) task.Task {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if q.paused || len(q.inFlight) >= q.concurrency {
+		return nil
+	}
+
+	now := time.Now()
+	blocked := map[string]bool{}
+	for i, t := range q.items {
+		id := t.GetId()
+		ready := !q.inFlight[id] && !now.Before(retryAt[id])
+
+		var keys []string
+		if q.taskKeysFn != nil {
+			keys = q.taskKeysFn(t)
+		}
+		if len(keys) == 0 {
+			// A barrier waits for earlier tasks and blocks later tasks.
+			if i == 0 && ready && len(q.inFlight) == 0 {
+				q.inFlight[id] = true
+				return t
+			}
+			return nil
+		}
+
+		if ready {
+			isBlocked := false
+			for _, key := range keys {
+				if blocked[key] {
+					isBlocked = true
+					break
+				}
+			}
+			if !isBlocked {
+				q.inFlight[id] = true
+				return t
+			}
+		}
+		for _, key := range keys {
+			blocked[key] = true
+		}
+	}
+	return nil
+}
+
+func (q *TaskQueue) doneParallelTask(id string) {
+	q.m.Lock()
+	delete(q.inFlight, id)
+	q.m.Unlock()
+}
+
+// runParallel is a queue loop for concurrency > 1.
+func (q *TaskQueue) runParallel() {
+	q.m.Lock()
+	q.inFlight = make(map[string]bool)
+	q.m.Unlock()
+
+	results := make(chan parallelTaskResult)
+	retryAt := make(map[string]time.Time)
+	var pauseUntil time.Time
+	stopping := false
+
+	for {
+		if !stopping && q.Handler != nil && !time.Now().Before(pauseUntil) {
+			for {
+				t := q.nextParallelTask(retryAt)
+				if t == nil {
+					break
+				}
+				log.Debugf("queue %s: start task %s", q.Name, t.GetDescription())
+				go func(t task.Task) {
+					results <- parallelTaskResult{task: t, result: q.Handler(t)}
+				}(t)
+			}
+		}
+
+		inFlight := q.InFlight()
+		if stopping && inFlight == 0 {
+			q.setStatus("stop")
+			log.Infof("queue '%s' stopped", q.Name)
+			return
+		}
+		if !stopping {
+			q.setStatus(fmt.Sprintf("%d tasks in flight", inFlight))
+			if q.IsPaused() {
+				q.setStatus(fmt.Sprintf("paused, %d tasks in flight", inFlight))
+			}
+		}
+
+		// Check for new tasks periodically or when retry delay is over.
+		delay := DelayOnQueueIsEmpty
+		for _, at := range retryAt {
+			if d := time.Until(at); d < delay {
+				delay = d
+			}
+		}
+		if d := time.Until(pauseUntil); d > 0 && d < delay {
+			delay = d
+		}
+		if delay < DelayOnRepeat {
+			delay = DelayOnRepeat
+		}
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-q.ctx.Done():
+			if !stopping {
+				log.Infof("queue '%s': wait for %d tasks in flight before stop", q.Name, inFlight)
+				q.setStatus("stopping")
+				stopping = true
+			}
+		case res := <-results:
+			delay := q.handleParallelResult(res.task, res.result, retryAt)
+			if delay > 0 {
+				pauseUntil = time.Now().Add(delay)
+			}
+		case <-timer.C:
+			// Forget retry delays of tasks removed from the queue.
+			for id := range retryAt {
+				if q.Get(id) == nil {
+					delete(retryAt, id)
+				}
+			}
+		}
+		timer.Stop()
+	}
+}
+
+// handleParallelResult applies a task result and returns a delay before starting new tasks.
+func (q *TaskQueue) handleParallelResult(t task.Task, taskRes TaskResult, retryAt map[string]time.Time) time.Duration {
+	id := t.GetId()
+	switch taskRes.Status {
+	case "Fail":
+		// Exponential backoff delay before retry, other tasks are not blocked.
+		delay := exponential_backoff.CalculateDelay(DelayOnFailedTask, t.GetFailureCount())
+		if taskRes.DelayBeforeNextTask != 0 {
+			delay = taskRes.DelayBeforeNextTask
+			taskRes.DelayBeforeNextTask = 0
+		}
+		retryAt[id] = time.Now().Add(delay)
+		t.IncrementFailureCount()
+	case "Success":
+		delete(retryAt, id)
+		for i := len(taskRes.AfterTasks) - 1; i >= 0; i-- {
+			q.AddAfter(id, taskRes.AfterTasks[i])
+		}
+		for _, newTask := range taskRes.TailTasks {
+			q.AddLast(newTask)
+		}
+		q.DoWithHeadLock(func(q *TaskQueue) {
+			q.Remove(id)
+			for _, newTask := range taskRes.HeadTasks {
+				q.AddFirst(newTask)
+			}
+		})
+	case "Repeat":
+		retryAt[id] = time.Now().Add(DelayOnRepeat)
+	}
+	q.doneParallelTask(id)
+
+	if taskRes.AfterHandle != nil {
+		taskRes.AfterHandle()
+	}
+
+	log.Debugf("queue %s: tasks after handle %s", q.Name, q.String())
+	return taskRes.DelayBeforeNextTask
+}
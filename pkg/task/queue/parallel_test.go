@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+func Test_TaskQueue_Parallel_OrderByKeys(t *testing.T) {
+	g := NewWithT(t)
+
+	defer func(d time.Duration) { DelayOnQueueIsEmpty = d }(DelayOnQueueIsEmpty)
+	DelayOnQueueIsEmpty = 10 * time.Millisecond
+
+	keys := map[string][]string{
+		"a1":      {"a"},
+		"a2":      {"a"},
+		"b1":      {"b"},
+		"barrier": nil,
+		"c1":      {"c"},
+	}
+
+	var m sync.Mutex
+	started := make([]string, 0)
+	releaseA1 := make(chan struct{})
+
+	q := NewTasksQueue()
+	q.WithName("parallel")
+	q.WithContext(context.Background())
+	q.WithConcurrency(2)
+	q.WithTaskKeysFn(func(tsk task.Task) []string {
+		return keys[tsk.GetId()]
+	})
+	q.WithHandler(func(tsk task.Task) TaskResult {
+		m.Lock()
+		started = append(started, tsk.GetId())
+		m.Unlock()
+		if tsk.GetId() == "a1" {
+			<-releaseA1
+		}
+		return TaskResult{Status: "Success"}
+	})
+	defer q.Stop()
+
+	for _, id := range []string{"a1", "a2", "b1", "barrier", "c1"} {
+		q.AddLast(&task.BaseTask{Id: id})
+	}
+
+	getStarted := func() []string {
+		m.Lock()
+		defer m.Unlock()
+		return append([]string{}, started...)
+	}
+
+	q.Start()
+
+	// a2 waits for a1, b1 is handled in parallel, barrier waits for all earlier tasks.
+	g.Eventually(getStarted, "2s").Should(ConsistOf("a1", "b1"))
+	g.Consistently(getStarted, "100ms").Should(HaveLen(2))
+	g.Expect(q.InFlight()).Should(Equal(1))
+
+	close(releaseA1)
+	g.Eventually(getStarted, "2s").Should(HaveLen(5))
+	g.Expect(getStarted()[2:]).Should(Equal([]string{"a2", "barrier", "c1"}))
+	g.Eventually(q.IsEmpty, "2s").Should(BeTrue())
+}
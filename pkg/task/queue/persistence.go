@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+// PersistInterval is an interval to save changed queues into a TaskStore.
+var PersistInterval = time.Second
+
+// TaskStore keeps serialized tasks between restarts.
+type TaskStore interface {
+	// Load returns saved data or nil if nothing was saved.
+	Load() ([]byte, error)
+	Save(data []byte) error
+}
+
+// TaskCodec converts tasks to bytes and back.
+type TaskCodec interface {
+	// Encode returns nil for a task that should not be persisted.
+	Encode(t task.Task) ([]byte, error)
+	Decode(data []byte) (task.Task, error)
+}
+
+type persistedQueues struct {
+	Queues map[string][]json.RawMessage `json:"queues"`
+}
+
+// WithPersistence enables saving tasks into the store. Queues should be created after this call.
+func (tqs *TaskQueueSet) WithPersistence(store TaskStore, codec TaskCodec) {
+	tqs.store = store
+	tqs.codec = codec
+}
+
+func (tqs *TaskQueueSet) markChanged(_ task.Task) {
+	atomic.StoreInt32(&tqs.changed, 1)
+}
+
+// Persist saves tasks of all queues if queues were changed since the last save.
+func (tqs *TaskQueueSet) Persist() error {
+	if tqs.store == nil || atomic.SwapInt32(&tqs.changed, 0) == 0 {
+		return nil
+	}
+
+	state := persistedQueues{Queues: make(map[string][]json.RawMessage)}
+	var err error
+	tqs.Iterate(func(q *TaskQueue) {
+		q.Iterate(func(t task.Task) {
+			if err != nil {
+				return
+			}
+			var data []byte
+			data, err = tqs.codec.Encode(t)
+			if err != nil {
+				err = fmt.Errorf("encode task %s: %v", t.GetDescription(), err)
+				return
+			}
+			if data != nil {
+				state.Queues[q.Name] = append(state.Queues[q.Name], data)
+			}
+		})
+	})
+
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(state)
+		if err == nil {
+			err = tqs.store.Save(data)
+		}
+	}
+	if err != nil {
+		// Try again on the next call.
+		tqs.markChanged(nil)
+	}
+	return err
+}
+
+// StartPersistence saves changed queues every PersistInterval until the queue set is stopped.
+func (tqs *TaskQueueSet) StartPersistence() {
+	if tqs.store == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(PersistInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tqs.ctx.Done():
+				return
+			case <-ticker.C:
+				err := tqs.Persist()
+				if err != nil {
+					log.Errorf("Persist queues: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Restore loads tasks saved by Persist. Tasks are returned by queue name in the saved order
+// and are not added to queues. Tasks that cannot be decoded are skipped with an error message.
+func (tqs *TaskQueueSet) Restore() (map[string][]task.Task, error) {
+	res := make(map[string][]task.Task)
+	if tqs.store == nil {
+		return res, nil
+	}
+
+	data, err := tqs.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return res, nil
+	}
+
+	var state persistedQueues
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, fmt.Errorf("parse saved queues: %v", err)
+	}
+
+	for name, items := range state.Queues {
+		for _, item := range items {
+			t, err := tqs.codec.Decode(item)
+			if err != nil {
+				log.Errorf("Restore task in queue '%s': %v", name, err)
+				continue
+			}
+			res[name] = append(res[name], t)
+		}
+	}
+	return res, nil
+}
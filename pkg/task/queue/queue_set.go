@@ -16,6 +16,11 @@ type TaskQueueSet struct {
 
 	metricStorage *metric_storage.MetricStorage
 
+	// Optional persistence of tasks, see WithPersistence.
+	store   TaskStore
+	codec   TaskCodec
+	changed int32
+
 	m      sync.Mutex
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -66,6 +71,10 @@ func (tqs *TaskQueueSet) NewNamedQueue(name string, handler func(task.Task) Task
 	q.WithHandler(handler)
 	q.WithContext(tqs.ctx)
 	q.WithMetricStorage(tqs.metricStorage)
+	if tqs.store != nil {
+		q.WithAddHandler(tqs.markChanged)
+		q.WithRemoveHandler(tqs.markChanged)
+	}
 	tqs.Queues[name] = q
 }
 
@@ -62,6 +62,16 @@ type TaskQueue struct {
 	measureActionFnOnce sync.Once
 	addHandler          func(task.Task)
 	removeHandler       func(task.Task)
+
+	// A paused queue does not start new tasks, see Pause.
+	paused bool
+	// An id of the task that is handled by the sequential loop.
+	runningId string
+
+	// Parallel handling of tasks, see WithConcurrency.
+	concurrency int
+	taskKeysFn  func(task.Task) []string
+	inFlight    map[string]bool
 }
 
 func NewTasksQueue() *TaskQueue {
@@ -178,6 +188,47 @@ func (q *TaskQueue) AddLast(t task.Task) {
 	q.m.Unlock()
 }
 
+// AddLastWithPendingFn adds new tail element. fn is called under the lock with pending
+// tasks and returns tasks to keep in the queue. The head task and tasks in flight are
+// not passed to fn because they can be handled at the moment.
+func (q *TaskQueue) AddLastWithPendingFn(t task.Task, fn func(pending []task.Task) []task.Task) {
+	defer q.MeasureActionTime("AddLastWithPendingFn")()
+	q.addHandler(t)
+	q.m.Lock()
+	removed := make([]task.Task, 0)
+	if len(q.items) > 1 {
+		pending := make([]task.Task, 0, len(q.items)-1)
+		for _, item := range q.items[1:] {
+			if !q.isInFlight(item.GetId()) {
+				pending = append(pending, item)
+			}
+		}
+		kept := fn(pending)
+
+		keptIds := make(map[string]bool, len(kept))
+		for _, item := range kept {
+			keptIds[item.GetId()] = true
+		}
+		for _, item := range pending {
+			if !keptIds[item.GetId()] {
+				removed = append(removed, item)
+			}
+		}
+		newItems := []task.Task{q.items[0]}
+		for _, item := range q.items[1:] {
+			if q.isInFlight(item.GetId()) || keptIds[item.GetId()] {
+				newItems = append(newItems, item)
+			}
+		}
+		q.items = newItems
+	}
+	q.items = append(q.items, t)
+	q.m.Unlock()
+	for _, item := range removed {
+		q.removeHandler(item)
+	}
+}
+
 // RemoveLast deletes a tail element, so tail is moved.
 func (q *TaskQueue) RemoveLast() (t task.Task) {
 	defer q.MeasureActionTime("RemoveLast")()
@@ -317,14 +368,19 @@ func (q *TaskQueue) Start() {
 	if q.started {
 		return
 	}
+	if q.IsParallel() {
+		go q.runParallel()
+		q.started = true
+		return
+	}
 	go func() {
-		q.Status = ""
+		q.setStatus("")
 		var sleepDelay time.Duration
 		for {
 			log.Debugf("queue %s: wait for task, delay %d", q.Name, sleepDelay)
 			var t = q.waitForTask(sleepDelay)
 			if t == nil {
-				q.Status = "stop"
+				q.setStatus("stop")
 				log.Infof("queue '%s' stopped", q.Name)
 				return
 			}
@@ -338,14 +394,15 @@ func (q *TaskQueue) Start() {
 				continue
 			}
 			var nextSleepDelay time.Duration
-			q.Status = "run first task"
+			q.setStatus("run first task")
 			taskRes := q.Handler(t)
+			q.setRunningId("")
 
 			// Check Done channel after long running operation.
 			select {
 			case <-q.ctx.Done():
 				log.Infof("queue '%s' stopped after task handling", q.Name)
-				q.Status = "stop"
+				q.setStatus("stop")
 				return
 			default:
 			}
@@ -355,7 +412,7 @@ func (q *TaskQueue) Start() {
 				// Exponential backoff delay before retry.
 				nextSleepDelay = exponential_backoff.CalculateDelay(DelayOnFailedTask, t.GetFailureCount())
 				t.IncrementFailureCount()
-				q.Status = fmt.Sprintf("sleep after fail for %s", nextSleepDelay.String())
+				q.setStatus(fmt.Sprintf("sleep after fail for %s", nextSleepDelay.String()))
 			case "Success":
 				// add tasks after current task in reverse order
 				for i := len(taskRes.AfterTasks) - 1; i >= 0; i-- {
@@ -372,16 +429,16 @@ func (q *TaskQueue) Start() {
 						q.AddFirst(newTask)
 					}
 				})
-				q.Status = ""
+				q.setStatus("")
 			case "Repeat":
 				// repeat a current task after a small delay
 				nextSleepDelay = DelayOnRepeat
-				q.Status = "repeat head task"
+				q.setStatus("repeat head task")
 			}
 
 			if taskRes.DelayBeforeNextTask != 0 {
 				nextSleepDelay = taskRes.DelayBeforeNextTask
-				q.Status = fmt.Sprintf("sleep for %s", nextSleepDelay.String())
+				q.setStatus(fmt.Sprintf("sleep for %s", nextSleepDelay.String()))
 			}
 
 			sleepDelay = nextSleepDelay
@@ -397,6 +454,30 @@ func (q *TaskQueue) Start() {
 	q.started = true
 }
 
+// startFirst returns a head task and marks it as running. It returns nil if queue is empty or paused.
+func (q *TaskQueue) startFirst() task.Task {
+	q.m.Lock()
+	defer q.m.Unlock()
+	if q.paused || q.isEmpty() {
+		return nil
+	}
+	q.runningId = q.items[0].GetId()
+	return q.items[0]
+}
+
+// setStatus changes a status of the queue, it is read by other goroutines.
+func (q *TaskQueue) setStatus(status string) {
+	q.m.Lock()
+	q.Status = status
+	q.m.Unlock()
+}
+
+func (q *TaskQueue) setRunningId(id string) {
+	q.m.Lock()
+	q.runningId = id
+	q.m.Unlock()
+}
+
 // waitForTask returns a task that can be processed or a nil if context is canceled.
 // sleepDelay is used to sleep before check a task, e.g. in case of failed previous task.
 // If queue is empty, than it will be checked every DelayOnQueueIsEmpty.
@@ -413,9 +494,10 @@ func (q *TaskQueue) waitForTask(sleepDelay time.Duration) task.Task {
 	waitBegin := time.Now()
 	for {
 		// Skip this loop if sleep is not needed and there is a task to process.
-		if !q.IsEmpty() && sleepDelay == 0 {
-			return q.GetFirst()
-			//break
+		if sleepDelay == 0 {
+			if t := q.startFirst(); t != nil {
+				return t
+			}
 		}
 		//log.WithField("operator.component", "taskRunner").
 		//	Debug("Task queue is empty. Will sleep now.")
@@ -437,10 +519,12 @@ func (q *TaskQueue) waitForTask(sleepDelay time.Duration) task.Task {
 				return nil
 			case <-secondTicker.C:
 				waitSeconds := time.Since(waitBegin).Truncate(time.Second).String()
-				if sleepDelay == 0 {
-					q.Status = fmt.Sprintf("waiting for task %s", waitSeconds)
+				if q.IsPaused() {
+					q.setStatus(fmt.Sprintf("paused %s", waitSeconds))
+				} else if sleepDelay == 0 {
+					q.setStatus(fmt.Sprintf("waiting for task %s", waitSeconds))
 				} else {
-					q.Status = fmt.Sprintf("%s (elapsed %s)", origStatus, waitSeconds)
+					q.setStatus(fmt.Sprintf("%s (elapsed %s)", origStatus, waitSeconds))
 				}
 			}
 			if stop {
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/shell-operator/pkg/kube"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+const (
+	ConfigMapTasksKey      = "tasks.json"
+	ConfigMapDeadLetterKey = "dead_letter.json"
+)
+
+// FileTaskStore saves tasks into a local file. The file is replaced atomically.
+type FileTaskStore struct {
+	Path string
+}
+
+var _ queue.TaskStore = &FileTaskStore{}
+
+func NewFileTaskStore(path string) *FileTaskStore {
+	return &FileTaskStore{Path: path}
+}
+
+func (s *FileTaskStore) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *FileTaskStore) Save(data []byte) error {
+	err := os.MkdirAll(filepath.Dir(s.Path), 0755)
+	if err != nil {
+		return err
+	}
+	tmpPath := s.Path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.Path)
+}
+
+// MemoryTaskStore keeps saved data in memory. It is useful for tests.
+type MemoryTaskStore struct {
+	m    sync.Mutex
+	data []byte
+}
+
+var _ queue.TaskStore = &MemoryTaskStore{}
+
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{}
+}
+
+func (s *MemoryTaskStore) Load() ([]byte, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.data, nil
+}
+
+func (s *MemoryTaskStore) Save(data []byte) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.data = data
+	return nil
+}
+
+// ConfigMapTaskStore saves tasks into a ConfigMap. It is useful with leader election
+// as all replicas have access to tasks. Note that ConfigMap size is limited to 1MiB.
+type ConfigMapTaskStore struct {
+	KubeClient kube.KubernetesClient
+	Namespace  string
+	Name       string
+	// Key is a key in the ConfigMap data, other keys are not changed.
+	Key string
+}
+
+var _ queue.TaskStore = &ConfigMapTaskStore{}
+
+func NewConfigMapTaskStore(client kube.KubernetesClient, namespace string, name string) *ConfigMapTaskStore {
+	return &ConfigMapTaskStore{
+		KubeClient: client,
+		Namespace:  namespace,
+		Name:       name,
+		Key:        ConfigMapTasksKey,
+	}
+}
+
+func (s *ConfigMapTaskStore) WithKey(key string) *ConfigMapTaskStore {
+	s.Key = key
+	return s
+}
+
+func (s *ConfigMapTaskStore) Load() ([]byte, error) {
+	cm, err := s.KubeClient.CoreV1().ConfigMaps(s.Namespace).Get(s.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get ConfigMap %s/%s: %v", s.Namespace, s.Name, err)
+	}
+	return []byte(cm.Data[s.Key]), nil
+}
+
+func (s *ConfigMapTaskStore) Save(data []byte) error {
+	configMaps := s.KubeClient.CoreV1().ConfigMaps(s.Namespace)
+
+	cm, err := configMaps.Get(s.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.Name,
+				Namespace: s.Namespace,
+			},
+			Data: map[string]string{s.Key: string(data)},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[s.Key] = string(data)
+	_, err = configMaps.Update(cm)
+	return err
+}
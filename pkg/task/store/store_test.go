@@ -0,0 +1,71 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/shell-operator/pkg/kube"
+	"github.com/flant/shell-operator/pkg/task/queue"
+)
+
+func testTaskStoreRoundTrip(g *WithT, s queue.TaskStore) {
+	// No saved tasks.
+	data, err := s.Load()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(data).Should(BeEmpty())
+
+	g.Expect(s.Save([]byte(`{"queues":{"main":[{"id":"1"}]}}`))).Should(Succeed())
+	data, err = s.Load()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(data)).Should(Equal(`{"queues":{"main":[{"id":"1"}]}}`))
+
+	// Save replaces tasks.
+	g.Expect(s.Save([]byte(`{"queues":{}}`))).Should(Succeed())
+	data, err = s.Load()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(data)).Should(Equal(`{"queues":{}}`))
+}
+
+func Test_FileTaskStore(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpDir, err := ioutil.TempDir("", "task_store")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	// Directory is created on Save.
+	path := filepath.Join(tmpDir, "state", "tasks.json")
+	testTaskStoreRoundTrip(g, NewFileTaskStore(path))
+
+	_, err = os.Stat(path + ".tmp")
+	g.Expect(os.IsNotExist(err)).Should(BeTrue())
+}
+
+func Test_ConfigMapTaskStore(t *testing.T) {
+	g := NewWithT(t)
+
+	client := kube.NewFakeKubernetesClient()
+	testTaskStoreRoundTrip(g, NewConfigMapTaskStore(client, "default", "shell-operator-tasks"))
+
+	// Other keys of the ConfigMap are kept.
+	configMaps := client.CoreV1().ConfigMaps("default")
+	cm, err := configMaps.Get("shell-operator-tasks", metav1.GetOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	cm.Data["other"] = "value"
+	_, err = configMaps.Update(cm)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	s := NewConfigMapTaskStore(client, "default", "shell-operator-tasks")
+	g.Expect(s.Save([]byte(`{"queues":{"main":[]}}`))).Should(Succeed())
+	cm, err = configMaps.Get("shell-operator-tasks", metav1.GetOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(cm.Data).Should(Equal(map[string]string{
+		ConfigMapTasksKey: `{"queues":{"main":[]}}`,
+		"other":           "value",
+	}))
+}
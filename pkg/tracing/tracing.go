@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+/*
+Spans follow an event from the informer to the hook process:
+
+  HandleWatchEvent -> EmitKubeEvent -> HandleKubeEvent -> QueueTask -> WaitInQueue -> HookRun -> CombineBindingContext
+                                                                                              -> HookExec
+
+Span context is passed through channels in KubeEvent and through queues in task props.
+A hook gets a TRACEPARENT environment variable to continue the trace.
+*/
+
+const TracerName = "github.com/flant/shell-operator"
+
+// SpanContextProp is a task prop with a trace.SpanContext of the span that queued the task.
+const SpanContextProp = "spanContext"
+
+// Config is a configuration of the OTLP exporter.
+type Config struct {
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+	SampleRatio float64
+}
+
+// Init sets a global tracer provider with the OTLP HTTP exporter. Tracing is a no-op
+// if the endpoint is empty. The returned function flushes and stops the exporter.
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start starts a span with a remote parent. A new trace is started if parent is not valid.
+func Start(parent trace.SpanContext, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx := context.Background()
+	if parent.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// StartAt starts a span with a start time in the past, e.g. for a time spent in the queue.
+func StartAt(parent trace.SpanContext, name string, start time.Time, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Start(parent, name, append(opts, trace.WithTimestamp(start))...)
+}
+
+// Traceparent returns a W3C traceparent header value for the span in ctx or an empty string.
+func Traceparent(ctx context.Context) string {
+	carrier := propagation.HeaderCarrier(http.Header{})
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// WithTaskSpanContext saves the span context into task props.
+func WithTaskSpanContext(t task.Task, sc trace.SpanContext) {
+	if sc.IsValid() {
+		t.SetProp(SpanContextProp, sc)
+	}
+}
+
+// TaskSpanContext returns a span context from task props or an empty span context.
+func TaskSpanContext(t task.Task) trace.SpanContext {
+	sc, _ := t.GetProp(SpanContextProp).(trace.SpanContext)
+	return sc
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/flant/shell-operator/pkg/task"
+)
+
+func Test_SpanContextInTask(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, queueSpan := Start(trace.SpanContext{}, "QueueTask")
+	tsk := task.NewTask("HookRun")
+	WithTaskSpanContext(tsk, queueSpan.SpanContext())
+	queueSpan.End()
+
+	parent := TaskSpanContext(tsk)
+	g.Expect(parent).Should(Equal(queueSpan.SpanContext()))
+
+	ctx, runSpan := Start(parent, "HookRun")
+	g.Expect(runSpan.SpanContext().TraceID()).Should(Equal(parent.TraceID()))
+	g.Expect(Traceparent(ctx)).Should(Equal(fmt.Sprintf("00-%s-%s-01", parent.TraceID(), runSpan.SpanContext().SpanID())))
+	runSpan.End()
+
+	g.Expect(recorder.Ended()).Should(HaveLen(2))
+
+	// No span context for a task without the prop.
+	g.Expect(TaskSpanContext(task.NewTask("HookRun")).IsValid()).Should(BeFalse())
+	g.Expect(Traceparent(context.Background())).Should(BeEmpty())
+}
@@ -0,0 +1,119 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is a JSON patch operation as defined in RFC 6902.
+type Operation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON omits 'value' field for 'remove' operation
+// and keeps it for other operations even if value is null.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"op":   o.Op,
+		"path": o.Path,
+	}
+	if o.Op != "remove" {
+		m["value"] = o.Value
+	}
+	return json.Marshal(m)
+}
+
+// CreatePatch returns a JSON patch to transform original document into modified.
+//
+// Objects are compared recursively. Arrays with different lengths are replaced as a whole,
+// arrays with equal lengths are compared element by element.
+func CreatePatch(original, modified []byte) ([]byte, error) {
+	ops, err := CreateOperations(original, modified)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
+
+// CreateOperations returns a list of operations to transform original document into modified.
+func CreateOperations(original, modified []byte) ([]Operation, error) {
+	var origDoc, modDoc interface{}
+
+	err := json.Unmarshal(original, &origDoc)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal original document: %v", err)
+	}
+	err = json.Unmarshal(modified, &modDoc)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal modified document: %v", err)
+	}
+
+	return diff("", origDoc, modDoc), nil
+}
+
+func diff(path string, orig, mod interface{}) []Operation {
+	switch origVal := orig.(type) {
+	case map[string]interface{}:
+		if modVal, ok := mod.(map[string]interface{}); ok {
+			return diffObjects(path, origVal, modVal)
+		}
+	case []interface{}:
+		if modVal, ok := mod.([]interface{}); ok && len(origVal) == len(modVal) {
+			ops := make([]Operation, 0)
+			for i := range origVal {
+				ops = append(ops, diff(path+"/"+strconv.Itoa(i), origVal[i], modVal[i])...)
+			}
+			return ops
+		}
+	}
+
+	if reflect.DeepEqual(orig, mod) {
+		return []Operation{}
+	}
+	return []Operation{{Op: "replace", Path: path, Value: mod}}
+}
+
+func diffObjects(path string, orig, mod map[string]interface{}) []Operation {
+	ops := make([]Operation, 0)
+
+	// Sort keys to get stable patches.
+	keys := make([]string, 0, len(orig))
+	for k := range orig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		keyPath := path + "/" + escapeKey(k)
+		modVal, has := mod[k]
+		if !has {
+			ops = append(ops, Operation{Op: "remove", Path: keyPath})
+			continue
+		}
+		ops = append(ops, diff(keyPath, orig[k], modVal)...)
+	}
+
+	keys = make([]string, 0, len(mod))
+	for k := range mod {
+		if _, has := orig[k]; !has {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ops = append(ops, Operation{Op: "add", Path: path + "/" + escapeKey(k), Value: mod[k]})
+	}
+
+	return ops
+}
+
+// escapeKey escapes '~' and '/' in a key according to RFC 6901.
+func escapeKey(key string) string {
+	key = strings.Replace(key, "~", "~0", -1)
+	return strings.Replace(key, "/", "~1", -1)
+}
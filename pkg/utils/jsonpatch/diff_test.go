@@ -0,0 +1,65 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	evanjsonpatch "github.com/evanphx/json-patch"
+	. "github.com/onsi/gomega"
+)
+
+func Test_CreatePatch(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		expected string
+	}{
+		{
+			"equal documents",
+			`{"metadata":{"name":"pod-0"}}`,
+			`{"metadata":{"name":"pod-0"}}`,
+			`[]`,
+		},
+		{
+			"add label",
+			`{"metadata":{"name":"pod-0"}}`,
+			`{"metadata":{"name":"pod-0","labels":{"app":"nginx"}}}`,
+			`[{"op":"add","path":"/metadata/labels","value":{"app":"nginx"}}]`,
+		},
+		{
+			"remove and replace",
+			`{"metadata":{"name":"pod-0","annotations":{"a/b":"c"}},"spec":{"replicas":1}}`,
+			`{"metadata":{"name":"pod-1"},"spec":{"replicas":null}}`,
+			`[{"op":"remove","path":"/metadata/annotations"},{"op":"replace","path":"/metadata/name","value":"pod-1"},{"op":"replace","path":"/spec/replicas","value":null}]`,
+		},
+		{
+			"escape keys",
+			`{"metadata":{"annotations":{}}}`,
+			`{"metadata":{"annotations":{"example.com/owner~1":"me"}}}`,
+			`[{"op":"add","path":"/metadata/annotations/example.com~1owner~01","value":"me"}]`,
+		},
+		{
+			"arrays",
+			`{"items":[1,{"a":1}],"other":[1]}`,
+			`{"items":[1,{"a":2}],"other":[1,2]}`,
+			`[{"op":"replace","path":"/items/1/a","value":2},{"op":"replace","path":"/other","value":[1,2]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := CreatePatch([]byte(tt.original), []byte(tt.modified))
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(string(patch)).Should(MatchJSON(tt.expected))
+
+			// Patch should transform original into modified.
+			p, err := evanjsonpatch.DecodePatch(patch)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			patched, err := p.Apply([]byte(tt.original))
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(string(patched)).Should(MatchJSON(tt.modified))
+		})
+	}
+}
@@ -34,6 +34,49 @@ func (c *ValidatingWebhookConfig) UpdateIds(confId, webhookId string) {
 	c.Metadata.WebhookId = safeUrlString(webhookId)
 }
 
+// MutatingWebhookConfig
+type MutatingWebhookConfig struct {
+	*v1.MutatingWebhook
+	Metadata struct {
+		Name            string
+		WebhookId       string
+		ConfigurationId string // A suffix to create different MutatingWebhookConfiguration resources.
+		DebugName       string
+		LogLabels       map[string]string
+		MetricLabels    map[string]string
+	}
+}
+
+// UpdateIds use confId and webhookId to set a ConfigurationId prefix and a WebhookId.
+// Mutating webhooks use a separate default ConfigurationId, so the handler
+// can distinguish them from validating webhooks by the request path.
+func (c *MutatingWebhookConfig) UpdateIds(confId, webhookId string) {
+	c.Metadata.ConfigurationId = confId
+	if confId == "" {
+		c.Metadata.ConfigurationId = DefaultMutatingConfigurationId
+	}
+	c.Metadata.WebhookId = safeUrlString(webhookId)
+}
+
+// ConversionWebhookConfig
+type ConversionWebhookConfig struct {
+	// CrdName is a name of CustomResourceDefinition to patch with webhook clientConfig.
+	CrdName  string
+	Metadata struct {
+		Name         string
+		WebhookId    string
+		DebugName    string
+		LogLabels    map[string]string
+		MetricLabels map[string]string
+	}
+}
+
+// UpdateIds sets a WebhookId from CRD name. ConfigurationId is
+// always ConversionConfigurationId for conversion webhooks.
+func (c *ConversionWebhookConfig) UpdateIds() {
+	c.Metadata.WebhookId = safeUrlString(c.CrdName)
+}
+
 var safeReList = []*regexp.Regexp{
 	regexp.MustCompile(`([A-Z])`),
 	regexp.MustCompile(`[^a-z0-9-/]`),
@@ -0,0 +1,102 @@
+package validating_webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+var CrdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// ConversionWebhookResource patches CustomResourceDefinitions
+// with a webhook conversion strategy.
+type ConversionWebhookResource struct {
+	KubeClient  kube.KubernetesClient
+	Webhooks    map[string]*ConversionWebhookConfig
+	Namespace   string
+	ServiceName string
+	CABundle    []byte
+}
+
+func NewConversionWebhookResource() *ConversionWebhookResource {
+	return &ConversionWebhookResource{
+		Webhooks: make(map[string]*ConversionWebhookConfig),
+	}
+}
+
+func (w *ConversionWebhookResource) AddWebhook(config *ConversionWebhookConfig) {
+	w.Webhooks[config.Metadata.WebhookId] = config
+}
+
+func (w *ConversionWebhookResource) RemoveWebhook(config *ConversionWebhookConfig) {
+	delete(w.Webhooks, config.Metadata.WebhookId)
+}
+
+// UpdateCustomResourceDefinitions sets spec.conversion for each registered CRD.
+func (w *ConversionWebhookResource) UpdateCustomResourceDefinitions() error {
+	for _, webhook := range w.Webhooks {
+		err := w.UpdateCustomResourceDefinition(webhook)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *ConversionWebhookResource) UpdateCustomResourceDefinition(webhook *ConversionWebhookConfig) error {
+	path := w.CreateWebhookPath(webhook)
+
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"conversion": map[string]interface{}{
+				"strategy": "Webhook",
+				"webhook": map[string]interface{}{
+					"clientConfig": map[string]interface{}{
+						"service": map[string]interface{}{
+							"namespace": w.Namespace,
+							"name":      w.ServiceName,
+							"path":      path,
+						},
+						"caBundle": w.CABundle,
+					},
+					"conversionReviewVersions": []string{"v1", "v1beta1"},
+				},
+			},
+		},
+	}
+
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Set '%s' conversion path in CRD '%s'", path, webhook.CrdName)
+
+	_, err = w.KubeClient.Dynamic().Resource(CrdGVR).Patch(webhook.CrdName, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("patch CRD '%s': %v", webhook.CrdName, err)
+	}
+	return nil
+}
+
+func (w *ConversionWebhookResource) CreateWebhookPath(webhook *ConversionWebhookConfig) string {
+	s := new(strings.Builder)
+
+	s.WriteString("/")
+	s.WriteString(ConversionConfigurationId)
+	s.WriteString("/")
+	s.WriteString(webhook.Metadata.WebhookId)
+
+	return s.String()
+}
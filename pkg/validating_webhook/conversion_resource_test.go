@@ -0,0 +1,52 @@
+package validating_webhook
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+func Test_ConversionWebhookResource_UpdateCustomResourceDefinition(t *testing.T) {
+	kubeClient := kube.NewFakeKubernetesClient()
+
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName("crontabs.stable.example.com")
+	_, err := kubeClient.Dynamic().Resource(CrdGVR).Create(crd, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("CRD should be created: %v", err)
+	}
+
+	r := NewConversionWebhookResource()
+	r.KubeClient = kubeClient
+	r.Namespace = "default"
+	r.ServiceName = "webhook-service"
+	r.CABundle = []byte("ca-bundle")
+
+	cfg := &ConversionWebhookConfig{CrdName: "crontabs.stable.example.com"}
+	cfg.UpdateIds()
+	r.AddWebhook(cfg)
+
+	err = r.UpdateCustomResourceDefinitions()
+	if err != nil {
+		t.Fatalf("CRD should be patched: %v", err)
+	}
+
+	obj, err := kubeClient.Dynamic().Resource(CrdGVR).Get("crontabs.stable.example.com", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("CRD should exist: %v", err)
+	}
+
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "conversion", "strategy")
+	if strategy != "Webhook" {
+		t.Fatalf("CRD should have Webhook conversion strategy, got '%s'", strategy)
+	}
+	path, _, _ := unstructured.NestedString(obj.Object, "spec", "conversion", "webhook", "clientConfig", "service", "path")
+	if path != "/conversion/crontabs-stable-example-com" {
+		t.Fatalf("CRD should have conversion path, got '%s'", path)
+	}
+}
@@ -13,7 +13,9 @@ import (
 
 	v1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/flant/shell-operator/pkg/utils/jsonpatch"
 	"github.com/flant/shell-operator/pkg/utils/structured-logger"
 	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
 )
@@ -47,18 +49,24 @@ func (h *WebhookHandler) ServeReviewRequest(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	admissionResponse, err := h.HandleReviewRequest(r.URL.Path, bodyBytes)
+	var reviewResponse interface{}
+	configurationId, _ := DetectConfigurationAndWebhook(r.URL.Path)
+	if h.Manager.IsConversion(configurationId) {
+		reviewResponse, err = h.HandleConversionRequest(r.URL.Path, bodyBytes)
+	} else {
+		reviewResponse, err = h.HandleReviewRequest(r.URL.Path, bodyBytes)
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
 		return
 	}
 
-	respBytes, err := json.Marshal(admissionResponse)
+	respBytes, err := json.Marshal(reviewResponse)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte("Error json encoding AdmissionReview"))
-		log.Errorf("Error json encoding AdmissionReview: %v", err)
+		_, _ = w.Write([]byte("Error json encoding review response"))
+		log.Errorf("Error json encoding review response: %v", err)
 		return
 	}
 
@@ -85,6 +93,10 @@ func (h *WebhookHandler) HandleReviewRequest(path string, body []byte) (*v1.Admi
 		},
 	}
 
+	if h.Manager.IsMutating(configurationId) {
+		return h.handleMutatingReview(configurationId, webhookId, &review, response), nil
+	}
+
 	if h.Manager.ValidatingEventHandlerFn == nil {
 		response.Response.Allowed = false
 		response.Response.Result = &metav1.Status{
@@ -123,6 +135,145 @@ func (h *WebhookHandler) HandleReviewRequest(path string, body []byte) (*v1.Admi
 	return response, nil
 }
 
+// handleMutatingReview calls MutatingEventHandlerFn and fills a JSONPatch
+// in the response. If hook returns a modified object, the patch is
+// calculated as a difference between the original and the modified object.
+func (h *WebhookHandler) handleMutatingReview(configurationId string, webhookId string, review *v1.AdmissionReview, response *v1.AdmissionReview) *v1.AdmissionReview {
+	if h.Manager.MutatingEventHandlerFn == nil {
+		response.Response.Allowed = false
+		response.Response.Result = &metav1.Status{
+			Code:    500,
+			Message: "AdmissionReview handler is not defined",
+		}
+		return response
+	}
+
+	event := MutatingEvent{
+		WebhookId:       webhookId,
+		ConfigurationId: configurationId,
+		Review:          review,
+	}
+
+	mutatingResponse, err := h.Manager.MutatingEventHandlerFn(event)
+	if err != nil {
+		response.Response.Allowed = false
+		response.Response.Result = &metav1.Status{
+			Code:    500,
+			Message: err.Error(),
+		}
+		return response
+	}
+
+	if !mutatingResponse.Allowed {
+		response.Response.Allowed = false
+		response.Response.Result = &metav1.Status{
+			Code:    403,
+			Message: mutatingResponse.Message,
+		}
+		return response
+	}
+
+	patch := []byte(mutatingResponse.Patch)
+	if len(mutatingResponse.Object) > 0 {
+		patch, err = jsonpatch.CreatePatch(review.Request.Object.Raw, mutatingResponse.Object)
+		if err != nil {
+			log.Errorf("Error creating patch for mutated object: %v", err)
+			response.Response.Allowed = false
+			response.Response.Result = &metav1.Status{
+				Code:    500,
+				Message: "fail to create patch for mutated object",
+			}
+			return response
+		}
+	}
+
+	response.Response.Allowed = true
+	if len(patch) > 0 && string(patch) != "[]" {
+		patchType := v1.PatchTypeJSONPatch
+		response.Response.Patch = patch
+		response.Response.PatchType = &patchType
+	}
+	return response
+}
+
+// HandleConversionRequest calls ConversionEventHandlerFn and returns ConversionReview
+// with converted objects. Converted objects are checked to have a desired apiVersion.
+func (h *WebhookHandler) HandleConversionRequest(path string, body []byte) (*ConversionReview, error) {
+	_, webhookId := DetectConfigurationAndWebhook(path)
+	log.Infof("Got ConversionReview request for webhookId='%s'", webhookId)
+
+	var review ConversionReview
+	err := json.Unmarshal(body, &review)
+	if err != nil {
+		log.Errorf("Error parsing ConversionReview: %v", err)
+		return nil, fmt.Errorf("fail to parse ConversionReview")
+	}
+	if review.Request == nil {
+		return nil, fmt.Errorf("ConversionReview has no request")
+	}
+
+	response := &ConversionReview{
+		TypeMeta: review.TypeMeta,
+		Response: &ConversionResponse{
+			UID:              review.Request.UID,
+			ConvertedObjects: []runtime.RawExtension{},
+		},
+	}
+
+	fail := func(message string) *ConversionReview {
+		response.Response.Result = metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: message,
+		}
+		return response
+	}
+
+	if h.Manager.ConversionEventHandlerFn == nil {
+		return fail("ConversionReview handler is not defined"), nil
+	}
+
+	crdName := h.Manager.ConversionCrdName(webhookId)
+	if crdName == "" {
+		crdName = webhookId
+	}
+
+	event := ConversionEvent{
+		WebhookId: webhookId,
+		CrdName:   crdName,
+		Review:    &review,
+	}
+
+	conversionResponse, err := h.Manager.ConversionEventHandlerFn(event)
+	if err != nil {
+		return fail(err.Error()), nil
+	}
+
+	if conversionResponse.FailedMessage != "" {
+		return fail(conversionResponse.FailedMessage), nil
+	}
+
+	if len(conversionResponse.ConvertedObjects) != len(review.Request.Objects) {
+		return fail(fmt.Sprintf("hook returned %d converted objects, expected %d", len(conversionResponse.ConvertedObjects), len(review.Request.Objects))), nil
+	}
+
+	for i, obj := range conversionResponse.ConvertedObjects {
+		var typeMeta metav1.TypeMeta
+		err := json.Unmarshal(obj, &typeMeta)
+		if err != nil {
+			return fail(fmt.Sprintf("converted object [%d] is invalid: %v", i, err)), nil
+		}
+		if typeMeta.APIVersion != review.Request.DesiredAPIVersion {
+			return fail(fmt.Sprintf("converted object [%d] has apiVersion '%s', expected '%s'", i, typeMeta.APIVersion, review.Request.DesiredAPIVersion)), nil
+		}
+		response.Response.ConvertedObjects = append(response.Response.ConvertedObjects, runtime.RawExtension{Raw: obj})
+	}
+
+	response.Response.Result = metav1.Status{
+		Status: metav1.StatusSuccess,
+	}
+	return response, nil
+}
+
 func DetectConfigurationAndWebhook(path string) (configurationId string, webhookId string) {
 	parts := strings.Split(path, "/")
 	webhookParts := []string{}
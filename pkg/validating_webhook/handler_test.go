@@ -1,6 +1,14 @@
 package validating_webhook
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
+)
 
 func Test_DetectConfigurationAndWebhook(t *testing.T) {
 	tests := []struct {
@@ -52,3 +60,81 @@ func Test_DetectConfigurationAndWebhook(t *testing.T) {
 		})
 	}
 }
+
+func Test_HandleReviewRequest_Mutating(t *testing.T) {
+	m := NewWebhookManager()
+	m.MutatingResources[DefaultMutatingConfigurationId] = NewMutatingWebhookResource()
+	m.WithMutatingEventHandler(func(event MutatingEvent) (*MutatingResponse, error) {
+		if event.WebhookId != "add-label" {
+			t.Fatalf("expected webhookId 'add-label', got '%s'", event.WebhookId)
+		}
+		return &MutatingResponse{
+			Allowed: true,
+			Object:  []byte(`{"metadata":{"name":"pod-1","labels":{"foo":"bar"}}}`),
+		}, nil
+	})
+	h := NewWebhookHandler()
+	h.Manager = m
+
+	review := `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"123","object":{"metadata":{"name":"pod-1"}}}}`
+
+	res, err := h.HandleReviewRequest("/"+DefaultMutatingConfigurationId+"/add-label", []byte(review))
+	if err != nil {
+		t.Fatalf("HandleReviewRequest should not fail: %v", err)
+	}
+	if !res.Response.Allowed {
+		t.Fatalf("response should be allowed")
+	}
+	if res.Response.PatchType == nil || *res.Response.PatchType != admissionv1.PatchTypeJSONPatch {
+		t.Fatalf("response should have JSONPatch patchType")
+	}
+	expected := `[{"op":"add","path":"/metadata/labels","value":{"foo":"bar"}}]`
+	if string(res.Response.Patch) != expected {
+		t.Fatalf("expected patch '%s', got '%s'", expected, string(res.Response.Patch))
+	}
+}
+
+func Test_HandleConversionRequest(t *testing.T) {
+	m := NewWebhookManager()
+	m.WithConversionEventHandler(func(event ConversionEvent) (*ConversionHookResponse, error) {
+		if event.CrdName != "crontabs.stable.example.com" {
+			t.Fatalf("expected crdName 'crontabs.stable.example.com', got '%s'", event.CrdName)
+		}
+		return &ConversionHookResponse{
+			ConvertedObjects: []json.RawMessage{
+				[]byte(`{"apiVersion":"stable.example.com/v1","kind":"CronTab","metadata":{"name":"ct-1"}}`),
+			},
+		}, nil
+	})
+	h := NewWebhookHandler()
+	h.Manager = m
+
+	review := `{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"123","desiredAPIVersion":"stable.example.com/v1","objects":[{"apiVersion":"stable.example.com/v1alpha1","kind":"CronTab","metadata":{"name":"ct-1"}}]}}`
+
+	res, err := h.HandleConversionRequest("/"+ConversionConfigurationId+"/crontabs.stable.example.com", []byte(review))
+	if err != nil {
+		t.Fatalf("HandleConversionRequest should not fail: %v", err)
+	}
+	if res.Response.Result.Status != metav1.StatusSuccess {
+		t.Fatalf("response should be successful: %#v", res.Response.Result)
+	}
+	if len(res.Response.ConvertedObjects) != 1 {
+		t.Fatalf("response should have 1 converted object, got %d", len(res.Response.ConvertedObjects))
+	}
+
+	// Hook returns an object with wrong apiVersion.
+	m.WithConversionEventHandler(func(event ConversionEvent) (*ConversionHookResponse, error) {
+		return &ConversionHookResponse{
+			ConvertedObjects: []json.RawMessage{
+				[]byte(`{"apiVersion":"stable.example.com/v1alpha1","kind":"CronTab","metadata":{"name":"ct-1"}}`),
+			},
+		}, nil
+	})
+	res, err = h.HandleConversionRequest("/"+ConversionConfigurationId+"/crontabs.stable.example.com", []byte(review))
+	if err != nil {
+		t.Fatalf("HandleConversionRequest should not fail: %v", err)
+	}
+	if res.Response.Result.Status != metav1.StatusFailure {
+		t.Fatalf("response should be failed: %#v", res.Response.Result)
+	}
+}
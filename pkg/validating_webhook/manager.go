@@ -1,8 +1,11 @@
 package validating_webhook
 
 import (
-	log "github.com/sirupsen/logrus"
 	"io/ioutil"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
 
 	. "github.com/flant/shell-operator/pkg/validating_webhook/types"
 
@@ -11,37 +14,63 @@ import (
 )
 
 type ValidatingEventHandlerFn func(event ValidatingEvent) (*ValidatingResponse, error)
+type MutatingEventHandlerFn func(event MutatingEvent) (*MutatingResponse, error)
+type ConversionEventHandlerFn func(event ConversionEvent) (*ConversionHookResponse, error)
 
 // DefaultConfigurationId is a ConfigurationId for ValidatingWebhookConfiguration
 // without suffix.
 const DefaultConfigurationId = "hooks"
 
+// DefaultMutatingConfigurationId is a ConfigurationId for MutatingWebhookConfiguration
+// without suffix.
+const DefaultMutatingConfigurationId = "mutating-hooks"
+
+// ConversionConfigurationId is a first element in path for conversion webhooks.
+const ConversionConfigurationId = "conversion"
+
 // WebhookManager is a public interface to be used from operator.go.
 //
-// No dynamic configuration for now. The steps are:
+// The steps are:
 //   - Init manager
-//   - Call AddWEbhook for every binding in hooks
-//   - Start() to run server and create ValidatingWebhookConfiguration
+//   - Call AddWEbhook and AddMutatingWebhook for every binding in hooks
+//   - Call AddConversionWebhook for every kubernetesCustomResourceConversion binding
+//   - Start() to run server, create ValidatingWebhookConfiguration
+//     and MutatingWebhookConfiguration and patch CustomResourceDefinitions
+//
+// Webhooks can be changed after Start with Add* and Remove* methods
+// followed by UpdateConfigurations().
 type WebhookManager struct {
 	KubeClient kube.KubernetesClient
 
 	ValidatingEventHandlerFn ValidatingEventHandlerFn
+	MutatingEventHandlerFn   MutatingEventHandlerFn
+	ConversionEventHandlerFn ConversionEventHandlerFn
+
+	Namespace                 string
+	ConfigurationName         string
+	MutatingConfigurationName string
+	ServiceName               string
 
-	Namespace         string
-	ConfigurationName string
-	ServiceName       string
+	CABundle                       []byte
+	DefaultConfigurationId         string
+	DefaultMutatingConfigurationId string
 
-	CABundle               []byte
-	DefaultConfigurationId string
+	Server             *WebhookServer
+	Resources          map[string]*WebhookResource
+	MutatingResources  map[string]*MutatingWebhookResource
+	ConversionResource *ConversionWebhookResource
+	Handler            *WebhookHandler
 
-	Server    *WebhookServer
-	Resources map[string]*WebhookResource
-	Handler   *WebhookHandler
+	started bool
+	// m guards webhooks in resources
+	m sync.RWMutex
 }
 
 func NewWebhookManager() *WebhookManager {
 	return &WebhookManager{
-		Resources: make(map[string]*WebhookResource),
+		Resources:          make(map[string]*WebhookResource),
+		MutatingResources:  make(map[string]*MutatingWebhookResource),
+		ConversionResource: NewConversionWebhookResource(),
 	}
 }
 
@@ -53,6 +82,14 @@ func (m *WebhookManager) WithValidatingEventHandler(handler ValidatingEventHandl
 	m.ValidatingEventHandlerFn = handler
 }
 
+func (m *WebhookManager) WithMutatingEventHandler(handler MutatingEventHandlerFn) {
+	m.MutatingEventHandlerFn = handler
+}
+
+func (m *WebhookManager) WithConversionEventHandler(handler ConversionEventHandlerFn) {
+	m.ConversionEventHandlerFn = handler
+}
+
 // Init creates dependencies
 func (m *WebhookManager) Init() error {
 	log.Info("Initialize validating webhooks manager. Load certificates.")
@@ -60,6 +97,12 @@ func (m *WebhookManager) Init() error {
 	if m.DefaultConfigurationId == "" {
 		m.DefaultConfigurationId = DefaultConfigurationId
 	}
+	if m.DefaultMutatingConfigurationId == "" {
+		m.DefaultMutatingConfigurationId = DefaultMutatingConfigurationId
+	}
+	if m.MutatingConfigurationName == "" {
+		m.MutatingConfigurationName = m.ConfigurationName
+	}
 	// settings
 	caBundleBytes, err := ioutil.ReadFile(app.ValidatingWebhookSettings.CAPath)
 	if err != nil {
@@ -83,10 +126,27 @@ func (m *WebhookManager) Init() error {
 	r.CABundle = m.CABundle
 	m.Resources[m.DefaultConfigurationId] = r
 
+	m.MutatingResources = make(map[string]*MutatingWebhookResource)
+	mr := NewMutatingWebhookResource()
+	mr.KubeClient = m.KubeClient
+	mr.Namespace = m.Namespace
+	mr.ConfigurationName = m.MutatingConfigurationName
+	mr.ServiceName = m.ServiceName
+	mr.CABundle = m.CABundle
+	m.MutatingResources[m.DefaultMutatingConfigurationId] = mr
+
+	m.ConversionResource = NewConversionWebhookResource()
+	m.ConversionResource.KubeClient = m.KubeClient
+	m.ConversionResource.Namespace = m.Namespace
+	m.ConversionResource.ServiceName = m.ServiceName
+	m.ConversionResource.CABundle = m.CABundle
+
 	return nil
 }
 
 func (m *WebhookManager) AddWebhook(config *ValidatingWebhookConfig) {
+	m.m.Lock()
+	defer m.m.Unlock()
 	confId := config.Metadata.ConfigurationId
 	if confId == "" {
 		confId = m.DefaultConfigurationId
@@ -104,18 +164,156 @@ func (m *WebhookManager) AddWebhook(config *ValidatingWebhookConfig) {
 	r.AddWebhook(config)
 }
 
+func (m *WebhookManager) AddMutatingWebhook(config *MutatingWebhookConfig) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	confId := config.Metadata.ConfigurationId
+	if confId == "" {
+		confId = m.DefaultMutatingConfigurationId
+	}
+	r, ok := m.MutatingResources[confId]
+	if !ok {
+		r = NewMutatingWebhookResource()
+		r.KubeClient = m.KubeClient
+		r.Namespace = m.Namespace
+		r.ConfigurationName = m.MutatingConfigurationName + "-" + confId
+		r.ServiceName = m.ServiceName
+		r.CABundle = m.CABundle
+		m.MutatingResources[confId] = r
+	}
+	r.AddWebhook(config)
+}
+
+func (m *WebhookManager) AddConversionWebhook(config *ConversionWebhookConfig) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.ConversionResource.AddWebhook(config)
+}
+
+func (m *WebhookManager) RemoveWebhook(config *ValidatingWebhookConfig) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	for _, r := range m.Resources {
+		r.RemoveWebhook(config)
+	}
+}
+
+func (m *WebhookManager) RemoveMutatingWebhook(config *MutatingWebhookConfig) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	for _, r := range m.MutatingResources {
+		r.RemoveWebhook(config)
+	}
+}
+
+func (m *WebhookManager) RemoveConversionWebhook(config *ConversionWebhookConfig) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.ConversionResource.RemoveWebhook(config)
+}
+
+// ConversionCrdName returns a name of CRD for conversion webhookId or empty string.
+func (m *WebhookManager) ConversionCrdName(webhookId string) string {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	if cfg, has := m.ConversionResource.Webhooks[webhookId]; has {
+		return cfg.CrdName
+	}
+	return ""
+}
+
+// IsConversion returns true if configurationId is for conversion webhooks.
+func (m *WebhookManager) IsConversion(configurationId string) bool {
+	return configurationId == ConversionConfigurationId
+}
+
+// IsMutating returns true if configurationId belongs to MutatingWebhookConfiguration.
+func (m *WebhookManager) IsMutating(configurationId string) bool {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	_, has := m.MutatingResources[configurationId]
+	return has
+}
+
 func (m *WebhookManager) Start() error {
 	err := m.Server.Start()
 	if err != nil {
 		return err
 	}
 
+	m.m.RLock()
+	defer m.m.RUnlock()
+
 	for _, r := range m.Resources {
+		if len(r.Webhooks) == 0 {
+			continue
+		}
 		err = r.CreateConfiguration()
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, r := range m.MutatingResources {
+		if len(r.Webhooks) == 0 {
+			continue
+		}
+		err = r.CreateConfiguration()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = m.ConversionResource.UpdateCustomResourceDefinitions()
+	if err != nil {
+		return err
+	}
+
+	m.started = true
 	return nil
 }
+
+// IsStarted returns true if server is started and configurations are created.
+func (m *WebhookManager) IsStarted() bool {
+	return m.started
+}
+
+// UpdateConfigurations creates or updates configurations with webhooks
+// and deletes configurations without webhooks. CustomResourceDefinitions
+// are patched for registered conversion webhooks.
+func (m *WebhookManager) UpdateConfigurations() error {
+	m.m.RLock()
+	defer m.m.RUnlock()
+
+	for _, r := range m.Resources {
+		var err error
+		if len(r.Webhooks) == 0 {
+			err = r.DeleteConfiguration()
+			if errors.IsNotFound(err) {
+				err = nil
+			}
+		} else {
+			err = r.CreateConfiguration()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range m.MutatingResources {
+		var err error
+		if len(r.Webhooks) == 0 {
+			err = r.DeleteConfiguration()
+			if errors.IsNotFound(err) {
+				err = nil
+			}
+		} else {
+			err = r.CreateConfiguration()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.ConversionResource.UpdateCustomResourceDefinitions()
+}
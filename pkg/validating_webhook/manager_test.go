@@ -58,3 +58,55 @@ func Test_Manager_AddWebhook(t *testing.T) {
 		}
 	}
 }
+
+func Test_Manager_AddMutatingWebhook(t *testing.T) {
+	m := NewWebhookManager()
+	app.Namespace = "default"
+	vs := app.ValidatingWebhookSettings
+	vs.ConfigurationName = "webhook-configuration"
+	vs.ServiceName = "webhook-service"
+	vs.ServerKeyPath = "testdata/demo-certs/server-key.pem"
+	vs.ServerCertPath = "testdata/demo-certs/server.crt"
+	vs.CAPath = "testdata/demo-certs/ca.pem"
+
+	err := m.Init()
+
+	if err != nil {
+		t.Fatalf("WebhookManager should init: %v", err)
+	}
+
+	cfg := &MutatingWebhookConfig{
+		MutatingWebhook: &v1.MutatingWebhook{
+			Name: "test-mutating",
+			Rules: []v1.RuleWithOperations{
+				{
+					Operations: []v1.OperationType{v1.Create},
+					Rule: v1.Rule{
+						APIGroups:   []string{""},
+						APIVersions: []string{"v1"},
+						Resources:   []string{"pods"},
+					},
+				},
+			},
+		},
+	}
+	cfg.UpdateIds("", "test-mutating")
+	m.AddMutatingWebhook(cfg)
+
+	if len(m.MutatingResources) != 1 {
+		t.Fatalf("WebhookManager should have mutating resources: got length %d", len(m.MutatingResources))
+	}
+
+	for k, v := range m.MutatingResources {
+		if len(v.Webhooks) != 1 {
+			t.Fatalf("Mutating resource '%s' should have Webhooks: got length %d", k, len(v.Webhooks))
+		}
+	}
+
+	if !m.IsMutating(DefaultMutatingConfigurationId) {
+		t.Fatalf("'%s' should be a mutating configurationId", DefaultMutatingConfigurationId)
+	}
+	if m.IsMutating(DefaultConfigurationId) {
+		t.Fatalf("'%s' should not be a mutating configurationId", DefaultConfigurationId)
+	}
+}
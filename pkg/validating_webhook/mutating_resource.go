@@ -0,0 +1,113 @@
+package validating_webhook
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/shell-operator/pkg/kube"
+)
+
+// MutatingWebhookResource is a MutatingWebhookConfiguration resource
+// with webhooks from kubernetesMutating bindings.
+type MutatingWebhookResource struct {
+	KubeClient        kube.KubernetesClient
+	Webhooks          map[string]*MutatingWebhookConfig
+	Namespace         string
+	ConfigurationName string
+	ServiceName       string
+	CABundle          []byte
+}
+
+func NewMutatingWebhookResource() *MutatingWebhookResource {
+	return &MutatingWebhookResource{
+		Webhooks: make(map[string]*MutatingWebhookConfig),
+	}
+}
+
+func (w *MutatingWebhookResource) AddWebhook(config *MutatingWebhookConfig) {
+	w.Webhooks[config.Metadata.WebhookId] = config
+}
+
+func (w *MutatingWebhookResource) RemoveWebhook(config *MutatingWebhookConfig) {
+	delete(w.Webhooks, config.Metadata.WebhookId)
+}
+
+func (w *MutatingWebhookResource) CreateConfiguration() error {
+	equivalent := v1.Equivalent
+
+	configuration := &v1.MutatingWebhookConfiguration{
+		Webhooks: []v1.MutatingWebhook{},
+	}
+	configuration.Name = w.ConfigurationName
+
+	for _, webhook := range w.Webhooks {
+		webhook.MatchPolicy = &equivalent
+		webhook.AdmissionReviewVersions = []string{"v1", "v1beta1"}
+		webhook.ClientConfig = v1.WebhookClientConfig{
+			Service: &v1.ServiceReference{
+				Namespace: w.Namespace,
+				Name:      w.ServiceName,
+				Path:      w.CreateWebhookPath(webhook),
+			},
+			CABundle: w.CABundle,
+		}
+
+		log.Infof("Add '%s' path to '%s'", *webhook.ClientConfig.Service.Path, w.ConfigurationName)
+
+		configuration.Webhooks = append(configuration.Webhooks, *webhook.MutatingWebhook)
+	}
+
+	return w.CreateOrUpdateConfiguration(configuration)
+}
+
+func (w *MutatingWebhookResource) DeleteConfiguration() error {
+	return w.KubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().
+		Delete(w.ConfigurationName, &metav1.DeleteOptions{})
+}
+
+func (w *MutatingWebhookResource) CreateWebhookPath(webhook *MutatingWebhookConfig) *string {
+	s := new(strings.Builder)
+
+	s.WriteString("/")
+	if webhook.Metadata.ConfigurationId == "" {
+		s.WriteString(DefaultMutatingConfigurationId)
+	} else {
+		s.WriteString(webhook.Metadata.ConfigurationId)
+	}
+
+	s.WriteString("/")
+	s.WriteString(webhook.Metadata.WebhookId)
+
+	res := s.String()
+	return &res
+}
+
+func (w *MutatingWebhookResource) CreateOrUpdateConfiguration(conf *v1.MutatingWebhookConfiguration) error {
+	client := w.KubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations()
+
+	listOpts := metav1.ListOptions{
+		FieldSelector: "metadata.name=" + conf.Name,
+	}
+	list, err := client.List(listOpts)
+	if err != nil {
+		return err
+	}
+	if len(list.Items) == 0 {
+		_, err = client.Create(conf)
+		if err != nil {
+			log.Errorf("Create MutatingWebhookConfiguration/%s: %v", conf.Name, err)
+		}
+	} else {
+		newConf := list.Items[0]
+		newConf.Webhooks = conf.Webhooks
+		_, err = client.Update(&newConf)
+		if err != nil {
+			log.Errorf("Replace MutatingWebhookConfiguration/%s: %v", conf.Name, err)
+		}
+	}
+	return nil
+}
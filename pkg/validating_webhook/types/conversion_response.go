@@ -0,0 +1,64 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// ConversionHookResponse is a response from the kubernetesCustomResourceConversion hook.
+type ConversionHookResponse struct {
+	FailedMessage    string            `json:"failedMessage,omitempty"`
+	ConvertedObjects []json.RawMessage `json:"convertedObjects,omitempty"`
+}
+
+func ConversionHookResponseFromFile(filePath string) (*ConversionHookResponse, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", filePath, err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return ConversionHookResponseFromBytes(data)
+}
+
+func ConversionHookResponseFromBytes(data []byte) (*ConversionHookResponse, error) {
+	return ConversionHookResponseFromReader(bytes.NewReader(data))
+}
+
+func ConversionHookResponseFromReader(r io.Reader) (*ConversionHookResponse, error) {
+	response := new(ConversionHookResponse)
+
+	dec := json.NewDecoder(r)
+
+	err := dec.Decode(response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.FailedMessage != "" && len(response.ConvertedObjects) > 0 {
+		return nil, fmt.Errorf("'failedMessage' and 'convertedObjects' fields are mutually exclusive")
+	}
+
+	return response, nil
+}
+
+func (r *ConversionHookResponse) Dump() string {
+	b := new(strings.Builder)
+	b.WriteString("ConversionHookResponse(")
+	if r.FailedMessage != "" {
+		b.WriteString("failedMessage=")
+		b.WriteString(r.FailedMessage)
+	} else {
+		b.WriteString("convertedObjects.len=")
+		b.WriteString(strconv.FormatInt(int64(len(r.ConvertedObjects)), 10))
+	}
+	b.WriteString(")")
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package types
+
+import "testing"
+
+func Test_ConversionHookResponseFromBytes(t *testing.T) {
+	r, err := ConversionHookResponseFromBytes([]byte(`{"convertedObjects":[{"apiVersion":"stable.example.com/v1","kind":"CronTab"}]}`))
+	if err != nil {
+		t.Fatalf("ConversionHookResponse should be loaded: %v", err)
+	}
+	if len(r.ConvertedObjects) != 1 {
+		t.Fatalf("ConversionHookResponse should have 1 converted object: %#v", r)
+	}
+
+	r, err = ConversionHookResponseFromBytes([]byte(`{"failedMessage":"unknown version"}`))
+	if err != nil {
+		t.Fatalf("ConversionHookResponse should be loaded: %v", err)
+	}
+	if r.FailedMessage != "unknown version" {
+		t.Fatalf("ConversionHookResponse should have failedMessage: %#v", r)
+	}
+
+	_, err = ConversionHookResponseFromBytes([]byte(`{"failedMessage":"error","convertedObjects":[{}]}`))
+	if err == nil {
+		t.Fatalf("ConversionHookResponse with both fields should not be loaded")
+	}
+}
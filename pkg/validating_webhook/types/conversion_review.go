@@ -0,0 +1,41 @@
+package types
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+A copy of ConversionReview types from k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1
+to not depend on apiextensions-apiserver module.
+*/
+
+// ConversionReview describes a conversion request/response.
+type ConversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	// request describes the attributes for the conversion request.
+	Request *ConversionRequest `json:"request,omitempty"`
+	// response describes the attributes for the conversion response.
+	Response *ConversionResponse `json:"response,omitempty"`
+}
+
+// ConversionRequest describes the conversion request parameters.
+type ConversionRequest struct {
+	// uid is an identifier for the individual request/response.
+	UID types.UID `json:"uid"`
+	// desiredAPIVersion is the version to convert given objects to. e.g. "myapi.example.com/v1"
+	DesiredAPIVersion string `json:"desiredAPIVersion"`
+	// objects is the list of custom resource objects to be converted.
+	Objects []runtime.RawExtension `json:"objects"`
+}
+
+// ConversionResponse describes a conversion response.
+type ConversionResponse struct {
+	// uid is an identifier for the individual request/response.
+	UID types.UID `json:"uid"`
+	// convertedObjects is the list of converted version of `request.objects`.
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	// result contains the result of conversion with extra details if the conversion failed.
+	Result metav1.Status `json:"result"`
+}
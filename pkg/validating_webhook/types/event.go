@@ -9,3 +9,15 @@ type ValidatingEvent struct {
 	ConfigurationId string
 	Review          *v1.AdmissionReview
 }
+
+type MutatingEvent struct {
+	WebhookId       string
+	ConfigurationId string
+	Review          *v1.AdmissionReview
+}
+
+type ConversionEvent struct {
+	WebhookId string
+	CrdName   string
+	Review    *ConversionReview
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// MutatingResponse is a response from the kubernetesMutating hook.
+//
+// Hook can return a JSON patch in the 'patch' field or a full mutated
+// object in the 'object' field. The operator calculates a JSON patch
+// for the 'object' field using an object from AdmissionReview request.
+type MutatingResponse struct {
+	Allowed bool            `json:"allowed"`
+	Message string          `json:"message,omitempty"`
+	Patch   json.RawMessage `json:"patch,omitempty"`
+	Object  json.RawMessage `json:"object,omitempty"`
+}
+
+func MutatingResponseFromFile(filePath string) (*MutatingResponse, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", filePath, err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return MutatingResponseFromBytes(data)
+}
+
+func MutatingResponseFromBytes(data []byte) (*MutatingResponse, error) {
+	return MutatingResponseFromReader(bytes.NewReader(data))
+}
+
+func MutatingResponseFromReader(r io.Reader) (*MutatingResponse, error) {
+	response := new(MutatingResponse)
+
+	dec := json.NewDecoder(r)
+
+	err := dec.Decode(response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Patch) > 0 && len(response.Object) > 0 {
+		return nil, fmt.Errorf("'patch' and 'object' fields are mutually exclusive")
+	}
+
+	return response, nil
+}
+
+func (r *MutatingResponse) Dump() string {
+	b := new(strings.Builder)
+	b.WriteString("MutatingResponse(allowed=")
+	b.WriteString(strconv.FormatBool(r.Allowed))
+	if len(r.Patch) > 0 {
+		b.WriteString(",patch")
+	}
+	if len(r.Object) > 0 {
+		b.WriteString(",object")
+	}
+	if r.Message != "" {
+		b.WriteString(",")
+		b.WriteString(r.Message)
+	}
+	b.WriteString(")")
+	return b.String()
+}
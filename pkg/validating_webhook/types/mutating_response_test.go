@@ -0,0 +1,47 @@
+package types
+
+import "testing"
+
+func Test_MutatingResponseFromFile_Patch(t *testing.T) {
+	r, err := MutatingResponseFromFile("testdata/response/good_mutate_patch.json")
+
+	if err != nil {
+		t.Fatalf("MutatingResponse should be loaded from file: %v", err)
+	}
+
+	if r == nil {
+		t.Fatalf("MutatingResponse should not be nil")
+	}
+
+	if !r.Allowed {
+		t.Fatalf("MutatingResponse should have allowed=true: %#v", r)
+	}
+
+	if len(r.Patch) == 0 {
+		t.Fatalf("MutatingResponse should have patch: %#v", r)
+	}
+}
+
+func Test_MutatingResponseFromFile_Object(t *testing.T) {
+	r, err := MutatingResponseFromFile("testdata/response/good_mutate_object.json")
+
+	if err != nil {
+		t.Fatalf("MutatingResponse should be loaded from file: %v", err)
+	}
+
+	if r == nil {
+		t.Fatalf("MutatingResponse should not be nil")
+	}
+
+	if len(r.Object) == 0 {
+		t.Fatalf("MutatingResponse should have object: %#v", r)
+	}
+}
+
+func Test_MutatingResponseFromBytes_PatchAndObject(t *testing.T) {
+	_, err := MutatingResponseFromBytes([]byte(`{"allowed":true, "patch":[], "object":{}}`))
+
+	if err == nil {
+		t.Fatalf("MutatingResponse with patch and object should not be loaded")
+	}
+}
@@ -87,6 +87,51 @@ func ValidateValidatingWebhook(hook *v1.ValidatingWebhook, fldPath *field.Path)
 	return allErrors
 }
 
+func ValidateMutatingWebhooks(e *v1.MutatingWebhookConfiguration) error {
+	var allErrors *multierror.Error
+
+	hookNames := make(map[string]struct{})
+	for i, hook := range e.Webhooks {
+		allErrors = multierror.Append(allErrors, ValidateMutatingWebhook(&hook, field.NewPath("webhooks").Index(i)))
+		if len(hook.Name) > 0 {
+			if _, has := hookNames[hook.Name]; has {
+				allErrors = multierror.Append(allErrors, field.Duplicate(field.NewPath("webhooks").Index(i).Child("name"), hook.Name))
+			}
+			hookNames[hook.Name] = struct{}{}
+		}
+	}
+
+	return allErrors.ErrorOrNil()
+}
+
+// ValidateMutatingWebhook checks a "webhook" section of MutatingWebhookConfiguration.
+//
+// "reinvocationPolicy" is validated by hook config schema. Other fields
+// are checked the same way as in ValidateValidatingWebhook.
+func ValidateMutatingWebhook(hook *v1.MutatingWebhook, fldPath *field.Path) error {
+	var allErrors *multierror.Error
+	// hook.Name must be fully qualified
+	allErrors = AppendFieldList(allErrors, utilvalidation.IsFullyQualifiedName(fldPath.Child("name"), hook.Name))
+
+	for i, rule := range hook.Rules {
+		allErrors = AppendFieldList(allErrors, ValidateRuleWithOperations(&rule, fldPath.Child("rules").Index(i)))
+	}
+
+	if hook.TimeoutSeconds != nil && (*hook.TimeoutSeconds > 30 || *hook.TimeoutSeconds < 1) {
+		allErrors = multierror.Append(allErrors, field.Invalid(fldPath.Child("timeoutSeconds"), *hook.TimeoutSeconds, "the timeout value must be between 1 and 30 seconds"))
+	}
+
+	if hook.NamespaceSelector != nil {
+		allErrors = AppendFieldList(allErrors, metav1validation.ValidateLabelSelector(hook.NamespaceSelector, fldPath.Child("namespaceSelector")))
+	}
+
+	if hook.ObjectSelector != nil {
+		allErrors = AppendFieldList(allErrors, metav1validation.ValidateLabelSelector(hook.ObjectSelector, fldPath.Child("objectSelector")))
+	}
+
+	return allErrors
+}
+
 func AppendFieldList(err error, list field.ErrorList) *multierror.Error {
 	var res *multierror.Error
 	res = multierror.Append(res, err)